@@ -5,7 +5,24 @@ from src.shared.constants import CMessagePartsSizes, CMessageConfig, CCommandTyp
 
 
 def parse_message(input_str: str) -> NetworkMessage:
-    def _parse_param_str(params_string)->Param:
+    def _split_params_str(params_string) -> List[str]:
+        # params are split by delimiter outside of arrays, array elements have their own delimiters
+        parts = []
+        depth = 0
+        start = 0
+        for i, char in enumerate(params_string):
+            if char == CMessageConfig.ARRAY_BRACKETS.opening:
+                depth += 1
+            elif char == CMessageConfig.ARRAY_BRACKETS.closing:
+                depth -= 1
+            elif char == CMessageConfig.PARAMS_DELIMITER and depth == 0:
+                parts.append(params_string[start:i])
+                start = i + 1
+        parts.append(params_string[start:])
+
+        return parts
+
+    def _parse_param_str(params_string) -> List[Param]:
         def __process_array_values(param : Param) -> Param:
             def ___is_array_param(param : Param) -> bool:
                 if len(param.value) == 0:
@@ -58,7 +75,7 @@ def parse_message(input_str: str) -> NetworkMessage:
             return Param(name, value)
 
         if len(params_string) == 0:
-            return Param("", "")
+            return [Param("", "")]
 
         if params_string[0] != CMessageConfig.PARAMS_BRACKETS.opening:
             raise ValueError("Invalid paramArray format")
@@ -67,13 +84,32 @@ def parse_message(input_str: str) -> NetworkMessage:
             raise ValueError("Invalid paramArray format")
 
         # without brackets
-        params_string = params_string[1:-1] #always one parameter
+        params_string = params_string[1:-1]
 
-        parameter = __parse_param_element(params_string)
+        param_list = []
+        for param_string in _split_params_str(params_string):
+            parameter = __parse_param_element(param_string)
+            param_list.append(__process_array_values(parameter))
 
-        param = __process_array_values(parameter)
+        return param_list
 
-        return param
+    def _extract_sequence_number(param_list: List[Param]) -> tuple[int, List[Param]]:
+        # seq is added by server to messages waiting for ResponseClientSuccess, it is not a command param
+        sequence_number = 0
+        params_without_seq = []
+        for param in param_list:
+            if param.name == CMessageConfig.PARAM_SEQUENCE_NUMBER:
+                if not param.value.isdigit():
+                    raise MessageFormatError("Invalid sequence number")
+                sequence_number = int(param.value)
+                continue
+            params_without_seq.append(param)
+
+        # same as params parsed from empty brackets
+        if not params_without_seq:
+            params_without_seq = [Param("", "")]
+
+        return sequence_number, params_without_seq
 
     def _convert_arrayparam_to_specified_datastructures(command_id: int, param: Param) -> List[Param]:
         command = CCommandTypeEnum.get_command_by_id(command_id)
@@ -86,7 +122,7 @@ def parse_message(input_str: str) -> NetworkMessage:
         if len(param_value) == 0:
             return [param]
         if not isinstance(param_value, list):
-            raise [param]
+            return [param]
 
         param_array = param_value
         # check names in array elements
@@ -154,14 +190,17 @@ def parse_message(input_str: str) -> NetworkMessage:
     # Read Parameters
     parameters_str = input_str[start:]
 
-    param = _parse_param_str(parameters_str)
+    param_list = _parse_param_str(parameters_str)
+    sequence_number, param_list = _extract_sequence_number(param_list)
 
     # Convert values
     command_id_int = int(command_id)
 
-    param = _convert_arrayparam_to_specified_datastructures(command_id_int, param)
+    converted_param_list = []
+    for param in param_list:
+        converted_param_list.extend(_convert_arrayparam_to_specified_datastructures(command_id_int, param))
 
-    return NetworkMessage(signature, command_id_int, timestamp, player_nickname, param)
+    return NetworkMessage(signature, command_id_int, timestamp, player_nickname, converted_param_list, sequence_number)
 
 def convert_message_to_network_string(message: NetworkMessage) -> str:
     def _convert_params_to_network_string(params: List[Param]) -> str:
@@ -3,6 +3,7 @@ import socket
 import sys
 import threading
 import time
+from collections import deque
 from datetime import datetime
 from typing import List
 
@@ -59,6 +60,7 @@ class ServerCommunication:
         self._receive_messages_list: List[NetworkMessage] = []
 
         self._received_messages_to_process_list: List[NetworkMessage] = []
+        self._received_sequence_numbers: deque[int] = deque(maxlen=CNetworkConfig.RECEIVED_SEQUENCE_NUMBERS_SIZE)
 
     # region PRIVATE FUNCTIONS
 
@@ -73,6 +75,8 @@ class ServerCommunication:
         self._ip = ip
         self._port = port
         self._s = s
+        # server may have restarted and numbers its messages from the beginning
+        self._received_sequence_numbers.clear()
 
         self._was_connected = True
 
@@ -175,21 +179,27 @@ class ServerCommunication:
                 logging.info(f"MESSAGE PROCESSING: Received message: {received_message}")
                 return True, received_message
 
-            data = b""
+            parsed_message_list = []
+            # data with retransmitted messages only are read again
+            while not parsed_message_list:
+                data = b""
 
-            try:
-                data = self._receive_loop(data)
-                if not data:
+                try:
+                    data = self._receive_loop(data)
+                    if not data:
+                        self._close_connection_processes()
+                        return False, None
+                except Exception as e:
+                    # exception -> closed connection from server - trying to recconect
+                    # exception -> timeout
+                    logging.error(f"Server did not respond in time. {e}")
                     self._close_connection_processes()
                     return False, None
-            except Exception as e:
-                # exception -> closed connection from server - trying to recconect
-                # exception -> timeout
-                logging.error(f"Server did not respond in time. {e}")
-                self._close_connection_processes()
-                return False, None
 
-            is_connected, parsed_message_list = self._process_received_data(data)
+                is_connected, parsed_message_list = self._process_received_data(data)
+                if not is_connected:
+                    return False, None
+
             if len(parsed_message_list) != 1:
                 self._received_messages_to_process_list.extend(parsed_message_list[1:])
 
@@ -211,6 +221,14 @@ class ServerCommunication:
                         return False, None
                     continue
 
+                if received_command.id == CCommandTypeEnum.ServerShutdown.value.id:
+                    self._process_server_shutdown()
+                    return False, None
+
+                if received_command.id == CCommandTypeEnum.ServerAnnouncement.value.id:
+                    self._process_server_announcement(received_message)
+                    continue
+
                 break
 
             return is_connected, received_message
@@ -259,6 +277,15 @@ class ServerCommunication:
                     if not is_connected:
                         return False, None
                     continue
+
+                if received_command.id == CCommandTypeEnum.ServerShutdown.value.id:
+                    self._process_server_shutdown()
+                    return False, None
+
+                if received_command.id == CCommandTypeEnum.ServerAnnouncement.value.id:
+                    self._process_server_announcement(received_message)
+                    continue
+
                 new_received_messages.append(received_message)
 
             return is_connected, new_received_messages
@@ -282,6 +309,17 @@ class ServerCommunication:
             is_valid_format = self._is_header_valid(parsed_message)
             if not is_valid_format:
                 raise MessageFormatError("Invalid header format.")
+
+            # retransmitted message was already processed, only its ack got lost
+            sequence_number = parsed_message.sequence_number
+            if sequence_number and sequence_number in self._received_sequence_numbers:
+                logging.info(f"MESSAGE: Dropped duplicate message seq {sequence_number}: {parsed_message}")
+                if not self._respond_client_success(parsed_message):
+                    return False, []
+                continue
+            if sequence_number:
+                self._received_sequence_numbers.append(sequence_number)
+
             parsed_messages_list.append(parsed_message)
             self._receive_messages_list.append(parsed_message)
             logging.info(f"MESSAGE: Received message: {parsed_message}")
@@ -320,13 +358,24 @@ class ServerCommunication:
 
     @staticmethod
     def _convert_params_error_message(parameters: List[Param]) -> str:
-        # Convert the parameters to a string
-        if len(parameters) != 1:
-            error_message = ""
-        error_message = parameters[0].value
+        # Convert the parameters to a string, code decides the error, message is for the user
+        values = {param.name: param.value for param in parameters}
+        error_message = values.get("message", "")
+        if "code" in values:
+            error_message = f"{error_message} (code {values['code']})"
 
         return error_message
 
+    def _process_server_shutdown(self):
+        # server closes the connection after shutdown, game can be reconnected when the server runs again
+        logging.info("SHUTDOWN: Server is shutting down.")
+        self._close_connection_processes()
+
+    @staticmethod
+    def _process_server_announcement(received_message: NetworkMessage):
+        # announcement from server admin is only logged, it does not change state and is not acked
+        logging.info(f"ANNOUNCEMENT: {received_message.get_single_param()}")
+
     def _close_connection_processes(self):
         def _close_connection(self):
             logging.info("Closing connection...")
@@ -776,7 +825,7 @@ class ServerCommunication:
         return received_command, None
 
     def _process_server_error_message(self, received_command, received_message) -> tuple[Command | None, str | None]:
-        return received_command, received_message.get_single_param()
+        return received_command, self._convert_params_error_message(received_message.parameters)
 
     def _receive_standard_state_messages(self, allowed_commands: dict[int, callable]) -> tuple[
         bool, list[tuple[Command | None, any]] | None]:
@@ -821,6 +870,11 @@ class ServerCommunication:
                 message_result_list.append((command, message_data))
                 continue
 
+            # announcement from server admin is only logged, it does not change state
+            if received_command_id == CCommandTypeEnum.ServerAnnouncement.value.id:
+                self._process_server_announcement(received_message)
+                continue
+
             # check if statemachine can fire
             if not GAME_STATE_MACHINE.can_fire(received_command.trigger.id):
                 raise MessageStateError("Invalid state machine transition.")
@@ -861,8 +915,12 @@ class ServerCommunication:
         return received_command, received_message.get_array_param()
 
     def _respond_client_success(self, received_message: NetworkMessage) -> bool:
+        # ack names the message by its sequence number, timestamp is echoed for older servers
         command = CCommandTypeEnum.ResponseClientSuccess.value
-        return self._send_message(command, None, timeStamp=received_message.timestamp)
+        param_list = None
+        if received_message.sequence_number:
+            param_list = [Param(CMessageConfig.PARAM_SEQUENCE_NUMBER, received_message.sequence_number)]
+        return self._send_message(command, param_list, timeStamp=received_message.timestamp)
     # endregion
 
     def receive_server_running_game_messages(self) -> tuple[bool, list[tuple[Command | None, GameData | None | str]]]:
@@ -131,7 +131,7 @@ class CubeValuesList(list):
 class NetworkMessage:
 
     def __init__(self, signature: str, command_id: int, timestamp, player_nickname: str, parameters: List[
-        Param]):
+        Param], sequence_number: int = 0):
         def process_signature(signature: str) -> str:
             if signature != CMessageConfig.SIGNATURE:
                 raise ValueError("Invalid signature")
@@ -152,6 +152,8 @@ class NetworkMessage:
         self._timestamp : timestamp = timestamp
         self._player_nickname : str = process_player_nickname(player_nickname)
         self._parameters : List[Param] = self._process_parameters(parameters)
+        # set by server for messages waiting for ResponseClientSuccess, 0 otherwise
+        self._sequence_number : int = sequence_number
 
     def _process_parameters(self, parameters: List[Param]) -> List[Param]:
         command = CCommandTypeEnum.get_command_by_id(self._command_id)
@@ -179,6 +181,10 @@ class NetworkMessage:
     def parameters(self) -> List[Param]:
         return self._parameters
 
+    @property
+    def sequence_number(self) -> int:
+        return self._sequence_number
+
     def get_param_value_by_name(self, param_name: str):
         for param in self._parameters:
             if param.name == param_name:
@@ -207,7 +213,7 @@ class NetworkMessage:
 
     def __str__(self):
         command_name = CCommandTypeEnum.get_command_name_from_id(self._command_id)
-        return f"NetworkMessage: {self._signature}, {self._command_id}:{command_name}, {self._timestamp}, {self._player_nickname}, {self._parameters}"
+        return f"NetworkMessage: {self._signature}, {self._command_id}:{command_name}, {self._timestamp}, {self._player_nickname}, {self._parameters}, seq {self._sequence_number}"
 
 
 
@@ -419,7 +425,7 @@ class CCommandTypeEnum(Enum):
 
     # RESPONSES SERVER->CLIENT
     ResponseServerSuccess: Command = Command(30, None, [], None)
-    ResponseServerError: Command = Command(32, None, ["message"], None)
+    ResponseServerError: Command = Command(32, None, ["code", "message"], None)
 
     ResponseServerGameList: Command = Command(33, GAME_STATE_MACHINE.ResponseServerGameList, ["gameList"],
                                               game_list_info)
@@ -443,6 +449,8 @@ class CCommandTypeEnum(Enum):
     ServerUpdateStartGame: Command = Command(41, GAME_STATE_MACHINE.ServerUpdateStartGame, [], None)
     ServerUpdateEndScore: Command = Command(42, GAME_STATE_MACHINE.ServerUpdateEndScore, ["playerName"], None)
     ServerUpdateNotEnoughPlayers: Command = Command(51, GAME_STATE_MACHINE.ServerUpdateNotEnoughPlayers, [], None)
+    ServerShutdown: Command = Command(52, None, [], None)
+    ServerAnnouncement: Command = Command(53, None, ["message"], None)
 
     ServerUpdateGameData: Command = Command(43, GAME_STATE_MACHINE.ServerUpdateGameData, ["gameData"], game_data_info)
     ServerUpdateGameList: Command = Command(44, GAME_STATE_MACHINE.ServerUpdateGameList, ["gameList"], game_list_info)
@@ -496,6 +504,7 @@ class CMessageConfig:
     TIMESTAMP_FORMAT: str = '%Y-%m-%d %H:%M:%S.%f'
     SIGNATURE: str = "KIVUPS"
     END_OF_MESSAGE: str = "\n"
+    PARAM_SEQUENCE_NUMBER: str = "seq"
     NAME_MIN_CHARS : Final = 3
     NAME_MAX_CHARS : Final = 20
 
@@ -516,6 +525,8 @@ class CNetworkConfig:
     MAX_MESSAGE_SIZE: Final = 1024
     RECONNECT_ATTEMPTS: Final = 20  # todo change
     RECONNECT_TIMEOUT_SEC: Final = 2
+    # sequence numbers of last received messages, retransmitted message with one of them is dropped
+    RECEIVED_SEQUENCE_NUMBERS_SIZE: Final = 256
 
 # endregion
 # endregion
@@ -1,57 +1,118 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
 	"gameserver/internal"
+	"gameserver/internal/config"
 	"gameserver/internal/logger"
+	"gameserver/internal/models"
 	"gameserver/internal/utils/constants"
 	"gameserver/internal/utils/errorHandeling"
-	"gameserver/internal/utils/helpers"
 	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
 	"time"
 )
 
-func initLogger() {
+func initLogger(logConfig config.LogConfig) {
 	// Initialize the logger with desired configuration
 
-	folderPath := constants.CLogsFolderPath
-
-	currentTime := time.Now().Format("2006-01-02_15-04-05")
-	filePath := folderPath + "/log_" + currentTime + ".txt"
-
-	config := logger.LoggerConfig{
-		LogToFile:       true,
-		FilePath:        filePath,
-		UseJSONFormat:   false,
-		LogLevel:        "debug",
-		EnableCaller:    true,
+	loggerConfig := logger.LoggerConfig{
+		LogToFile:  logConfig.ToFile,
+		FolderPath: logConfig.Folder,
+		Rotation: logger.RotationConfig{
+			MaxSize:  int64(logConfig.MaxSizeMB) * 1024 * 1024,
+			MaxAge:   time.Duration(logConfig.MaxAgeHours) * time.Hour,
+			MaxFiles: logConfig.MaxFiles,
+			Compress: logConfig.Compress,
+		},
+		UseJSONFormat:   logConfig.JSONFormat,
+		LogLevel:        logConfig.Level,
+		EnableCaller:    logConfig.Caller,
 		TimestampFormat: "2006-01-02 15:04:05,000",
 	}
 
-	err := logger.InitLogger(config)
+	err := logger.InitLogger(loggerConfig)
 	if err != nil {
-		errorHandeling.AssertError(err)
+		errorHandeling.AssertError(logger.Log, err)
 	}
 }
 
-func setServerConfig(filepath string) {
-	ip, port, err := helpers.ReadConfigFile(filepath)
+// readConfig reads config file given by -config flag or GAMESERVER_CONFIG, environment and the other flags override it.
+// It returns also loader which reads the same sources again for reload.
+func readConfig(args []string) (config.Config, internal.ConfigLoader) {
+	flags := flag.NewFlagSet(filepath.Base(os.Args[0]), flag.ExitOnError)
+	configPath := flags.String("config", constants.CConfigFilePath, "path of config file, empty uses only defaults, environment and flags, also "+config.CEnvironmentConfigPath)
+	overrides := config.BindFlags(flags)
+	_ = flags.Parse(args)
+
+	path := *configPath
+	isConfigFlagSet := false
+	flags.Visit(func(f *flag.Flag) {
+		isConfigFlagSet = isConfigFlagSet || f.Name == "config"
+	})
+	if environmentPath, ok := os.LookupEnv(config.CEnvironmentConfigPath); ok && !isConfigFlagSet {
+		path = environmentPath
+	}
+
+	load := func() (config.Config, error) {
+		return config.Load(path, os.Environ(), overrides)
+	}
+
+	serverConfig, err := load()
 	if err != nil {
-		errorHandeling.PrintError(err)
-		log.Fatalf("Failed to read server config: %v", err)
+		log.Fatalf("Failed to read config: %v", err)
 	}
 
-	// Set the server configuration using the read IP and port
-	constants.CConIPadress = ip
-	constants.CConnPort = port
+	return serverConfig, load
 }
 
 func main() {
 
-	setServerConfig(constants.CConfigFilePath)
+	serverConfig, loadConfig := readConfig(os.Args[1:])
 
-	initLogger()
+	initLogger(serverConfig.Log)
 
 	logger.Log.Info("Starting server...")
 
-	internal.StartServer()
+	instanceConfig, err := internal.CreateServerConfig(serverConfig)
+	if err != nil {
+		log.Fatalf("Failed to create server config: %v", err)
+	}
+	instanceConfig.Log = logger.Log
+
+	server := models.CreateServer(instanceConfig)
+	internal.EnableReload(server, serverConfig, loadConfig)
+
+	// SIGINT/SIGTERM start graceful shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// SIGHUP reloads config, the result is logged by reload
+	reloadSignals := make(chan os.Signal, 1)
+	signal.Notify(reloadSignals, syscall.SIGHUP)
+	defer signal.Stop(reloadSignals)
+	go func() {
+		for range reloadSignals {
+			_, _ = server.Reload()
+		}
+	}()
+
+	err = internal.StartServer(ctx, server)
+	if err != nil {
+		fmt.Println("Error running server:", err)
+	}
+
+	closeErr := logger.Close()
+	if closeErr != nil {
+		fmt.Println("Error closing log file:", closeErr)
+	}
+
+	if err != nil {
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"gameserver/internal/models"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// replay sends messages received in one session of a spilled journal to a server again,
+// with the same gaps between them unless -speed is changed
+func main() {
+	file := flag.String("file", "", "journal file, .jsonl or .jsonl.gz")
+	address := flag.String("address", "localhost:10000", "server address")
+	sessionID := flag.Int("session", 0, "session id to replay")
+	speed := flag.Float64("speed", 1, "replay speed, 0 sends without waiting")
+	flag.Parse()
+
+	if *file == "" || *sessionID == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	entries, err := readEntries(*file, *sessionID)
+	if err != nil {
+		log.Fatalf("Failed to read journal: %v", err)
+	}
+	if len(entries) == 0 {
+		log.Fatalf("No received messages of session %d", *sessionID)
+	}
+
+	connection, err := net.Dial("tcp", *address)
+	if err != nil {
+		log.Fatalf("Failed to connect: %v", err)
+	}
+	defer connection.Close()
+
+	// answers of the server are only printed
+	go func() {
+		_, _ = io.Copy(os.Stdout, connection)
+	}()
+
+	previous := entries[0].Time
+	for _, entry := range entries {
+		if *speed > 0 {
+			time.Sleep(time.Duration(float64(entry.Time.Sub(previous)) / *speed))
+		}
+		previous = entry.Time
+
+		fmt.Printf("> %s\n", entry.Raw)
+		_, err = connection.Write([]byte(entry.Raw + "\n"))
+		if err != nil {
+			log.Fatalf("Failed to send message: %v", err)
+		}
+	}
+
+	// give server time to answer the last message
+	time.Sleep(time.Second)
+}
+
+// readEntries returns received messages of the session in order of the file
+func readEntries(path string, sessionID int) ([]models.JournalEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		gzipReader, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, err
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	}
+
+	var entries []models.JournalEntry
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry models.JournalEntry
+		err := json.Unmarshal(scanner.Bytes(), &entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid journal line: %w", err)
+		}
+		if entry.SessionID == sessionID && entry.Direction == strings.ToLower(models.Received.String()) {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, scanner.Err()
+}
@@ -0,0 +1,178 @@
+package admin
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gameserver/internal/models"
+	"gameserver/internal/utils/errorHandeling"
+	"net"
+	"os"
+	"strings"
+)
+
+// region CONSTANTS
+const (
+	cLineMaxSize = 4096
+
+	cCommandAuth = "auth"
+	cCommandQuit = "quit"
+)
+
+//endregion
+
+//region DATA STRUCTURES
+
+// response is written as one JSON line for every admin command
+type response struct {
+	OK     bool        `json:"ok"`
+	Error  string      `json:"error,omitempty"`
+	Result interface{} `json:"result,omitempty"`
+}
+
+//endregion
+
+//region LISTEN
+
+// Listen opens admin listener when admin is enabled, stale unix socket of previous run is removed first
+func Listen(server *models.Server) error {
+	config := server.Config
+	if !config.AdminEnabled {
+		return nil
+	}
+
+	if config.AdminNetwork == "unix" {
+		err := os.Remove(config.AdminAddress)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			errorHandeling.PrintError(server.Log, err)
+			return fmt.Errorf("Error removing admin socket: %w", err)
+		}
+	}
+
+	ln, err := net.Listen(config.AdminNetwork, config.AdminAddress)
+	if err != nil {
+		errorHandeling.PrintError(server.Log, err)
+		return fmt.Errorf("Error listening admin: %w", err)
+	}
+
+	// only the owner of the server process may connect to the socket
+	if config.AdminNetwork == "unix" {
+		err = os.Chmod(config.AdminAddress, 0600)
+		if err != nil {
+			_ = ln.Close()
+			errorHandeling.PrintError(server.Log, err)
+			return fmt.Errorf("Error setting admin socket permissions: %w", err)
+		}
+	}
+
+	server.SetAdminListener(ln)
+	return nil
+}
+
+// Serve accepts admin connections on listener opened by Listen, it returns after server.Close
+func Serve(server *models.Server) {
+	ln := server.GetAdminListener()
+	if ln == nil {
+		return
+	}
+	fmt.Println("Admin is listening on " + ln.Addr().String())
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			errorHandeling.PrintError(server.Log, err)
+			continue
+		}
+		go handleConnection(server, conn)
+	}
+}
+
+//endregion
+
+//region CONNECTION
+
+// handleConnection reads one command per line, the first one has to be "auth <token>"
+func handleConnection(server *models.Server, conn net.Conn) {
+	server.Log.Info("ADMIN: New connection from " + conn.RemoteAddr().String())
+
+	stopClose := context.AfterFunc(server.Context(), func() {
+		_ = conn.Close()
+	})
+	defer stopClose()
+	defer conn.Close()
+
+	defer errorHandeling.RecoverPanic(server.Log, server.GetSettings().Debug, nil)
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, cLineMaxSize), cLineMaxSize)
+	writer := bufio.NewWriter(conn)
+
+	isAuthenticated := false
+	for scanner.Scan() {
+		name, args := parseLine(scanner.Text())
+		if name == "" {
+			continue
+		}
+
+		var result response
+		switch {
+		case name == cCommandQuit:
+			return
+		case name == cCommandAuth:
+			isAuthenticated = isValidToken(server, args)
+			if !isAuthenticated {
+				server.Log.Warn("ADMIN: Invalid token from " + conn.RemoteAddr().String())
+				_ = writeResponse(writer, response{Error: "invalid token"})
+				return
+			}
+			result = response{OK: true}
+		case !isAuthenticated:
+			_ = writeResponse(writer, response{Error: "not authenticated"})
+			return
+		default:
+			server.Log.Infof("ADMIN: %s %s", name, strings.Join(args, " "))
+			result = executeCommand(server, name, args)
+		}
+
+		err := writeResponse(writer, result)
+		if err != nil {
+			errorHandeling.PrintError(server.Log, err)
+			return
+		}
+	}
+}
+
+func parseLine(line string) (string, []string) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return "", nil
+	}
+	return strings.ToLower(fields[0]), fields[1:]
+}
+
+func isValidToken(server *models.Server, args []string) bool {
+	if len(args) != 1 {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(args[0]), []byte(server.Config.AdminToken)) == 1
+}
+
+func writeResponse(writer *bufio.Writer, result response) error {
+	// usages contain <name>, the output is read by people
+	encoder := json.NewEncoder(writer)
+	encoder.SetEscapeHTML(false)
+
+	err := encoder.Encode(result)
+	if err != nil {
+		return fmt.Errorf("error writing admin response: %w", err)
+	}
+	return writer.Flush()
+}
+
+//endregion
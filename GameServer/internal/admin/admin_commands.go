@@ -0,0 +1,331 @@
+package admin
+
+import (
+	"fmt"
+	"gameserver/internal/command_processing"
+	"gameserver/internal/models"
+	"gameserver/internal/utils/constants"
+	"github.com/sirupsen/logrus"
+	"sort"
+	"strings"
+	"time"
+)
+
+//region DATA STRUCTURES
+
+type commandHandler func(server *models.Server, args []string) (interface{}, error)
+
+// commandInfo declares one admin command, MinArgs is checked before Handler runs
+type commandInfo struct {
+	Usage       string
+	Description string
+	MinArgs     int
+	Handler     commandHandler
+}
+
+type sessionInfo struct {
+	Nickname   string `json:"nickname"`
+	State      string `json:"state"`
+	Connection string `json:"connection"`
+	Game       string `json:"game,omitempty"`
+	Address    string `json:"address,omitempty"`
+	IsInTurn   bool   `json:"is_in_turn"`
+}
+
+type gameInfo struct {
+	Name       string   `json:"name"`
+	State      string   `json:"state"`
+	MaxPlayers int      `json:"max_players"`
+	Round      int      `json:"round"`
+	Players    []string `json:"players"`
+}
+
+// seatInfo is one row of GameData as clients get it in ServerUpdateGameData
+type seatInfo struct {
+	Nickname    string `json:"nickname"`
+	IsConnected bool   `json:"is_connected"`
+	Score       int    `json:"score"`
+	IsTurn      bool   `json:"is_turn"`
+}
+
+type gameDetail struct {
+	gameInfo
+	GameData []seatInfo                      `json:"game_data"`
+	History  []models.PlayerGameDataSnapshot `json:"history"`
+}
+
+//endregion
+
+// commands holds every admin command by name
+var commands map[string]commandInfo
+
+func init() {
+	// help lists commands, so the map is filled in init to avoid initialization cycle
+	commands = map[string]commandInfo{
+		"help":        {Usage: "help", Description: "list admin commands", Handler: processHelp},
+		"sessions":    {Usage: "sessions", Description: "list players and their connections", Handler: processSessions},
+		"games":       {Usage: "games", Description: "list games", Handler: processGames},
+		"game":        {Usage: "game <name>", Description: "show game data and turn history", MinArgs: 1, Handler: processGame},
+		"player":      {Usage: "player <nickname>", Description: "show state of player", MinArgs: 1, Handler: processPlayer},
+		"kick":        {Usage: "kick <nickname>", Description: "disconnect player and remove him from server", MinArgs: 1, Handler: processKick},
+		"endgame":     {Usage: "endgame <name>", Description: "end running game without a winner", MinArgs: 1, Handler: processEndGame},
+		"announce":    {Usage: "announce <message>", Description: "send message to all connected players", MinArgs: 1, Handler: processAnnounce},
+		"maintenance": {Usage: "maintenance [on|off]", Description: "show or switch maintenance mode", Handler: processMaintenance},
+		"loglevel":    {Usage: "loglevel [debug|info|warn|error]", Description: "show or change log level without restart", Handler: processLogLevel},
+		"reload":      {Usage: "reload", Description: "read config file again and apply keys which do not need restart", Handler: processReload},
+		"journal":     {Usage: "journal <nickname> [from] [to]", Description: "show sent and received messages of player, times are RFC3339 or duration ago like 10m", MinArgs: 1, Handler: processJournal},
+	}
+}
+
+//region FUNCTIONS
+
+func executeCommand(server *models.Server, name string, args []string) response {
+	command, ok := commands[name]
+	if !ok {
+		return response{Error: "unknown command, see help"}
+	}
+	if len(args) < command.MinArgs {
+		return response{Error: "usage: " + command.Usage}
+	}
+
+	result, err := command.Handler(server, args)
+	if err != nil {
+		return response{Error: err.Error()}
+	}
+	return response{OK: true, Result: result}
+}
+
+func getGame(server *models.Server, name string) (*models.Game, error) {
+	game, err := server.GameList.GetItemByName(name)
+	if err != nil || game == nil {
+		return nil, models.ErrGameNotFound
+	}
+	return game, nil
+}
+
+func getPlayer(server *models.Server, nickname string) (*models.Player, error) {
+	player, err := server.PlayerList.GetItem(nickname)
+	if err != nil || player == nil {
+		return nil, models.ErrPlayerNotFound
+	}
+	return player, nil
+}
+
+func getSessionInfo(server *models.Server, player *models.Player) sessionInfo {
+	info := sessionInfo{
+		Nickname:   player.GetNickname(),
+		State:      player.GetCurrentStateName(),
+		Connection: player.GetConnectionState().String(),
+		IsInTurn:   player.IsInTurn(),
+	}
+
+	if game := server.GameList.GetPlayersGame(player); game != nil {
+		info.Game = game.GetName()
+	}
+	if connection := player.GetConnectionInfo().Connection; connection != nil {
+		info.Address = connection.RemoteAddr().String()
+	}
+
+	return info
+}
+
+func getGameInfo(game *models.Game) gameInfo {
+	info := gameInfo{
+		Name:       game.GetName(),
+		State:      game.GetState().String(),
+		MaxPlayers: game.GetMaxPlayers(),
+		Round:      game.GetRoundNum(),
+		Players:    []string{},
+	}
+	for _, player := range game.GetPlayers() {
+		info.Players = append(info.Players, player.GetNickname())
+	}
+	return info
+}
+
+// parseJournalTime parses RFC3339 time or duration before now, e.g. 15m
+func parseJournalTime(value string) (time.Time, error) {
+	moment, err := time.Parse(time.RFC3339, value)
+	if err == nil {
+		return moment, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		return time.Time{}, fmt.Errorf("invalid time %q, use RFC3339 or duration like 10m", value)
+	}
+	return time.Now().Add(-duration), nil
+}
+
+//endregion
+
+//region COMMANDS
+
+func processHelp(server *models.Server, args []string) (interface{}, error) {
+	var lines []string
+	for _, command := range commands {
+		lines = append(lines, command.Usage+" - "+command.Description)
+	}
+	sort.Strings(lines)
+	return lines, nil
+}
+
+func processSessions(server *models.Server, args []string) (interface{}, error) {
+	sessions := []sessionInfo{}
+	for _, player := range server.PlayerList.GetValuesArray() {
+		sessions = append(sessions, getSessionInfo(server, player))
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].Nickname < sessions[j].Nickname })
+	return sessions, nil
+}
+
+func processGames(server *models.Server, args []string) (interface{}, error) {
+	games := []gameInfo{}
+	for _, game := range server.GameList.GetValuesArray() {
+		games = append(games, getGameInfo(game))
+	}
+	sort.Slice(games, func(i, j int) bool { return games[i].Name < games[j].Name })
+	return games, nil
+}
+
+func processGame(server *models.Server, args []string) (interface{}, error) {
+	game, err := getGame(server, args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	detail := gameDetail{GameData: []seatInfo{}}
+	err = game.Execute(func() error {
+		detail.gameInfo = getGameInfo(game)
+		detail.History = game.CreateSnapshot().Players
+
+		// game which has not started has no turn player and no scores
+		if game.GetState() != models.Running {
+			return nil
+		}
+
+		gameData, err := game.GetGameData()
+		if err != nil {
+			return err
+		}
+		for _, playerGameData := range gameData.PlayerGameDataArr {
+			detail.GameData = append(detail.GameData, seatInfo{
+				Nickname:    playerGameData.Player.GetNickname(),
+				IsConnected: playerGameData.Player.IsConnected(),
+				Score:       playerGameData.Score,
+				IsTurn:      playerGameData.Player == gameData.TurnPlayer,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return detail, nil
+}
+
+func processPlayer(server *models.Server, args []string) (interface{}, error) {
+	player, err := getPlayer(server, args[0])
+	if err != nil {
+		return nil, err
+	}
+	return getSessionInfo(server, player), nil
+}
+
+func processKick(server *models.Server, args []string) (interface{}, error) {
+	player, err := getPlayer(server, args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	err = command_processing.KickPlayer(server, player)
+	if err != nil {
+		return nil, fmt.Errorf("cannot kick player: %w", err)
+	}
+	return nil, nil
+}
+
+func processEndGame(server *models.Server, args []string) (interface{}, error) {
+	game, err := getGame(server, args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	err = command_processing.EndGame(server, game)
+	if err != nil {
+		return nil, fmt.Errorf("cannot end game: %w", err)
+	}
+	return nil, nil
+}
+
+func processAnnounce(server *models.Server, args []string) (interface{}, error) {
+	message := strings.Join(args, " ")
+	if len(message) > constants.CAnnouncementMaxLength {
+		return nil, fmt.Errorf("message is longer than %d characters", constants.CAnnouncementMaxLength)
+	}
+	if strings.ContainsAny(message, constants.CAnnouncementForbiddenChars) {
+		return nil, fmt.Errorf("message must not contain any of %s", constants.CAnnouncementForbiddenChars)
+	}
+
+	err := command_processing.Announce(server, message)
+	if err != nil {
+		return nil, fmt.Errorf("cannot send announcement: %w", err)
+	}
+	return nil, nil
+}
+
+func processMaintenance(server *models.Server, args []string) (interface{}, error) {
+	if len(args) > 0 {
+		switch strings.ToLower(args[0]) {
+		case "on":
+			server.SetMaintenance(true)
+		case "off":
+			server.SetMaintenance(false)
+		default:
+			return nil, fmt.Errorf("usage: maintenance [on|off]")
+		}
+		server.Log.Infof("ADMIN: Maintenance mode %v", server.IsMaintenance())
+	}
+
+	return map[string]bool{"maintenance": server.IsMaintenance()}, nil
+}
+
+func processLogLevel(server *models.Server, args []string) (interface{}, error) {
+	if len(args) > 0 {
+		level, err := logrus.ParseLevel(strings.ToLower(args[0]))
+		if err != nil {
+			return nil, fmt.Errorf("usage: loglevel [debug|info|warn|error]")
+		}
+		server.Log.SetLevel(level)
+		server.Log.Infof("ADMIN: Log level set to %s", level)
+	}
+
+	return map[string]string{"level": server.Log.GetLevel().String()}, nil
+}
+
+func processReload(server *models.Server, args []string) (interface{}, error) {
+	return server.Reload()
+}
+
+func processJournal(server *models.Server, args []string) (interface{}, error) {
+	var from, to time.Time
+	var err error
+
+	if len(args) > 1 {
+		from, err = parseJournalTime(args[1])
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(args) > 2 {
+		to, err = parseJournalTime(args[2])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return server.Journal.Query(args[0], from, to), nil
+}
+
+//endregion
@@ -0,0 +1,95 @@
+package command_processing
+
+import (
+	"fmt"
+	"gameserver/internal/models"
+	"gameserver/internal/network"
+	"gameserver/internal/utils/constants"
+	"gameserver/internal/utils/errorHandeling"
+	"gameserver/internal/utils/helpers"
+)
+
+//region ADMIN ACTIONS
+
+// KickPlayer sends ErrorCodeKicked to the player and removes him from PlayerList and his game right away,
+// the player may log in again as a new player
+func KickPlayer(server *models.Server, player *models.Player) error {
+	return server.GameList.ExecuteInPlayersGame(player, func() error {
+		connection := player.GetConnectionInfo().Connection
+		if player.IsConnected() {
+			responseInfo := models.MessageInfo{
+				ConnectionInfo: player.GetConnectionInfo(),
+				PlayerNickname: player.GetNickname(),
+			}
+			err := network.SendResponseServerError(server, responseInfo, constants.ErrorCodeKicked)
+			if err != nil {
+				errorHandeling.PrintError(server.Log, err)
+			}
+		}
+
+		server.Scheduler.Cancel(pingTimerKey(player))
+		network.CancelResponseTimeout(server, player)
+		network.CancelTotalDisconnect(server, player)
+		network.ImidiateDisconnectPlayer(server, player.GetNickname())
+
+		// players outside of a game stay in PlayerList after total disconnect
+		err := helpers.RemovePlayerFromLists(server, player)
+		if err != nil {
+			errorHandeling.PrintError(server.Log, err)
+			return fmt.Errorf("Error removing player: %w", err)
+		}
+
+		// connection handler of the player ends on the closed connection
+		err = network.CloseConnection(server, connection)
+		if err != nil {
+			errorHandeling.PrintError(server.Log, err)
+			return fmt.Errorf("Error closing connection: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// EndGame ends running game without a winner, connected players get ServerUpdateNotEnoughPlayers and return to lobby
+func EndGame(server *models.Server, game *models.Game) error {
+	return game.Execute(func() error {
+		if !server.GameList.HasValue(game) {
+			return models.ErrGameNotFound
+		}
+		if game.GetState() != models.Running {
+			return models.ErrGameNotStarted
+		}
+
+		server.Scheduler.Cancel(turnTimerKey(game))
+		server.Scheduler.Cancel(turnDeadlineKey(game))
+
+		playerList := helpers.PlayerListGetActivePlayers(game.GetPlayers())
+		err := network.CommunicationServerUpdateNotEnoughPlayers(server, playerList)
+		if err != nil {
+			errorHandeling.PrintError(server.Log, err)
+			return fmt.Errorf("Error sending response: %w", err)
+		}
+
+		err = server.GameList.RemoveItem(game)
+		if err != nil {
+			errorHandeling.PrintError(server.Log, err)
+			return fmt.Errorf("Error removing game: %w", err)
+		}
+
+		err = server.Events.Publish(models.GameAborted{Game: game})
+		if err != nil {
+			errorHandeling.PrintError(server.Log, err)
+			return fmt.Errorf("Error sending response: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// Announce sends message to all connected players
+func Announce(server *models.Server, message string) error {
+	playerList := helpers.PlayerListGetActivePlayers(server.PlayerList.GetValuesArray())
+	return network.CommunicationServerAnnouncement(server, playerList, message)
+}
+
+//endregion
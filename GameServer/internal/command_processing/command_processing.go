@@ -1,9 +1,11 @@
 package command_processing
 
 import (
+	"errors"
 	"fmt"
 	"gameserver/internal/command_processing/command_processing_utils"
 	"gameserver/internal/logger"
+	"gameserver/internal/metrics"
 	"gameserver/internal/models"
 	"gameserver/internal/models/state_machine"
 	"gameserver/internal/network"
@@ -11,132 +13,184 @@ import (
 	"gameserver/internal/utils/constants"
 	"gameserver/internal/utils/errorHandeling"
 	"gameserver/internal/utils/helpers"
-	"net"
 	"time"
 )
 
-// CommandsHandlers is a map of valid commands and their information.
-var CommandsHandlers = map[int]CommandInfo{
-	//1: {"PLAYER_LOGIN", processPlayerLogin} first case in if in ProcessMessage
-	constants.CGCommands.ClientCreateGame.CommandID: {processClientCreateGame, constants.CGCommands.ClientCreateGame},
-	constants.CGCommands.ClientJoinGame.CommandID:   {processClientJoinGame, constants.CGCommands.ClientJoinGame},
-	constants.CGCommands.ClientStartGame.CommandID:  {processClientStartGame, constants.CGCommands.ClientStartGame},
-	constants.CGCommands.ClientLogout.CommandID:     {processClientPlayerLogout, constants.CGCommands.ClientLogout},
-	constants.CGCommands.ClientReconnect.CommandID:  {processClientReconnect, constants.CGCommands.ClientReconnect},
-	//constants.CGCommands.ResponseClientSuccess.CommandID: {processResponseClientSucess, constants.CGCommands.ResponseClientSuccess},
-	constants.CGCommands.ClientRollDice.CommandID:      {processClientRollDice, constants.CGCommands.ClientRollDice},
-	constants.CGCommands.ClientSelectedCubes.CommandID: {processClientSelectedCubes, constants.CGCommands.ClientSelectedCubes},
-}
+// delay of repeated ServerUpdateGameData after turn start
+const cTurnUpdateDelay = 2 * time.Second
 
 //endregion
 
-//region STRUCTURES
-
-// CommandInfo represents information about a command.
-type CommandInfo struct {
-	Handler func(player *models.Player, params []constants.Params, command constants.Command) error
-	Command constants.Command
-}
+func ProcessMessage(message models.Message, session *models.Session) error {
+	log := session.Log().WithField(logger.FieldCorrelationID, message.CorrelationID)
+	log.Debugf("Starting to process: %v", message)
 
-//endregion
+	server := session.GetServer()
+	conn := session.GetConnection()
 
-func ProcessMessage(message models.Message, conn net.Conn) error {
-	logger.Log.Debugf("Starting to process: %v", message)
+	// waiting for the game goroutine is part of processing latency
+	defer metrics.ObserveSince(server.Metrics.ProcessingDuration, time.Now(), metrics.CommandLabels(message.CommandID)...)
 
 	// nested function handle invalid message format
-	handleInvalidMessageFormat := func(player *models.Player, responseInfo models.MessageInfo) error {
-		logger.Log.Errorf("Invalid message format")
-		err := dissconectPlayer(player)
+	handleInvalidMessageFormat := func(player *models.Player, code constants.ErrorCode) error {
+		server.PlayerLog(player).Errorf("Invalid message format")
+		err := dissconectPlayer(server, player, code)
 		if err != nil {
-			errorHandeling.PrintError(err)
+			errorHandeling.PrintError(server.Log, err)
 			return fmt.Errorf("error sending response: %w", err)
 		}
-		errorHandeling.PrintError(fmt.Errorf("invalid command or incorrect number of arguments"))
+		errorHandeling.PrintError(server.Log, fmt.Errorf("invalid command or incorrect number of arguments"))
 		return nil
 	}
 
-	logger.Log.Debugf("Received message: %v", message)
+	log.Debugf("Received message: %v", message)
 
 	//Check if valid signature
 	if message.Signature != constants.CMessageSignature {
 		return fmt.Errorf("invalid signature")
 	}
 
+	// acks answer messages of the server, so only messages the client sends by itself are limited
+	settings := server.GetSettings()
+	if message.CommandID != constants.CGCommands.ResponseClientSuccess.CommandID && !session.AllowMessage(settings.RateLimitMessages, settings.RateLimitWindow) {
+		log.Warnf("Rate limit of %d messages per %s exceeded", settings.RateLimitMessages, settings.RateLimitWindow)
+		return processRateLimited(server, session)
+	}
+
 	commandID := message.CommandID
 	playerNickname := message.PlayerNickname
 	timeStamp := message.TimeStamp
-	params := message.Parameters
+	clientMessageID, params := parser.ConvertParamClientMessageID(message.Parameters)
 
 	connectionInfo := models.ConnectionInfo{
 		Connection: conn,
 		TimeStamp:  timeStamp,
+		SessionID:  session.GetID(),
 	}
 
-	//SPECIAL CASE: If player_login
-	if commandID == constants.CGCommands.ClientLogin.CommandID {
-		if !isParamsEmpty(params) {
-			errorHandeling.PrintError(fmt.Errorf("invalid number of arguments"))
+	commandInfo, ok := getClientCommandInfo(commandID)
+
+	//SPECIAL CASE: player_login, there is no player yet
+	if ok && commandInfo.Dispatch == DispatchLogin {
+		args, err := parser.ConvertParams(params, commandInfo.Params, settings.NameLength)
+		if err != nil {
+			errorHandeling.PrintError(server.Log, err)
 			return fmt.Errorf("invalid number of arguments")
 		}
-		err := processPlayerLogin(playerNickname, connectionInfo, constants.CGCommands.ClientLogin)
+
+		err = commandInfo.Handler(CommandRequest{
+			Server:          server,
+			Session:         session,
+			PlayerNickname:  playerNickname,
+			Command:         commandInfo.Command,
+			Args:            args,
+			ConnectionInfo:  connectionInfo,
+			ClientMessageID: clientMessageID,
+			CorrelationID:   message.CorrelationID,
+		})
 		if err != nil {
-			errorHandeling.PrintError(err)
+			errorHandeling.PrintError(server.Log, err)
 			return fmt.Errorf("Error sending response: %w", err)
 		}
 		return nil
 	}
 
 	//Get player
-	player, err := models.GetInstancePlayerList().GetItem(playerNickname)
+	player, err := server.PlayerList.GetItem(playerNickname)
 	if err != nil {
-		logger.Log.Errorf("Error getting player: %v", err)
-		errorHandeling.PrintError(err)
+		log.Errorf("Error getting player: %v", err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("invalid command or incorrect number of arguments")
 	}
 	if player == nil {
-		logger.Log.Errorf("Error player is nil")
+		log.Errorf("Error player is nil")
 		//close connection
-		err := network.CloseConnection(conn)
+		err := network.CloseConnection(server, conn)
 		if err != nil {
-			errorHandeling.PrintError(err)
+			errorHandeling.PrintError(server.Log, err)
 			return fmt.Errorf("Error closing connection: %w", err)
 		}
 	}
 
 	//Set connection to player
-	player.SetConnectionInfo(connectionInfo)
+	server.PlayerList.SetPlayerConnection(player, connectionInfo)
+	session.SetPlayer(player)
 
-	responseInfo := models.MessageInfo{
-		ConnectionInfo: connectionInfo,
-		PlayerNickname: playerNickname,
+	// messages sent while processing carry correlation ID of the received message
+	player.SetCorrelationID(message.CorrelationID)
+	defer player.SetCorrelationID("")
+
+	//SPECIAL CASE: check if commandID valid
+	if !ok {
+		return handleInvalidMessageFormat(player, constants.ErrorCodeUnknownCommand)
+	}
+
+	args, paramsErr := parser.ConvertParams(params, commandInfo.Params, settings.NameLength)
+	request := CommandRequest{
+		Server:          server,
+		Session:         session,
+		PlayerNickname:  playerNickname,
+		Player:          player,
+		Command:         commandInfo.Command,
+		Args:            args,
+		ConnectionInfo:  connectionInfo,
+		ClientMessageID: clientMessageID,
+		CorrelationID:   message.CorrelationID,
 	}
 
 	// SPECIAL CASE: Response Success
-	if commandID == constants.CGCommands.ResponseClientSuccess.CommandID {
-		err = processResponseClientSucess(player, timeStamp)
+	if commandInfo.Dispatch == DispatchAck {
+		if paramsErr != nil {
+			server.PlayerLog(player).Errorf("Error converting params: %v", paramsErr)
+			return handleInvalidMessageFormat(player, constants.ErrorCodeBadParams)
+		}
+
+		err = commandInfo.Handler(request)
 		if err != nil {
-			errorHandeling.PrintError(err)
+			errorHandeling.PrintError(server.Log, err)
 			return fmt.Errorf("invalid command or incorrect number of arguments")
 		}
 		return nil
 	}
 
-	commandInfo, err := getCommandInfo(commandID)
+	// SPECIAL CASE: retried command is answered from cache instead of being executed twice
+	if clientMessageID != "" {
+		isProcessed, err := processDuplicateClientMessage(server, player, clientMessageID, commandID)
+		if isProcessed {
+			return err
+		}
 
-	//SPECIAL CASE: check if commandID valid
-	if err != nil {
-		return handleInvalidMessageFormat(player, responseInfo)
+		player.StartClientMessage(clientMessageID, commandID)
+		defer player.FinishClientMessage(server.GetSettings().ClientMessageCacheSize)
 	}
 
-	// SPECIAL CASE: check params are valid
-	if !isValidParamsNames(params, commandInfo.Command.ParamsNames) {
-		return handleInvalidMessageFormat(player, responseInfo)
-	}
+	// Call the corresponding handler function, commands of players in game run on the game goroutine
+	err = server.GameList.ExecuteInPlayersGame(player, func() error {
+		// SPECIAL CASE: check params are valid
+		if paramsErr != nil {
+			server.PlayerLog(player).Errorf("Error converting params: %v", paramsErr)
+			if commandInfo.HandleInvalidParams != nil {
+				return commandInfo.HandleInvalidParams(request)
+			}
+			return handleInvalidMessageFormat(player, constants.ErrorCodeBadParams)
+		}
 
-	// Call the corresponding handler function
-	err = commandInfo.Handler(player, params, commandInfo.Command)
+		//State machine check
+		if commandInfo.RequiredTrigger != nil {
+			canFire, err := player.GetStateMachine().CanFire(commandInfo.RequiredTrigger)
+			if err != nil {
+				errorHandeling.PrintError(server.Log, err)
+				return fmt.Errorf("cannot fire %s: %w", commandInfo.Name, err)
+			}
+			if !canFire {
+				return _handleCannotFire(server, player)
+			}
+		}
+
+		return commandInfo.Handler(request)
+	})
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("invalid command or incorrect number of arguments")
 	}
 
@@ -145,48 +199,59 @@ func ProcessMessage(message models.Message, conn net.Conn) error {
 
 // region UTILS FUNCTIONS
 
-func isParamsEmpty(params []constants.Params) bool {
-	if len(params) == 1 && params[0].Name == "" && params[0].Value == "" {
-		return true
+// processDuplicateClientMessage resends cached responses if client message has already been processed
+// Return: bool isProcessed - if message was duplicate
+func processDuplicateClientMessage(server *models.Server, player *models.Player, clientMessageID string, commandID int) (bool, error) {
+	responses, isDuplicate := player.GetClientMessageResponses(clientMessageID, commandID)
+	if !isDuplicate {
+		return false, nil
 	}
-	return false
-}
 
-func isValidParamsNames(params []constants.Params, names []string) bool {
-	if len(params) != len(names) {
-		return false
-	}
+	server.PlayerLog(player).Infof("Duplicate client message %s from %s, sending cached responses", clientMessageID, player.GetNickname())
 
-	for i := 0; i < len(params); i++ {
-		if params[i].Name != names[i] {
-			return false
-		}
+	err := network.SendCachedResponses(server, player.GetConnectionInfo().Connection, responses)
+	if err != nil {
+		errorHandeling.PrintError(server.Log, err)
+		return true, fmt.Errorf("Error sending response: %w", err)
 	}
 
-	return true
+	return true, nil
 }
 
-func getCommandInfo(commandID int) (CommandInfo, error) {
-	commandInfo, exists := CommandsHandlers[commandID]
-	if !exists {
-		// The commandID is not valid.
-		return CommandInfo{}, fmt.Errorf("invalid commandID")
+// processRateLimited disconnects player of the session with ErrorCodeRateLimited,
+// connection without logged in player is only closed
+func processRateLimited(server *models.Server, session *models.Session) error {
+	player := session.GetPlayer()
+	if player == nil || !player.IsConnected() {
+		err := network.CloseConnection(server, session.GetConnection())
+		if err != nil {
+			errorHandeling.PrintError(server.Log, err)
+			return fmt.Errorf("Error closing connection: %w", err)
+		}
+		return nil
 	}
 
-	// Check if the number of arguments matches the expected count.
-	return commandInfo, nil
+	err := server.GameList.ExecuteInPlayersGame(player, func() error {
+		return dissconectPlayer(server, player, constants.ErrorCodeRateLimited)
+	})
+	if err != nil {
+		errorHandeling.PrintError(server.Log, err)
+		return fmt.Errorf("Error disconnecting player: %w", err)
+	}
+	return nil
 }
 
-func dissconectPlayer(player *models.Player) error {
+// dissconectPlayer sends error code to the player and disconnects him
+func dissconectPlayer(server *models.Server, player *models.Player, code constants.ErrorCode) error {
 	responseInfo := models.MessageInfo{
 		ConnectionInfo: player.GetConnectionInfo(),
 		PlayerNickname: player.GetNickname(),
 	}
 
 	//send response
-	err := network.ProcessSendResponseServerErrorDisconnected(responseInfo)
+	err := network.ProcessSendResponseServerError(server, responseInfo, code)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("Error sending response: %w", err)
 	}
 
@@ -208,189 +273,252 @@ func cubesCanBePlayed(values []int) bool {
 
 //region PROCESS FUNCTIONS
 
-func processResponseClientSucess(player *models.Player, timeStamp string) error {
-	err := command_processing_utils.ProcessResponseClientSucessByPlayer(player, timeStamp)
+func processResponseClientSucess(request CommandRequest) error {
+	server, player := request.Server, request.Player
+
+	// 0 when client acks by timestamp only
+	sequenceNumber := request.Args.Int("seq")
+	err := command_processing_utils.ProcessResponseClientSucessByPlayer(server, player, sequenceNumber, request.ConnectionInfo.TimeStamp)
 	if err != nil {
 		//disconnect player
-		logger.Log.Errorf("Error processing response success: %v", err)
-		err = dissconectPlayer(player)
+		server.PlayerLog(player).Errorf("Error processing response success: %v", err)
+		err = dissconectPlayer(server, player, constants.ErrorCodeInvalidState)
 		if err != nil {
-			errorHandeling.PrintError(err)
+			errorHandeling.PrintError(server.Log, err)
 			return fmt.Errorf("Error sending response: %w", err)
 		}
 	}
 	return nil
 }
 
-func processPlayerLogin(playerNickname string, connectionInfo models.ConnectionInfo, command constants.Command) error {
+func processClientLogin(request CommandRequest) error {
+	server, session := request.Server, request.Session
+	playerNickname := request.PlayerNickname
+	conn := request.ConnectionInfo.Connection
+
+	// retried login from the same connection
+	if request.ClientMessageID != "" {
+		player, err := server.PlayerList.GetItem(playerNickname)
+		if err == nil && player != nil && player.GetConnectionInfo().Connection == conn {
+			isProcessed, err := processDuplicateClientMessage(server, player, request.ClientMessageID, request.Command.CommandID)
+			if isProcessed {
+				return err
+			}
+		}
+	}
+
+	err := processPlayerLogin(server, playerNickname, request.ConnectionInfo, request.Command, request.ClientMessageID, request.CorrelationID)
+	if err != nil {
+		errorHandeling.PrintError(server.Log, err)
+		return err
+	}
+
+	// nil if login was refused
+	session.SetPlayer(server.PlayerList.GetPlayerByConnection(conn))
+	return nil
+}
+
+func processPlayerLogin(server *models.Server, playerNickname string, connectionInfo models.ConnectionInfo, command constants.Command, clientMessageID string, correlationID string) error {
 	responseInfo := models.MessageInfo{
 		ConnectionInfo: connectionInfo,
 		PlayerNickname: playerNickname,
 	}
 
+	// no new players in maintenance, players already logged in may continue
+	if server.IsMaintenance() {
+		err := network.SendResponseServerError(server, responseInfo, constants.ErrorCodeMaintenance)
+		if err != nil {
+			errorHandeling.PrintError(server.Log, err)
+		}
+
+		err = network.CloseConnection(server, connectionInfo.Connection)
+		if err != nil {
+			errorHandeling.PrintError(server.Log, err)
+			return fmt.Errorf("Error closing connection: %w", err)
+		}
+		return nil
+	}
+
 	//Check if playerNickname in list
-	if models.GetInstancePlayerList().HasItemName(playerNickname) {
-		err := network.ProcessSendResponseServerErrDuplicitNickname(responseInfo)
+	if server.PlayerList.HasItemName(playerNickname) {
+		err := network.ProcessSendResponseServerError(server, responseInfo, constants.ErrorCodeNicknameTaken)
 		if err != nil {
-			errorHandeling.PrintError(err)
+			errorHandeling.PrintError(server.Log, err)
 			return fmt.Errorf("Error sending response: %w", err)
 		}
 		return nil
 	}
 	// Add the player to the playerData
-	player := models.CreatePlayer(playerNickname, connectionInfo)
+	player := models.CreatePlayer(playerNickname, connectionInfo, server.Log)
+	player.SetCorrelationID(correlationID)
+	defer player.SetCorrelationID("")
+	if clientMessageID != "" {
+		player.StartClientMessage(clientMessageID, command.CommandID)
+		defer player.FinishClientMessage(server.GetSettings().ClientMessageCacheSize)
+	}
 
-	err := models.GetInstancePlayerList().AddItem(player)
+	err := server.PlayerList.AddItem(player)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("Error adding player: %w", err)
 	}
 
 	//Send response
-	err = sendResponseServerGameList(player)
+	err = sendResponseServerGameList(server, player)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("Error sending response: %w", err)
 	}
 
 	// Move state machine to lobby
 	err = player.FireStateMachine(command.Trigger)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return err
 	}
 
+	SchedulePing(server, player)
+
+	// message of the day is sent after the game list, so clients are already in lobby
+	motd := server.GetSettings().MOTD
+	if motd != "" {
+		err = network.CommunicationServerAnnouncement(server, []*models.Player{player}, motd)
+		if err != nil {
+			errorHandeling.PrintError(server.Log, err)
+			return fmt.Errorf("Error sending message of the day: %w", err)
+		}
+	}
+
 	return nil
 }
 
 //region func processClientCreateGame
 
-func processClientCreateGame(player *models.Player, params []constants.Params, command constants.Command) error {
+func processClientCreateGame(request CommandRequest) error {
+	server, player, command := request.Server, request.Player, request.Command
 	responseInfo := models.MessageInfo{
 		ConnectionInfo: player.GetConnectionInfo(),
 		PlayerNickname: player.GetNickname(),
 	}
 
-	//State machine check
-	canFire, err := player.GetStateMachine().CanFire(command.Trigger)
-	if err != nil {
-		errorHandeling.PrintError(err)
-		return fmt.Errorf("cannot create game %w", err)
-	}
-	if !canFire {
-		return _handleCannotFire(player)
-	}
+	gameName := request.Args.String("gameName")
+	maxPlayers := request.Args.Int("maxPlayers")
 
-	//Convert params
-	gameName, maxPlayers, err := parser.ConvertParamClientCreateGame(params, command.ParamsNames)
-	if err != nil {
-		logger.Log.Errorf("Error converting params: %v", err)
-		errDissconnect := dissconectPlayer(player)
-		if errDissconnect != nil {
-			errorHandeling.PrintError(errDissconnect)
-			return fmt.Errorf("Error sending response: %w", errDissconnect)
+	// the player stays in lobby and may join existing games
+	if server.IsMaintenance() {
+		err := network.SendResponseServerError(server, responseInfo, constants.ErrorCodeMaintenance)
+		if err != nil {
+			errorHandeling.PrintError(server.Log, err)
+			return fmt.Errorf("Error sending response: %w", err)
 		}
-
-		errorHandeling.PrintError(err)
 		return nil
 	}
 
 	//Check if playerNickname in list
-	if models.GetInstanceGameList().HasItemName(gameName) {
-		err := network.ProcessSendResponseServerErrorDuplicitGameName(responseInfo)
+	if server.GameList.HasItemName(gameName) {
+		err := network.ProcessSendResponseServerError(server, responseInfo, constants.ErrorCodeGameNameTaken)
 		if err != nil {
-			errorHandeling.PrintError(err)
+			errorHandeling.PrintError(server.Log, err)
 			return fmt.Errorf("Error sending response: %w", err)
 		}
 		return nil
 	}
 
 	// Initialize the game
-	game, err := initGame(player, gameName, maxPlayers)
+	game, err := initGame(server, player, gameName, maxPlayers)
 	if err != nil {
 		return err
 	}
+	if game == nil {
+		return nil
+	}
 
+	return game.Execute(func() error {
+		return processCreatedGame(server, player, game, command, responseInfo)
+	})
+}
+
+func processCreatedGame(server *models.Server, player *models.Player, game *models.Game, command constants.Command, responseInfo models.MessageInfo) error {
 	// Send the response
-	err = network.SendResponseServerSuccess(responseInfo)
+	err := network.SendResponseServerSuccess(server, responseInfo)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("Error sending response: %w", err)
 	}
 
 	//Change state machine
 	err = player.FireStateMachine(command.Trigger)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("Error sending response: %w", err)
 	}
 
-	//region ServerUpdateGameList
-	err = network.ProcessCommunicationServerUpdateGameList()
+	//ServerUpdateGameList, ServerUpdatePlayerList
+	err = server.Events.Publish(models.PlayerJoined{Player: player, Game: game})
 	if err != nil {
-		errorHandeling.PrintError(err)
-		return fmt.Errorf("Error sending response: %w", err)
-	}
-	//endregion
-
-	//ServerUpdatePlayerList
-
-	err = network.ProcessCommunicationServerUpdatePlayerList(game)
-	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("Error sending response: %w", err)
 	}
 
 	return nil
 }
 
-func sendResponseServerGameList(player *models.Player) error {
+func sendResponseServerGameList(server *models.Server, player *models.Player) error {
 	responseInfo := models.MessageInfo{
 		ConnectionInfo: player.GetConnectionInfo(),
 		PlayerNickname: player.GetNickname(),
 	}
-	gameList := models.GetInstanceGameList().GetCreatedGameList()
+	gameList := server.GameList.GetCreatedGameList()
 
-	err := network.SendResponseServerGameList(responseInfo, gameList)
+	err := network.SendResponseServerGameList(server, responseInfo, gameList)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("Error sending response: %w", err)
 	}
 
 	return nil
 }
 
-func initGame(player *models.Player, name string, maxPlayers int) (game *models.Game, error error) {
+func initGame(server *models.Server, player *models.Player, name string, maxPlayers int) (game *models.Game, error error) {
 	// Create the game
-	game, err := models.CreateGame(name, maxPlayers)
+	game, err := models.CreateGame(name, maxPlayers, server.GetSettings().GameRules(), server.Log)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return nil, nil
 	}
 
 	// Add the player to the game
 	err = game.AddPlayer(player)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return nil, nil
 	}
 
-	_, err = models.GetInstanceGameList().AddItem(game)
+	_, err = server.GameList.AddItem(game)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return nil, nil
 	}
 
 	return game, nil
 }
 
-func _handleCannotFire(player *models.Player) error {
+func _handleCannotFire(server *models.Server, player *models.Player) error {
 
 	err := fmt.Errorf("state machine cannot fire")
 
-	logger.Log.Errorf("TOTAL_DISCONNECT: Cannot fire state machine: %v", err)
+	server.PlayerLog(player).Errorf("TOTAL_DISCONNECT: Cannot fire state machine: %v", err)
+
+	responseInfo := models.MessageInfo{
+		ConnectionInfo: player.GetConnectionInfo(),
+		PlayerNickname: player.GetNickname(),
+	}
+	err = network.SendResponseServerError(server, responseInfo, constants.ErrorCodeInvalidState)
+	if err != nil {
+		errorHandeling.PrintError(server.Log, err)
+	}
 
-	network.ImidiateDisconnectPlayer(player.GetNickname())
+	network.ImidiateDisconnectPlayer(server, player.GetNickname())
 	return nil
 }
 
@@ -398,110 +526,95 @@ func _handleCannotFire(player *models.Player) error {
 
 //region func processClientJoinGame
 
-func processClientJoinGame(player *models.Player, params []constants.Params, command constants.Command) error {
+func processClientJoinGame(request CommandRequest) error {
+	server, player, command := request.Server, request.Player, request.Command
 	responseInfo := models.MessageInfo{
 		ConnectionInfo: player.GetConnectionInfo(),
 		PlayerNickname: player.GetNickname(),
 	}
 
-	canFire, err := player.GetStateMachine().CanFire(command.Trigger)
-	if err != nil {
-		errorHandeling.PrintError(err)
-		return fmt.Errorf("cannot join game %w", err)
-	}
-	if !canFire {
-		return _handleCannotFire(player)
-	}
+	gameName := request.Args.String("gameName")
 
-	// Convert params
-	gameName, err := parser.ConvertParamClientJoinGame(params, command.ParamsNames) // assuming gameName is the first parameter
+	game, err := server.GameList.GetItemByName(gameName)
 	if err != nil {
-		logger.Log.Errorf("Error converting params: %v", err)
-		errDissconnect := dissconectPlayer(player)
-		if errDissconnect != nil {
-			errorHandeling.PrintError(errDissconnect)
-			return fmt.Errorf("Error sending response: %w", errDissconnect)
-		}
-
-		errorHandeling.PrintError(err)
-		return nil
-	}
-
-	game, err := models.GetInstanceGameList().GetItemByName(gameName)
-	if err != nil {
-		errorHandeling.PrintError(err)
-		logger.Log.Errorf("Error getting game: %v", err)
-		errDisconnect := dissconectPlayer(player)
+		errorHandeling.PrintError(server.Log, err)
+		server.PlayerLog(player).Errorf("Error getting game: %v", err)
+		errDisconnect := dissconectPlayer(server, player, models.GetErrorCode(err))
 		if errDisconnect != nil {
-			errorHandeling.PrintError(errDisconnect)
+			errorHandeling.PrintError(server.Log, errDisconnect)
 			return fmt.Errorf("Error sending response: %w", errDisconnect)
 		}
 
 		return fmt.Errorf("Error sending response: %w", err)
 	}
 
-	err = processAddPlayerToGame(player, game)
+	// Check if player is already in game, before entering game goroutine which must not be entered twice
+	isPlayerInGame := server.GameList.GetPlayersGame(player) != nil
+	if isPlayerInGame {
+		server.PlayerLog(player).Errorf("Player is already in a game")
+		return dissconectPlayer(server, player, constants.ErrorCodeAlreadyInGame)
+	}
+
+	return game.Execute(func() error {
+		return processJoinedGame(server, player, game, command, responseInfo)
+	})
+}
+
+func processJoinedGame(server *models.Server, player *models.Player, game *models.Game, command constants.Command, responseInfo models.MessageInfo) error {
+	err := processAddPlayerToGame(server, player, game)
 	if err != nil {
-		errorHandeling.PrintError(err)
-		return fmt.Errorf("Error sending response: %w", err)
+		errorHandeling.PrintError(server.Log, err)
+		return dissconectPlayer(server, player, models.GetErrorCode(err))
 	}
 
 	// Send the response
-	err = network.SendResponseServerSuccess(responseInfo)
+	err = network.SendResponseServerSuccess(server, responseInfo)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("Error sending response: %w", err)
 	}
 
 	//Change state machine
 	err = player.FireStateMachine(command.Trigger)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("Error sending response: %w", err)
 	}
 
-	//region ServerUpdateGameList
-	err = network.ProcessCommunicationServerUpdateGameList()
+	//ServerUpdateGameList, ServerUpdatePlayerList
+	err = server.Events.Publish(models.PlayerJoined{Player: player, Game: game})
 	if err != nil {
-		errorHandeling.PrintError(err)
-		return fmt.Errorf("Error sending response: %w", err)
-	}
-	//endregion
-
-	// Send update to all players
-	err = network.ProcessCommunicationServerUpdatePlayerList(game)
-	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("Error sending response: %w", err)
 	}
 
 	return nil
 }
 
-func processAddPlayerToGame(player *models.Player, game *models.Game) error {
+func processAddPlayerToGame(server *models.Server, player *models.Player, game *models.Game) error {
 	var err error
 
 	// Check if player is already in game
-	isPlayerInGame := models.GetInstanceGameList().GetPlayersGame(player) != nil
+	isPlayerInGame := server.GameList.GetPlayersGame(player) != nil
 	if isPlayerInGame {
-		err = fmt.Errorf("Player is already in a game")
-		errorHandeling.PrintError(err)
+		err = models.ErrPlayerInGame
+		errorHandeling.PrintError(server.Log, err)
 		return err
 	}
 
 	// Check if the game has already started
 	canStartGame := game.GetState() == models.Created
 	if !canStartGame {
-		err = fmt.Errorf("Game has already started")
-		errorHandeling.PrintError(err)
+		err = models.ErrGameStarted
+		errorHandeling.PrintError(server.Log, err)
 		return err
 	}
 
 	// Add the player to the game
-	err = game.AddPlayer(player)
+	err = server.GameList.AddPlayerToGame(game, player)
 	if err != nil {
 		err = fmt.Errorf("Error adding player to game: %w", err)
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return err
 	}
 
@@ -510,29 +623,22 @@ func processAddPlayerToGame(player *models.Player, game *models.Game) error {
 
 //endregion
 
-func processClientStartGame(player *models.Player, params []constants.Params, command constants.Command) error {
+func processClientStartGame(request CommandRequest) error {
+	server, player, command := request.Server, request.Player, request.Command
 
 	responseInfo := models.MessageInfo{
 		ConnectionInfo: player.GetConnectionInfo(),
 		PlayerNickname: player.GetNickname(),
 	}
 
-	//State machine check
-	canFire, err := player.GetStateMachine().CanFire(command.Trigger)
-	if err != nil {
-		errorHandeling.PrintError(err)
-		return fmt.Errorf("cannot join playersGame %w", err)
-	}
-	if !canFire {
-		return _handleCannotFire(player)
-	}
+	var err error
 
-	playersGame := models.GetInstanceGameList().GetPlayersGame(player)
+	playersGame := server.GameList.GetPlayersGame(player)
 	if playersGame == nil {
-		logger.Log.Errorf("Error getting playersGame: %v", err)
-		err = dissconectPlayer(player)
+		server.PlayerLog(player).Errorf("Error getting playersGame: %v", err)
+		err = dissconectPlayer(server, player, constants.ErrorCodeNotInGame)
 		if err != nil {
-			errorHandeling.PrintError(err)
+			errorHandeling.PrintError(server.Log, err)
 			return fmt.Errorf("Error sending response: %w", err)
 		}
 		return nil
@@ -542,9 +648,9 @@ func processClientStartGame(player *models.Player, params []constants.Params, co
 
 	//region SendResponseServerSuccess
 
-	err = network.SendResponseServerSuccess(responseInfo)
+	err = network.SendResponseServerSuccess(server, responseInfo)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("Error sending response: %w", err)
 	}
 
@@ -556,9 +662,9 @@ func processClientStartGame(player *models.Player, params []constants.Params, co
 	// remove player from list
 	playersList = helpers.RemovePlayerFromList(playersList, player)
 
-	err = network.CommunicationServerUpdateStartGame(playersList)
+	err = network.CommunicationServerUpdateStartGame(server, playersList)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("Error sending response: %w", err)
 	}
 
@@ -567,30 +673,22 @@ func processClientStartGame(player *models.Player, params []constants.Params, co
 	//region StartGame
 	err = playersGame.StartGame()
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("Error sending response: %w", err)
 	}
 
 	//Change state machine to Running_game
 	err = player.FireStateMachine(command.Trigger)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("Error sending response: %w", err)
 	}
 	//endregion
 
-	//region ServerUpdateGameList
-	err = network.ProcessCommunicationServerUpdateGameList()
+	//region ServerUpdateGameList, ServerUpdateGameData
+	err = server.Events.Publish(models.GameStarted{Player: player, Game: playersGame})
 	if err != nil {
-		errorHandeling.PrintError(err)
-		return fmt.Errorf("Error sending response: %w", err)
-	}
-	//endregion
-
-	//region ServerUpdateGameData
-	err = network.ProcessCommunicationServerUpdateGameData(playersGame)
-	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("Error sending response: %w", err)
 	}
 	//endregion
@@ -600,89 +698,70 @@ func processClientStartGame(player *models.Player, params []constants.Params, co
 	return nil
 }
 
-func processClientPlayerLogout(player *models.Player, params []constants.Params, command constants.Command) error {
+func processClientPlayerLogout(request CommandRequest) error {
+	server, player := request.Server, request.Player
 	responseInfo := models.MessageInfo{
 		ConnectionInfo: player.GetConnectionInfo(),
 		PlayerNickname: player.GetNickname(),
 	}
 
-	canFire, err := player.GetStateMachine().CanFire(command.Trigger)
-	if err != nil {
-		errorHandeling.PrintError(err)
-		return fmt.Errorf("cannot join game %w", err)
-	}
-	if !canFire {
-		return _handleCannotFire(player)
-	}
-
-	// Convert params
-	if len(params) != 0 {
-		logger.Log.Errorf("Error number of params: %v", err)
-		err = dissconectPlayer(player)
-		if err != nil {
-			errorHandeling.PrintError(err)
-			return fmt.Errorf("Error sending response: %w", err)
-		}
-		return nil
-	}
-
 	// Send the response
-	err = network.SendResponseServerSuccess(responseInfo)
+	err := network.SendResponseServerSuccess(server, responseInfo)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("Error sending response: %w", err)
 	}
 
 	//Remove from game
-	err = models.GetInstanceGameList().RemovePlayerFromGame(player)
+	err = server.GameList.RemovePlayerFromGame(player)
 	if err != nil {
 		err = fmt.Errorf("Error removing player from game: %w", err)
-		errorHandeling.PrintError(err)
-		panic(err)
+		errorHandeling.PrintError(server.Log, err)
+		return err
 	}
 
-	logger.Log.Errorf("Logout player: %v", player.GetNickname())
+	server.PlayerLog(player).Errorf("Logout player: %v", player.GetNickname())
 	//disconnect player
-	err = dissconectPlayer(player)
+	err = dissconectPlayer(server, player, constants.ErrorCodeLogout)
 	if err != nil {
 		err = fmt.Errorf("Error disconnecting player: %w", err)
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return err
 	}
 
 	return nil
 }
 
-func __handleErrorMyTurn(player *models.Player, game *models.Game) error {
-	err := dissconectPlayer(player)
+func __handleErrorMyTurn(server *models.Server, player *models.Player, game *models.Game, code constants.ErrorCode) error {
+	err := dissconectPlayer(server, player, code)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("Error sending response: %w", err)
 	}
 
 	//next Player turn
 	err = game.NextPlayerTurn()
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("Error sending response: %w", err)
 	}
 
-	err = network.ProcessCommunicationServerUpdateGameData(game)
+	err = network.ProcessCommunicationServerUpdateGameData(server, game)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("Error sending response: %w", err)
 	}
 
 	return nil
 }
 
-func validatePlayerTurn(player *models.Player) (*models.Game, error) {
-	game := models.GetInstanceGameList().GetPlayersGame(player)
+func validatePlayerTurn(server *models.Server, player *models.Player) (*models.Game, error) {
+	game := server.GameList.GetPlayersGame(player)
 	if game == nil {
-		logger.Log.Errorf("Error getting playersGame")
-		err := dissconectPlayer(player)
+		server.PlayerLog(player).Errorf("Error getting playersGame")
+		err := dissconectPlayer(server, player, constants.ErrorCodeNotInGame)
 		if err != nil {
-			errorHandeling.PrintError(err)
+			errorHandeling.PrintError(server.Log, err)
 			return nil, fmt.Errorf("Error sending response: %w", err)
 		}
 		return nil, nil
@@ -690,15 +769,15 @@ func validatePlayerTurn(player *models.Player) (*models.Game, error) {
 
 	turnPlayer, err := game.GetTurnPlayer()
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return nil, fmt.Errorf("Error sending response: %w", err)
 	}
 
 	if turnPlayer.GetNickname() != player.GetNickname() {
-		logger.Log.Errorf("Error player is not in turn")
-		err := dissconectPlayer(player)
+		server.PlayerLog(player).Errorf("Error player is not in turn")
+		err := dissconectPlayer(server, player, constants.ErrorCodeNotYourTurn)
 		if err != nil {
-			errorHandeling.PrintError(err)
+			errorHandeling.PrintError(server.Log, err)
 			return nil, fmt.Errorf("Error sending response: %w", err)
 		}
 		return nil, nil
@@ -707,7 +786,8 @@ func validatePlayerTurn(player *models.Player) (*models.Game, error) {
 	return game, nil
 }
 
-func processClientRollDice(player *models.Player, params []constants.Params, command constants.Command) error {
+func processClientRollDice(request CommandRequest) error {
+	server, player, command := request.Server, request.Player, request.Command
 	//inline function _handleCannotFire
 
 	responseInfo := models.MessageInfo{
@@ -716,20 +796,12 @@ func processClientRollDice(player *models.Player, params []constants.Params, com
 	}
 
 	//region CHECK
-	//State machine check
-	canFire, err := player.GetStateMachine().CanFire(command.Trigger)
-	if err != nil {
-		errorHandeling.PrintError(err)
-		return fmt.Errorf("cannot join playersGame %w", err)
-	}
-	if !canFire {
-		return _handleCannotFire(player)
-	}
+	var canFire bool
 
 	//region check if it is players turn
-	game, err := validatePlayerTurn(player)
+	game, err := validatePlayerTurn(server, player)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("Error sending response: %w", err)
 	}
 	if game == nil {
@@ -741,20 +813,20 @@ func processClientRollDice(player *models.Player, params []constants.Params, com
 	err = player.GetStateMachine().Fire(command.Trigger)
 	if err != nil {
 		err = fmt.Errorf("Error firing state machine: %w", err)
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return err
 	}
 
 	//region Fork_my_turn
 	cubeValues, err := game.NewThrow(player)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("Error sending response: %w", err)
 	}
-	logger.Log.Debugf("Cube values: %v", cubeValues)
+	server.PlayerLog(player).Debugf("Cube values: %v", cubeValues)
 
 	canBePlayed := cubesCanBePlayed(cubeValues)
-	logger.Log.Debugf("Can be played: %v", canBePlayed)
+	server.PlayerLog(player).Debugf("Can be played: %v", canBePlayed)
 	// endregion
 
 	//region Fork_my_turn -> end 1. ResponseServerEndTurn
@@ -764,38 +836,38 @@ func processClientRollDice(player *models.Player, params []constants.Params, com
 		canFire, err = player.GetStateMachine().CanFire(commandTrigger)
 		if err != nil {
 			err = fmt.Errorf("Error cannot fire: %w", err)
-			errorHandeling.PrintError(err)
+			errorHandeling.PrintError(server.Log, err)
 			return err
 		}
 		if !canFire {
-			logger.Log.Errorf("Cannot fire with trigger: %v", commandTrigger)
-			return __handleErrorMyTurn(player, game)
+			server.PlayerLog(player).Errorf("Cannot fire with trigger: %v", commandTrigger)
+			return __handleErrorMyTurn(server, player, game, constants.ErrorCodeInvalidState)
 		}
 
-		err = network.SendResponseServerEndTurn(player)
+		err = network.SendResponseServerEndTurn(server, player)
 		if err != nil {
-			errorHandeling.PrintError(err)
+			errorHandeling.PrintError(server.Log, err)
 			return fmt.Errorf("Error sending response: %w", err)
 		}
 
 		err = game.NextPlayerTurn()
 		if err != nil {
 			err = fmt.Errorf("Error next player turn: %w", err)
-			errorHandeling.PrintError(err)
+			errorHandeling.PrintError(server.Log, err)
 			return err
 		}
 
 		err = player.FireStateMachine(commandTrigger)
 		if err != nil {
 			err = fmt.Errorf("Error firing state machine: %w", err)
-			errorHandeling.PrintError(err)
+			errorHandeling.PrintError(server.Log, err)
 			return err
 		}
 
 		//ServerUpdateGameData
-		err = network.ProcessCommunicationServerUpdateGameData(game)
+		err = server.Events.Publish(models.DiceRolled{Player: player, Game: game, CubeValues: cubeValues, CanBePlayed: false})
 		if err != nil {
-			errorHandeling.PrintError(err)
+			errorHandeling.PrintError(server.Log, err)
 			return fmt.Errorf("Error sending response: %w", err)
 		}
 
@@ -808,33 +880,33 @@ func processClientRollDice(player *models.Player, params []constants.Params, com
 	canFire, err = player.GetStateMachine().CanFire(commandTrigger)
 	if err != nil {
 		err = fmt.Errorf("Error cannot fire: %w", err)
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return err
 	}
 	if !canFire {
-		logger.Log.Errorf("Cannot fire with trigger: %v", commandTrigger)
-		return __handleErrorMyTurn(player, game)
+		server.PlayerLog(player).Errorf("Cannot fire with trigger: %v", commandTrigger)
+		return __handleErrorMyTurn(server, player, game, constants.ErrorCodeInvalidState)
 	}
 
-	err = network.SendResponseServerSelectCubes(cubeValues, responseInfo)
+	err = network.SendResponseServerSelectCubes(server, cubeValues, responseInfo)
 	if err != nil {
 		err = fmt.Errorf("Error sending response: %w", err)
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return err
 	}
 
 	err = player.FireStateMachine(commandTrigger)
 	if err != nil {
 		err = fmt.Errorf("Error firing state machine: %w", err)
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return err
 	}
 	//endregion
 
 	//region ServerUpdateGameData
-	err = network.ProcessCommunicationServerUpdateGameData(game)
+	err = server.Events.Publish(models.DiceRolled{Player: player, Game: game, CubeValues: cubeValues, CanBePlayed: true})
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("Error sending response: %w", err)
 	}
 	//endregion
@@ -844,22 +916,41 @@ func processClientRollDice(player *models.Player, params []constants.Params, com
 	//endregion
 }
 
-func processClientSelectedCubes(player *models.Player, params []constants.Params, command constants.Command) error {
+// processInvalidSelectedCubes ends turn of player who has sent invalid cube values
+func processInvalidSelectedCubes(request CommandRequest) error {
+	server, player, command := request.Server, request.Player, request.Command
 
-	//region CHECK
 	canFire, err := player.GetStateMachine().CanFire(command.Trigger)
 	if err != nil {
-		errorHandeling.PrintError(err)
-		return fmt.Errorf("cannot join playersGame %w", err)
+		errorHandeling.PrintError(server.Log, err)
+		return fmt.Errorf("cannot fire %w", err)
 	}
 	if !canFire {
-		return _handleCannotFire(player)
+		return _handleCannotFire(server, player)
 	}
 
+	game, err := validatePlayerTurn(server, player)
+	if err != nil {
+		errorHandeling.PrintError(server.Log, err)
+		return fmt.Errorf("Error sending response: %w", err)
+	}
+	if game == nil {
+		return nil
+	}
+
+	return __handleErrorMyTurn(server, player, game, constants.ErrorCodeInvalidCubes)
+}
+
+func processClientSelectedCubes(request CommandRequest) error {
+	server, player, command := request.Server, request.Player, request.Command
+
+	//region CHECK
+	var canFire bool
+
 	//region check if it is players turn
-	game, err := validatePlayerTurn(player)
+	game, err := validatePlayerTurn(server, player)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("Error sending response: %w", err)
 	}
 	if game == nil {
@@ -872,66 +963,62 @@ func processClientSelectedCubes(player *models.Player, params []constants.Params
 	err = player.GetStateMachine().Fire(command.Trigger)
 	if err != nil {
 		err = fmt.Errorf("Error firing state machine: %w", err)
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return err
 	}
 
 	//region Fork_next_dice
-	selectedCubesValues, err := parser.ConvertParamClientSelectedCubes(params, command.ParamsNames)
-	if err != nil {
-		logger.Log.Errorf("Error converting params: %v", err)
-		return __handleErrorMyTurn(player, game)
-	}
+	selectedCubesValues := request.Args.Ints("cubeValues")
 
 	score, err := game.GetNewScore(player, selectedCubesValues)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("Error sending response: %w", err)
 	}
 	//endregion
 
 	//region Fork_next_dice -> end 1. ResponseServerEndScore
-	if score >= constants.CMaxScore {
+	if score >= game.GetRules().MaxScore {
 
 		//check if player can fire
 		commandTrigger := constants.CGCommands.ResponseServerEndScore.Trigger
 		canFire, err = player.GetStateMachine().CanFire(commandTrigger)
 		if err != nil {
-			errorHandeling.PrintError(err)
+			errorHandeling.PrintError(server.Log, err)
 			return fmt.Errorf("cannot join game %w", err)
 		}
 		if !canFire {
-			logger.Log.Errorf("Cannot fire with trigger: %v", commandTrigger)
-			return __handleErrorMyTurn(player, game)
+			server.PlayerLog(player).Errorf("Cannot fire with trigger: %v", commandTrigger)
+			return __handleErrorMyTurn(server, player, game, constants.ErrorCodeInvalidState)
 		}
 
 		//Send ResponseServerEndScore
-		err = network.SendResponseServerEndScore(player)
+		err = network.SendResponseServerEndScore(server, player)
 		if err != nil {
-			errorHandeling.PrintError(err)
+			errorHandeling.PrintError(server.Log, err)
 			return fmt.Errorf("Error sending response: %w", err)
 		}
 
 		//change player score
 		err = game.SetPlayerScore(player, selectedCubesValues)
 		if err != nil {
-			errorHandeling.PrintError(err)
+			errorHandeling.PrintError(server.Log, err)
 			return fmt.Errorf("Error sending response: %w", err)
 		}
 
 		//Send ServerUpdateEndScore
 		sendPlayerList := game.GetPlayers()
 		sendPlayerList = helpers.RemovePlayerFromList(sendPlayerList, player)
-		err = network.CommunicationServerUpdateEndScore(sendPlayerList, player.GetNickname())
+		err = network.CommunicationServerUpdateEndScore(server, sendPlayerList, player.GetNickname())
 		if err != nil {
-			errorHandeling.PrintError(err)
+			errorHandeling.PrintError(server.Log, err)
 			return fmt.Errorf("Error sending response: %w", err)
 		}
 
 		//remove game
-		err = models.GetInstanceGameList().RemoveItem(game)
+		err = server.GameList.RemoveItem(game)
 		if err != nil {
-			errorHandeling.PrintError(err)
+			errorHandeling.PrintError(server.Log, err)
 			return fmt.Errorf("error sending response: %w", err)
 		}
 
@@ -943,14 +1030,14 @@ func processClientSelectedCubes(player *models.Player, params []constants.Params
 		// fire state machine
 		err = player.FireStateMachine(commandTrigger)
 		if err != nil {
-			errorHandeling.PrintError(err)
+			errorHandeling.PrintError(server.Log, err)
 			return fmt.Errorf("Error sending response: %w", err)
 		}
 
 		// ServerUpdateGameList
-		err = network.ProcessCommunicationServerUpdateGameList()
+		err = server.Events.Publish(models.GameEnded{Winner: player, Game: game, Score: score})
 		if err != nil {
-			errorHandeling.PrintError(err)
+			errorHandeling.PrintError(server.Log, err)
 			return fmt.Errorf("Error sending response: %w", err)
 		}
 
@@ -964,33 +1051,33 @@ func processClientSelectedCubes(player *models.Player, params []constants.Params
 	commandTrigger := constants.CGCommands.ResponseServerDiceSuccess.Trigger
 	canFire, err = player.GetStateMachine().CanFire(commandTrigger)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("cannot join game %w", err)
 	}
 	if !canFire {
-		logger.Log.Errorf("Cannot fire with trigger: %v", commandTrigger)
-		return __handleErrorMyTurn(player, game)
+		server.PlayerLog(player).Errorf("Cannot fire with trigger: %v", commandTrigger)
+		return __handleErrorMyTurn(server, player, game, constants.ErrorCodeInvalidState)
 	}
 
 	err = game.SetPlayerScore(player, selectedCubesValues)
 
-	err = network.SendResponseServerDiceSuccess(player)
+	err = network.SendResponseServerDiceSuccess(server, player)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("Error sending response: %w", err)
 	}
 
 	err = player.FireStateMachine(commandTrigger)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("Error sending response: %w", err)
 	}
 	//endregion
 
 	//region ServerUpdateGameData
-	err = network.ProcessCommunicationServerUpdateGameData(game)
+	err = server.Events.Publish(models.ScoreChanged{Player: player, Game: game, Score: score})
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("Error sending response: %w", err)
 	}
 	//endregion
@@ -998,11 +1085,12 @@ func processClientSelectedCubes(player *models.Player, params []constants.Params
 	return nil
 }
 
-func processClientReconnect(player *models.Player, params []constants.Params, command constants.Command) error {
+func processClientReconnect(request CommandRequest) error {
+	server, player, command := request.Server, request.Player, request.Command
 	__disconnectPlayer := func(player *models.Player) error {
-		err := dissconectPlayer(player)
+		err := dissconectPlayer(server, player, constants.ErrorCodeInvalidState)
 		if err != nil {
-			errorHandeling.PrintError(err)
+			errorHandeling.PrintError(server.Log, err)
 			return fmt.Errorf("Error sending response: %w", err)
 		}
 		return nil
@@ -1011,50 +1099,50 @@ func processClientReconnect(player *models.Player, params []constants.Params, co
 	inner_send_respones_game_list := func(player *models.Player) error {
 
 		//region SendResponseServerGameList
-		logger.Log.Debugf("Processing SendResponseServerGameList: %v", player.GetNickname())
+		server.PlayerLog(player).Debugf("Processing SendResponseServerGameList: %v", player.GetNickname())
 		commandTrigger := constants.CGCommands.ResponseServerGameList.Trigger
 		stateMachine := player.GetStateMachine()
 
 		canFire, err := stateMachine.CanFire(commandTrigger)
 		if err != nil {
-			logger.Log.Errorf("Error cannot fire: %v", err)
-			errorHandeling.PrintError(err)
+			server.PlayerLog(player).Errorf("Error cannot fire: %v", err)
+			errorHandeling.PrintError(server.Log, err)
 			return fmt.Errorf("cannot join game %w", err)
 		}
 		if !canFire {
-			logger.Log.Errorf("Cannot fire with trigger: %v", commandTrigger)
-			errorHandeling.AssertError(fmt.Errorf("cannot fire state machine"))
+			server.PlayerLog(player).Errorf("Cannot fire with trigger: %v", commandTrigger)
+			errorHandeling.AssertError(server.Log, fmt.Errorf("cannot fire state machine"))
 		}
 
-		err = sendResponseServerGameList(player)
+		err = sendResponseServerGameList(server, player)
 		if err != nil {
-			logger.Log.Errorf("Error sending response: %v", err)
-			errorHandeling.PrintError(err)
+			server.PlayerLog(player).Errorf("Error sending response: %v", err)
+			errorHandeling.PrintError(server.Log, err)
 			return fmt.Errorf("Error sending response: %w", err)
 		}
 
 		err = stateMachine.Fire(commandTrigger)
 		if err != nil {
-			logger.Log.Errorf("Error firing state machine: %v", err)
-			errorHandeling.AssertError(fmt.Errorf("cannot fire state machine"))
+			server.PlayerLog(player).Errorf("Error firing state machine: %v", err)
+			errorHandeling.AssertError(server.Log, fmt.Errorf("cannot fire state machine"))
 		}
 		//endregion
 
 		return nil
 	}
 
-	logger.Log.Debugf("Processing client reconnect: %v", player.GetNickname())
+	server.PlayerLog(player).Debugf("Processing client reconnect: %v", player.GetNickname())
 
 	//region CHECK
-	playerFromList, err := models.GetInstancePlayerList().GetItem(player.GetNickname())
+	playerFromList, err := server.PlayerList.GetItem(player.GetNickname())
 	if err != nil {
-		logger.Log.Errorf("Error getting player from list: %v", player.GetNickname())
-		errorHandeling.PrintError(err)
+		server.PlayerLog(player).Errorf("Error getting player from list: %v", player.GetNickname())
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("Error sending response: %w", err)
 	}
 	if playerFromList == nil {
 
-		logger.Log.Errorf("Player not found in list: %v", player.GetNickname())
+		server.PlayerLog(player).Errorf("Player not found in list: %v", player.GetNickname())
 		return __disconnectPlayer(player)
 	}
 
@@ -1065,16 +1153,18 @@ func processClientReconnect(player *models.Player, params []constants.Params, co
 
 	//region LOGIC
 	player.SetConnectedByBool(true)
-	player.NullifyTotalDisconnectTime()
+	network.CancelTotalDisconnect(server, player)
+	SchedulePing(server, player)
 
 	currentStateName := player.GetCurrentStateName()
 
 	// State: Start -> ClientReconnect -> ...
 	player.ResetStateMachine()
+	network.CancelResponseTimeout(server, player)
 	err = player.FireStateMachine(command.Trigger)
 	if err != nil {
-		logger.Log.Errorf("Error firing state machine: %v", err)
-		errorHandeling.AssertError(fmt.Errorf("cannot fire state machine"))
+		server.PlayerLog(player).Errorf("Error firing state machine: %v", err)
+		errorHandeling.AssertError(server.Log, fmt.Errorf("cannot fire state machine"))
 	}
 	stateMachine := player.GetStateMachine()
 
@@ -1089,9 +1179,9 @@ func processClientReconnect(player *models.Player, params []constants.Params, co
 
 	if helpers.Contains(beforeGameAllowedStates, currentStateName) {
 		//region RespondServerReconnectBeforeGame
-		logger.Log.Debugf("Processing RespondServerReconnectBeforeGame: %v", player.GetNickname())
+		server.PlayerLog(player).Debugf("Processing RespondServerReconnectBeforeGame: %v", player.GetNickname())
 
-		game := models.GetInstanceGameList().GetPlayersGame(player)
+		game := server.GameList.GetPlayersGame(player)
 
 		if game == nil {
 			return inner_send_respones_game_list(player)
@@ -1104,29 +1194,29 @@ func processClientReconnect(player *models.Player, params []constants.Params, co
 		commandTrigger := constants.CGCommands.ResponseServerReconnectBeforeGame.Trigger
 		canFire, err := stateMachine.CanFire(commandTrigger)
 		if err != nil {
-			errorHandeling.PrintError(err)
+			errorHandeling.PrintError(server.Log, err)
 			return fmt.Errorf("cannot join game %w", err)
 		}
 		if !canFire {
-			errorHandeling.AssertError(fmt.Errorf("cannot fire state machine"))
+			errorHandeling.AssertError(server.Log, fmt.Errorf("cannot fire state machine"))
 		}
 
-		err = network.SendResponseServerReconnectBeforeGame(responseInfo, game)
+		err = network.SendResponseServerReconnectBeforeGame(server, responseInfo, game)
 		if err != nil {
-			errorHandeling.PrintError(err)
+			errorHandeling.PrintError(server.Log, err)
 			return fmt.Errorf("Error sending response: %w", err)
 		}
 
 		err = stateMachine.Fire(commandTrigger)
 		if err != nil {
-			errorHandeling.AssertError(fmt.Errorf("cannot fire state machine"))
+			errorHandeling.AssertError(server.Log, fmt.Errorf("cannot fire state machine"))
 		}
 		//endregion
 
 		//region CommmunicationServerUpdatePlayerList
-		err = network.ProcessCommunicationServerUpdatePlayerList(models.GetInstanceGameList().GetPlayersGame(player))
+		err = network.ProcessCommunicationServerUpdatePlayerList(server, server.GameList.GetPlayersGame(player))
 		if err != nil {
-			errorHandeling.PrintError(err)
+			errorHandeling.PrintError(server.Log, err)
 			return fmt.Errorf("Error sending response: %w", err)
 		}
 		//endregion
@@ -1135,9 +1225,9 @@ func processClientReconnect(player *models.Player, params []constants.Params, co
 
 	if helpers.Contains(runningGameAllowedStates, currentStateName) {
 		//region RespondServerReconnectRunningGame
-		logger.Log.Debugf("Processing RespondServerReconnectRunningGame: %v", player.GetNickname())
+		server.PlayerLog(player).Debugf("Processing RespondServerReconnectRunningGame: %v", player.GetNickname())
 
-		game := models.GetInstanceGameList().GetPlayersGame(player)
+		game := server.GameList.GetPlayersGame(player)
 		if game == nil || game.GetState() != models.Running {
 			return inner_send_respones_game_list(player)
 		}
@@ -1145,35 +1235,35 @@ func processClientReconnect(player *models.Player, params []constants.Params, co
 		commandTrigger := constants.CGCommands.ResponseServerReconnectRunningGame.Trigger
 		canFire, err := stateMachine.CanFire(commandTrigger)
 		if err != nil {
-			errorHandeling.PrintError(err)
+			errorHandeling.PrintError(server.Log, err)
 			return fmt.Errorf("cannot join game %w", err)
 		}
 		if !canFire {
-			errorHandeling.AssertError(fmt.Errorf("cannot fire state machine"))
+			errorHandeling.AssertError(server.Log, fmt.Errorf("cannot fire state machine"))
 		}
 
-		gameData, err := models.GetInstanceGameList().GetPlayersGame(player).GetGameData()
+		gameData, err := server.GameList.GetPlayersGame(player).GetGameData()
 		if err != nil {
-			errorHandeling.PrintError(err)
+			errorHandeling.PrintError(server.Log, err)
 			return fmt.Errorf("Error sending response: %w", err)
 		}
 
-		err = network.SendResponseServerReconnectRunningGame(responseInfo, gameData)
+		err = network.SendResponseServerReconnectRunningGame(server, responseInfo, gameData)
 		if err != nil {
-			errorHandeling.PrintError(err)
+			errorHandeling.PrintError(server.Log, err)
 			return fmt.Errorf("Error sending response: %w", err)
 		}
 
 		err = stateMachine.Fire(commandTrigger)
 		if err != nil {
-			errorHandeling.AssertError(fmt.Errorf("cannot fire state machine"))
+			errorHandeling.AssertError(server.Log, fmt.Errorf("cannot fire state machine"))
 		}
 		//endregion
 
 		//region CommmunicationServerUpdateGameData
-		err = network.ProcessCommunicationServerUpdateGameData(models.GetInstanceGameList().GetPlayersGame(player))
+		err = network.ProcessCommunicationServerUpdateGameData(server, server.GameList.GetPlayersGame(player))
 		if err != nil {
-			errorHandeling.PrintError(err)
+			errorHandeling.PrintError(server.Log, err)
 			return fmt.Errorf("Error sending response: %w", err)
 		}
 		//endregion
@@ -1181,19 +1271,19 @@ func processClientReconnect(player *models.Player, params []constants.Params, co
 		//region ServerStartTurn - if player is in my turn
 		turnPlayer, err := game.GetTurnPlayer()
 		if err != nil {
-			errorHandeling.PrintError(err)
+			errorHandeling.PrintError(server.Log, err)
 			return fmt.Errorf("Error sending response: %w", err)
 		}
 		if turnPlayer.GetNickname() != player.GetNickname() {
-			logger.Log.Debugf("Player %v is not in my turn it is turn Player: %v", player.GetNickname(), turnPlayer.GetNickname())
+			server.PlayerLog(player).Debugf("Player %v is not in my turn it is turn Player: %v", player.GetNickname(), turnPlayer.GetNickname())
 			return nil
 		}
 
-		logger.Log.Debugf("Player %v is in my turn it is turn Player: %v", player.GetNickname(), turnPlayer.GetNickname())
+		server.PlayerLog(player).Debugf("Player %v is in my turn it is turn Player: %v", player.GetNickname(), turnPlayer.GetNickname())
 
-		err = ProcessPlayerTurn(game)
+		err = ProcessPlayerTurn(server, game)
 		if err != nil {
-			errorHandeling.PrintError(err)
+			errorHandeling.PrintError(server.Log, err)
 			return fmt.Errorf("Error sending response: %w", err)
 		}
 		//endregion
@@ -1207,20 +1297,63 @@ func processClientReconnect(player *models.Player, params []constants.Params, co
 	return inner_send_respones_game_list(player)
 }
 
-func ProcessSendPingPlayer(player *models.Player) error {
+func pingTimerKey(player *models.Player) string {
+	return "ping:" + player.GetNickname()
+}
+
+// SchedulePing sends ping to player every ping interval while the player is connected
+func SchedulePing(server *models.Server, player *models.Player) {
+	server.Scheduler.Schedule(pingTimerKey(player), server.GetSettings().PingInterval, func() {
+		processScheduledPing(server, player)
+	})
+}
+
+func processScheduledPing(server *models.Server, player *models.Player) {
+	if server.Context().Err() != nil {
+		return
+	}
+
+	// reconnect schedules pings again
+	if !player.IsConnected() || !server.PlayerList.HasValue(player) {
+		return
+	}
+	// always rescheduled, a skipped or failed ping must not end pinging of the player
+	defer SchedulePing(server, player)
+
+	// state of the player is changed only by handlers of his game, so it is checked there
+	err := server.GameList.ExecuteInPlayersGame(player, func() error {
+		if !player.IsConnected() {
+			return nil
+		}
+
+		err := ProcessSendPingPlayer(server, player)
+		if err != nil {
+			server.PlayerLog(player).Info("Couldne sending ping: " + err.Error())
+			return network.DisconnectPlayerConnection(server, player)
+		}
+		return nil
+	})
+	if err != nil {
+		err = fmt.Errorf("Error pinging player: %w", err)
+		errorHandeling.PrintError(server.Log, err)
+	}
+}
+
+// ProcessSendPingPlayer sends ping if state of the player allows it, otherwise the ping is skipped
+func ProcessSendPingPlayer(server *models.Server, player *models.Player) error {
 	commandTrigger := constants.CGCommands.ServerPingPlayer.Trigger
 	canFire, err := player.GetStateMachine().CanFire(commandTrigger)
 	if err != nil {
-		errorHandeling.AssertError(fmt.Errorf("cannot fire state machine"))
+		return fmt.Errorf("cannot fire state machine: %w", err)
 	}
 	if !canFire {
-		errorHandeling.AssertError(fmt.Errorf("cannot fire state machine"))
+		return nil
 	}
 
-	err = network.CommunicationServerPingPlayer(player)
+	err = network.CommunicationServerPingPlayer(server, player)
 	if err != nil {
 		err = fmt.Errorf("Error pinging player: %w", err)
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return err
 	}
 
@@ -1229,76 +1362,129 @@ func ProcessSendPingPlayer(player *models.Player) error {
 
 
 // region func ProcessPlayerTurn
-func handleServerStartTurn(turnPlayer *models.Player) (bool, error) {
+func handleServerStartTurn(server *models.Server, turnPlayer *models.Player) (bool, error) {
 	command := constants.CGCommands.ServerStartTurn
 	commandTrigger := command.Trigger
 	canFire, err := turnPlayer.GetStateMachine().CanFire(commandTrigger)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return false, fmt.Errorf("cannot join game %w", err)
 	}
 	if !canFire {
 		err = fmt.Errorf("state machine cannot fire")
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		//is next player turn
 		return true, nil
 	}
 
-	err = network.CommunicationServerStartTurn(turnPlayer)
+	err = network.CommunicationServerStartTurn(server, turnPlayer)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return false, fmt.Errorf("Error sending response: %w", err)
 	}
 
 	err = turnPlayer.FireStateMachine(commandTrigger)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return false, fmt.Errorf("Error sending response: %w", err)
 	}
 
 	return false, nil
 }
 
-func ProcessPlayerTurn(game *models.Game) error {
+func ProcessPlayerTurn(server *models.Server, game *models.Game) error {
+	// turns in progress may finish during shutdown, new ones are not started
+	if server.IsShuttingDown() {
+		return nil
+	}
+
 	turnPlayer, err := game.GetTurnPlayer()
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("Error sending response: %w", err)
 	}
 
 	//region ServerStartTurn
-	isNextPlayerTurn, err := handleServerStartTurn(turnPlayer)
+	isNextPlayerTurn, err := handleServerStartTurn(server, turnPlayer)
 	if err != nil {
 		return fmt.Errorf("Error sending response: %w", err)
 	}
 	if isNextPlayerTurn {
-		logger.Log.Errorf("Next player turn")
-		return __handleErrorMyTurn(turnPlayer, game)
+		server.PlayerLog(turnPlayer).Errorf("Next player turn")
+		return __handleErrorMyTurn(server, turnPlayer, game, constants.ErrorCodeInvalidState)
 	}
 	//endregion
 
 	//region ServerUpdateGameData
-	err = network.ProcessCommunicationServerUpdateGameData(game)
+	err = server.Events.Publish(models.TurnStarted{Player: turnPlayer, Game: game})
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("Error sending response: %w", err)
 	}
 	//endregion
 
-	//todo remove
-	time.Sleep(2 * time.Second)
-
-	//region ServerUpdateGameData
-	err = network.ProcessCommunicationServerUpdateGameData(game)
-	if err != nil {
-		errorHandeling.PrintError(err)
-		return fmt.Errorf("Error sending response: %w", err)
-	}
+	//region ServerUpdateGameData - repeated after turn start
+	server.Scheduler.Schedule(turnTimerKey(game), cTurnUpdateDelay, func() {
+		err := game.Execute(func() error {
+			return network.ProcessCommunicationServerUpdateGameData(server, game)
+		})
+		// game may have ended before the timer fired
+		if err != nil && !errors.Is(err, models.ErrGameStopped) {
+			errorHandeling.PrintError(server.Log, err)
+		}
+	})
 	//endregion
 
+	scheduleTurnDeadline(server, game, turnPlayer)
+
 	return nil
 }
 
+func turnTimerKey(game *models.Game) string {
+	return "turn:" + game.GetName()
+}
+
+func turnDeadlineKey(game *models.Game) string {
+	return "turn_deadline:" + game.GetName()
+}
+
+// scheduleTurnDeadline ends turn of the player when he does not roll within turn timeout
+func scheduleTurnDeadline(server *models.Server, game *models.Game, turnPlayer *models.Player) {
+	timeout := server.GetSettings().TurnTimeout
+	if timeout <= 0 {
+		server.Scheduler.Cancel(turnDeadlineKey(game))
+		return
+	}
+
+	turnNum := game.GetTurnNum()
+	server.Scheduler.Schedule(turnDeadlineKey(game), timeout, func() {
+		err := game.Execute(func() error {
+			return processTurnTimeout(server, game, turnPlayer, turnNum)
+		})
+		// game may have ended before the timer fired
+		if err != nil && !errors.Is(err, models.ErrGameStopped) {
+			errorHandeling.PrintError(server.Log, err)
+		}
+	})
+}
+
+// processTurnTimeout disconnects turn player with ErrorCodeTurnTimeout and passes the turn,
+// nothing happens when the turn has already ended
+func processTurnTimeout(server *models.Server, game *models.Game, turnPlayer *models.Player, turnNum int) error {
+	if !server.GameList.HasValue(game) || game.GetState() != models.Running {
+		return nil
+	}
+	if game.GetTurnNum() != turnNum || !game.IsPlayerTurn(turnPlayer) {
+		return nil
+	}
+	if !turnPlayer.IsConnected() || !turnPlayer.IsInTurn() {
+		return nil
+	}
+
+	server.PlayerLog(turnPlayer).Infof("TURN_TIMEOUT: Player %s did not roll in game %s", turnPlayer.GetNickname(), game.GetName())
+	return __handleErrorMyTurn(server, turnPlayer, game, constants.ErrorCodeTurnTimeout)
+}
+
 
 //endregion
 
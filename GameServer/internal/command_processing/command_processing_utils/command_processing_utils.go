@@ -6,11 +6,11 @@ import (
 	"gameserver/internal/utils/errorHandeling"
 )
 
-func ProcessResponseClientSucessByPlayer(player *models.Player, timeStamp string) error {
-	err := player.DecreaseResponseSuccessExpected(timeStamp)
+func ProcessResponseClientSucessByPlayer(server *models.Server, player *models.Player, sequenceNumber int, timeStamp string) error {
+	err := player.DecreaseResponseSuccessExpected(sequenceNumber, timeStamp)
 	if err != nil {
 		err = fmt.Errorf("Error decreasing response expected: %w", err)
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return err
 	}
 
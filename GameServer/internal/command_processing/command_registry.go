@@ -0,0 +1,184 @@
+package command_processing
+
+import (
+	"gameserver/internal/models"
+	"gameserver/internal/parser"
+	"gameserver/internal/utils/constants"
+	"gameserver/pkg/stateless"
+)
+
+//region DATA STRUCTURES
+
+type CommandDirection int
+
+const (
+	ClientToServer CommandDirection = iota
+	ServerToClient
+)
+
+// CommandDispatch decides how ProcessMessage runs the handler
+type CommandDispatch int
+
+const (
+	// DispatchGame runs handler on the game goroutine of the player, retried commands are answered from cache
+	DispatchGame CommandDispatch = iota
+	// DispatchLogin runs handler before the player exists, request has no player
+	DispatchLogin
+	// DispatchAck runs handler directly, acks are never cached
+	DispatchAck
+)
+
+// CommandRequest is one client command with validated params
+type CommandRequest struct {
+	Server         *models.Server
+	Session        *models.Session
+	PlayerNickname string
+	// Player is nil for DispatchLogin
+	Player          *models.Player
+	Command         constants.Command
+	Args            parser.CommandArgs
+	ConnectionInfo  models.ConnectionInfo
+	ClientMessageID string
+	// CorrelationID is of the received message, messages sent because of it carry it
+	CorrelationID string
+}
+
+type CommandHandler func(request CommandRequest) error
+
+// CommandInfo declares one command of the protocol
+type CommandInfo struct {
+	Command   constants.Command
+	Name      string
+	Direction CommandDirection
+	Dispatch  CommandDispatch
+	Params    []constants.ParamSchema
+	// RequiredTrigger has to be fireable in the player's state machine, otherwise the player is disconnected;
+	// nil skips the check
+	RequiredTrigger stateless.Trigger
+	// Handler is nil for ServerToClient commands
+	Handler CommandHandler
+	// HandleInvalidParams replaces the default disconnect when params do not match Params
+	HandleInvalidParams CommandHandler
+}
+
+//endregion
+
+// commandRegistry holds every command of the protocol by ID
+var commandRegistry = createCommandRegistry([]CommandInfo{
+	//CLIENT->SERVER
+	{
+		Command:  constants.CGCommands.ClientLogin,
+		Dispatch: DispatchLogin,
+		Handler:  processClientLogin,
+	},
+	{
+		Command: constants.CGCommands.ClientCreateGame,
+		Params: []constants.ParamSchema{
+			{Name: "gameName", Type: constants.ParamString},
+			{Name: "maxPlayers", Type: constants.ParamInt},
+		},
+		RequiredTrigger: constants.CGCommands.ClientCreateGame.Trigger,
+		Handler:         processClientCreateGame,
+	},
+	{
+		Command: constants.CGCommands.ClientJoinGame,
+		Params: []constants.ParamSchema{
+			{Name: "gameName", Type: constants.ParamName},
+		},
+		RequiredTrigger: constants.CGCommands.ClientJoinGame.Trigger,
+		Handler:         processClientJoinGame,
+	},
+	{
+		Command:         constants.CGCommands.ClientStartGame,
+		RequiredTrigger: constants.CGCommands.ClientStartGame.Trigger,
+		Handler:         processClientStartGame,
+	},
+	{
+		Command:         constants.CGCommands.ClientRollDice,
+		RequiredTrigger: constants.CGCommands.ClientRollDice.Trigger,
+		Handler:         processClientRollDice,
+	},
+	{
+		Command:         constants.CGCommands.ClientLogout,
+		RequiredTrigger: constants.CGCommands.ClientLogout.Trigger,
+		Handler:         processClientPlayerLogout,
+	},
+	{
+		Command: constants.CGCommands.ClientReconnect,
+		Handler: processClientReconnect,
+	},
+	{
+		Command: constants.CGCommands.ClientSelectedCubes,
+		Params: []constants.ParamSchema{
+			{Name: "cubeValues", Type: constants.ParamCubeValues},
+		},
+		RequiredTrigger:     constants.CGCommands.ClientSelectedCubes.Trigger,
+		Handler:             processClientSelectedCubes,
+		HandleInvalidParams: processInvalidSelectedCubes,
+	},
+
+	//RESPONSES CLIENT->SERVER
+	{
+		Command:  constants.CGCommands.ResponseClientSuccess,
+		Dispatch: DispatchAck,
+		Params: []constants.ParamSchema{
+			{Name: "seq", Type: constants.ParamPositiveInt, Optional: true},
+		},
+		Handler: processResponseClientSucess,
+	},
+
+	//SERVER->CLIENT
+	{Command: constants.CGCommands.ResponseServerSuccess, Direction: ServerToClient},
+	{Command: constants.CGCommands.ResponseServerError, Direction: ServerToClient},
+	{Command: constants.CGCommands.ResponseServerGameList, Direction: ServerToClient},
+	{Command: constants.CGCommands.ResponseServerSelectCubes, Direction: ServerToClient},
+	{Command: constants.CGCommands.ResponseServerEndTurn, Direction: ServerToClient},
+	{Command: constants.CGCommands.ResponseServerEndScore, Direction: ServerToClient},
+	{Command: constants.CGCommands.ResponseServerDiceSuccess, Direction: ServerToClient},
+	{Command: constants.CGCommands.ResponseServerReconnectBeforeGame, Direction: ServerToClient},
+	{Command: constants.CGCommands.ResponseServerReconnectRunningGame, Direction: ServerToClient},
+	{Command: constants.CGCommands.ServerUpdateStartGame, Direction: ServerToClient},
+	{Command: constants.CGCommands.ServerUpdateEndScore, Direction: ServerToClient},
+	{Command: constants.CGCommands.ServerUpdateNotEnoughPlayers, Direction: ServerToClient},
+	{Command: constants.CGCommands.ServerShutdown, Direction: ServerToClient},
+	{Command: constants.CGCommands.ServerUpdateGameData, Direction: ServerToClient},
+	{Command: constants.CGCommands.ServerUpdateGameList, Direction: ServerToClient},
+	{Command: constants.CGCommands.ServerUpdatePlayerList, Direction: ServerToClient},
+	{Command: constants.CGCommands.ServerStartTurn, Direction: ServerToClient},
+	{Command: constants.CGCommands.ServerPingPlayer, Direction: ServerToClient},
+	{
+		Command:   constants.CGCommands.ServerAnnouncement,
+		Direction: ServerToClient,
+		Params: []constants.ParamSchema{
+			{Name: "message", Type: constants.ParamString},
+		},
+	},
+})
+
+//region FUNCTIONS
+
+func createCommandRegistry(commands []CommandInfo) map[int]CommandInfo {
+	registry := make(map[int]CommandInfo, len(commands))
+	for _, commandInfo := range commands {
+		commandInfo.Name = constants.GetCommandName(commandInfo.Command.CommandID)
+		registry[commandInfo.Command.CommandID] = commandInfo
+	}
+	return registry
+}
+
+// GetCommandInfo returns declaration of command
+func GetCommandInfo(commandID int) (CommandInfo, bool) {
+	commandInfo, ok := commandRegistry[commandID]
+	return commandInfo, ok
+}
+
+// getClientCommandInfo returns command which client may send
+func getClientCommandInfo(commandID int) (CommandInfo, bool) {
+	commandInfo, ok := commandRegistry[commandID]
+	if !ok || commandInfo.Direction != ClientToServer || commandInfo.Handler == nil {
+		return CommandInfo{}, false
+	}
+	return commandInfo, true
+}
+
+//endregion
@@ -0,0 +1,40 @@
+package command_processing
+
+import (
+	"gameserver/internal/utils/constants"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEveryServerCommandIsDeclared(t *testing.T) {
+	commands := reflect.ValueOf(constants.CGCommands)
+	for i := 0; i < commands.NumField(); i++ {
+		command := commands.Field(i).Interface().(constants.Command)
+		name := commands.Type().Field(i).Name
+		if !strings.HasPrefix(name, "Server") && !strings.HasPrefix(name, "ResponseServer") {
+			continue
+		}
+
+		commandInfo, ok := GetCommandInfo(command.CommandID)
+		if !ok {
+			t.Errorf("command %s (%d) is not declared in the registry", name, command.CommandID)
+			continue
+		}
+		if commandInfo.Direction != ServerToClient {
+			t.Errorf("command %s is not declared as server to client", name)
+		}
+	}
+}
+
+func TestServerAnnouncementDeclaresMessage(t *testing.T) {
+	commandInfo, ok := GetCommandInfo(constants.CGCommands.ServerAnnouncement.CommandID)
+	if !ok {
+		t.Fatalf("ServerAnnouncement is not declared")
+	}
+
+	expected := []constants.ParamSchema{{Name: "message", Type: constants.ParamString}}
+	if !reflect.DeepEqual(commandInfo.Params, expected) {
+		t.Fatalf("expected params %v, got %v", expected, commandInfo.Params)
+	}
+}
@@ -0,0 +1,400 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gameserver/internal/utils/constants"
+	"github.com/sirupsen/logrus"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+//region DATA STRUCTURES
+
+// Config is the whole configuration of the server. Values come from defaults, config file,
+// GAMESERVER_* environment variables and command line flags, each of them overrides the previous one.
+type Config struct {
+	Debug     bool            `json:"debug"`
+	Server    ServerConfig    `json:"server"`
+	Network   NetworkConfig   `json:"network"`
+	Messages  MessagesConfig  `json:"messages"`
+	Game      GameConfig      `json:"game"`
+	Snapshot  SnapshotConfig  `json:"snapshot"`
+	Log       LogConfig       `json:"log"`
+	WebSocket WebSocketConfig `json:"websocket"`
+	TLS       TLSConfig       `json:"tls"`
+	HTTP      HTTPConfig      `json:"http"`
+	Metrics   MetricsConfig   `json:"metrics"`
+	Health    HealthConfig    `json:"health"`
+	Admin     AdminConfig     `json:"admin"`
+	Journal   JournalConfig   `json:"journal"`
+}
+
+type ServerConfig struct {
+	IP   string `json:"ip"`
+	Port int    `json:"port"`
+	// MOTD is announced to every player after login, empty disables it
+	MOTD string `json:"motd"`
+}
+
+// NetworkConfig holds timeouts of connections and of the players on them
+type NetworkConfig struct {
+	// AckTimeoutSeconds is time for ResponseClientSuccess, connections are also read in this interval
+	AckTimeoutSeconds   int `json:"ack_timeout_seconds"`
+	PingIntervalSeconds int `json:"ping_interval_seconds"`
+	// ReconnectWindowSeconds is how long disconnected player may reconnect before he is removed
+	ReconnectWindowSeconds int `json:"reconnect_window_seconds"`
+	WriteTimeoutSeconds    int `json:"write_timeout_seconds"`
+	ShutdownTimeoutSeconds int `json:"shutdown_timeout_seconds"`
+	// HandlerStuckSeconds is how long a handler may run on game goroutine before health check fails
+	HandlerStuckSeconds int `json:"handler_stuck_seconds"`
+	SendQueueSize       int `json:"send_queue_size"`
+	// QueueFullPolicy is constants.CQueueFullDropUpdates or constants.CQueueFullDisconnect
+	QueueFullPolicy string `json:"queue_full_policy"`
+	MaxRetransmits  int    `json:"max_retransmits"`
+	// RateLimitMessages is how many messages a client may send within RateLimitWindowSeconds, acks are not counted,
+	// 0 disables the limit
+	RateLimitMessages      int `json:"rate_limit_messages"`
+	RateLimitWindowSeconds int `json:"rate_limit_window_seconds"`
+}
+
+// MessagesConfig limits received messages and names in them
+type MessagesConfig struct {
+	MaxSize         int `json:"max_size"`
+	BufferSize      int `json:"buffer_size"`
+	NameMinChars    int `json:"name_min_chars"`
+	NameMaxChars    int `json:"name_max_chars"`
+	ClientCacheSize int `json:"client_cache_size"`
+}
+
+type GameConfig struct {
+	MaxScore int `json:"max_score"`
+	// TurnTimeoutSeconds is time of the turn player to roll, 0 disables the limit
+	TurnTimeoutSeconds int `json:"turn_timeout_seconds"`
+}
+
+// SnapshotConfig is file with games saved for restart, empty file disables snapshots
+type SnapshotConfig struct {
+	File            string `json:"file"`
+	IntervalSeconds int    `json:"interval_seconds"`
+}
+
+type LogConfig struct {
+	Level      string `json:"level"`
+	JSONFormat bool   `json:"json_format"`
+	Caller     bool   `json:"caller"`
+	ToFile     bool   `json:"to_file"`
+	Folder     string `json:"folder"`
+	// limits of log files, 0 disables the limit
+	MaxSizeMB   int  `json:"max_size_mb"`
+	MaxAgeHours int  `json:"max_age_hours"`
+	MaxFiles    int  `json:"max_files"`
+	Compress    bool `json:"compress"`
+}
+
+type WebSocketConfig struct {
+	Enabled bool   `json:"enabled"`
+	IP      string `json:"ip"`
+	Port    int    `json:"port"`
+	Path    string `json:"path"`
+	// AllowedOrigins are browser origins accepted besides the host of the request, "*" accepts every origin
+	AllowedOrigins []string `json:"allowed_origins"`
+}
+
+type TLSConfig struct {
+	Enabled      bool   `json:"enabled"`
+	CertFile     string `json:"cert_file"`
+	KeyFile      string `json:"key_file"`
+	MinVersion   string `json:"min_version"`
+	ClientAuth   string `json:"client_auth"`
+	ClientCAFile string `json:"client_ca_file"`
+	SelfSigned   bool   `json:"self_signed"`
+}
+
+type HTTPConfig struct {
+	Enabled bool   `json:"enabled"`
+	IP      string `json:"ip"`
+	Port    int    `json:"port"`
+}
+
+type MetricsConfig struct {
+	Enabled bool   `json:"enabled"`
+	IP      string `json:"ip"`
+	Port    int    `json:"port"`
+}
+
+// HealthConfig is listener of /healthz and /readyz, the checks are served also by metrics listener
+type HealthConfig struct {
+	Enabled bool   `json:"enabled"`
+	IP      string `json:"ip"`
+	Port    int    `json:"port"`
+}
+
+type AdminConfig struct {
+	Enabled bool   `json:"enabled"`
+	Network string `json:"network"`
+	Address string `json:"address"`
+	Token   string `json:"token"`
+}
+
+type JournalConfig struct {
+	MaxMessagesPerSession int    `json:"max_messages_per_session"`
+	RetentionMinutes      int    `json:"retention_minutes"`
+	SpillEnabled          bool   `json:"spill_enabled"`
+	Folder                string `json:"folder"`
+	// limits of journal files, 0 disables the limit
+	MaxSizeMB   int  `json:"max_size_mb"`
+	MaxAgeHours int  `json:"max_age_hours"`
+	MaxFiles    int  `json:"max_files"`
+	Compress    bool `json:"compress"`
+}
+
+//endregion
+
+//region FUNCTIONS
+
+// Default returns configuration used for everything the config file does not set
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			IP:   "0.0.0.0",
+			Port: 10000,
+		},
+		Network: NetworkConfig{
+			AckTimeoutSeconds:      int(constants.CTimeout.Seconds()),
+			PingIntervalSeconds:    int(constants.CPingTime.Seconds()),
+			ReconnectWindowSeconds: int(constants.CTotalDisconnectTime.Seconds()),
+			WriteTimeoutSeconds:    int(constants.CWriteTimeout.Seconds()),
+			ShutdownTimeoutSeconds: int(constants.CShutdownTimeout.Seconds()),
+			HandlerStuckSeconds:    int(constants.CGameHandlerStuckTime.Seconds()),
+			SendQueueSize:          constants.CSendQueueSize,
+			QueueFullPolicy:        constants.CQueueFullDropUpdates,
+			MaxRetransmits:         constants.CMaxRetransmits,
+			RateLimitWindowSeconds: 1,
+		},
+		Messages: MessagesConfig{
+			MaxSize:         constants.CMessageMaxSize,
+			BufferSize:      constants.CMessageBufferSize,
+			NameMinChars:    constants.CMessageNameMinChars,
+			NameMaxChars:    constants.CMessageNameMaxChars,
+			ClientCacheSize: constants.CClientMessageCacheSize,
+		},
+		Game: GameConfig{
+			MaxScore:           constants.CMaxScore,
+			TurnTimeoutSeconds: int(constants.CTurnTimeout.Seconds()),
+		},
+		Snapshot: SnapshotConfig{
+			File:            constants.CSnapshotFilePath,
+			IntervalSeconds: int(constants.CSnapshotInterval.Seconds()),
+		},
+		Log: LogConfig{
+			Level:  "debug",
+			Caller: true,
+			ToFile: true,
+			Folder: constants.CLogsFolderPath,
+		},
+		WebSocket: WebSocketConfig{
+			Path: "/",
+		},
+		Health: HealthConfig{
+			IP:   "0.0.0.0",
+			Port: 10004,
+		},
+		Journal: JournalConfig{
+			MaxMessagesPerSession: 200,
+			RetentionMinutes:      30,
+			Folder:                constants.CJournalFolderPath,
+		},
+	}
+}
+
+// Load returns defaults overridden by config file, environment and flags, the result is validated.
+// Empty filePath means no config file.
+func Load(filePath string, environ []string, flags *FlagOverrides) (Config, error) {
+	config := Default()
+
+	if filePath != "" {
+		err := ReadFile(filePath, &config)
+		if err != nil {
+			return Config{}, err
+		}
+	}
+
+	err := config.ApplyEnvironment(environ)
+	if err != nil {
+		return Config{}, err
+	}
+
+	if flags != nil {
+		err = flags.Apply(&config)
+		if err != nil {
+			return Config{}, err
+		}
+	}
+
+	err = config.Validate()
+	if err != nil {
+		return Config{}, err
+	}
+
+	return config, nil
+}
+
+// ReadFile overrides values of config by config file in json format, unknown keys are reported as error
+func ReadFile(filePath string, config *Config) error {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("could not read config file: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(config)
+	if err != nil {
+		return fmt.Errorf("could not unmarshal config file %s: %w", filePath, err)
+	}
+
+	return nil
+}
+
+//endregion
+
+//region VALIDATION
+
+// Validate returns all invalid values joined in one error, every line names the key
+func (c *Config) Validate() error {
+	var problems []error
+	check := func(isValid bool, format string, args ...interface{}) {
+		if !isValid {
+			problems = append(problems, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(isValidIP(c.Server.IP), "server.ip: invalid ip address %q", c.Server.IP)
+	check(isValidPort(c.Server.Port), "server.port: invalid port %d", c.Server.Port)
+	check(len(c.Server.MOTD) <= constants.CAnnouncementMaxLength, "server.motd: is longer than %d characters", constants.CAnnouncementMaxLength)
+	check(!strings.ContainsAny(c.Server.MOTD, constants.CAnnouncementForbiddenChars), "server.motd: must not contain any of %s", constants.CAnnouncementForbiddenChars)
+
+	check(c.Network.AckTimeoutSeconds > 0, "network.ack_timeout_seconds: has to be positive")
+	check(c.Network.PingIntervalSeconds > 0, "network.ping_interval_seconds: has to be positive")
+	check(c.Network.ReconnectWindowSeconds > 0, "network.reconnect_window_seconds: has to be positive")
+	check(c.Network.WriteTimeoutSeconds > 0, "network.write_timeout_seconds: has to be positive")
+	check(c.Network.ShutdownTimeoutSeconds > 0, "network.shutdown_timeout_seconds: has to be positive")
+	check(c.Network.HandlerStuckSeconds > 0, "network.handler_stuck_seconds: has to be positive")
+	check(c.Network.SendQueueSize > 0, "network.send_queue_size: has to be positive")
+	check(c.Network.QueueFullPolicy == constants.CQueueFullDropUpdates || c.Network.QueueFullPolicy == constants.CQueueFullDisconnect,
+		"network.queue_full_policy: has to be %q or %q", constants.CQueueFullDropUpdates, constants.CQueueFullDisconnect)
+	check(c.Network.MaxRetransmits >= 0, "network.max_retransmits: cannot be negative")
+	check(c.Network.RateLimitMessages >= 0, "network.rate_limit_messages: cannot be negative")
+	check(c.Network.RateLimitWindowSeconds > 0, "network.rate_limit_window_seconds: has to be positive")
+
+	check(c.Messages.MaxSize > 0, "messages.max_size: has to be positive")
+	check(c.Messages.BufferSize > 0, "messages.buffer_size: has to be positive")
+	check(c.Messages.NameMinChars > 0, "messages.name_min_chars: has to be positive")
+	check(c.Messages.NameMaxChars >= c.Messages.NameMinChars, "messages.name_max_chars: cannot be less than name_min_chars")
+	check(c.Messages.ClientCacheSize > 0, "messages.client_cache_size: has to be positive")
+
+	check(c.Game.MaxScore > 0, "game.max_score: has to be positive")
+	check(c.Game.TurnTimeoutSeconds >= 0, "game.turn_timeout_seconds: cannot be negative")
+
+	check(c.Snapshot.IntervalSeconds > 0, "snapshot.interval_seconds: has to be positive")
+
+	_, err := logrus.ParseLevel(c.Log.Level)
+	check(err == nil, "log.level: invalid level %q", c.Log.Level)
+	check(!c.Log.ToFile || c.Log.Folder != "", "log.folder: is empty")
+	check(c.Log.MaxSizeMB >= 0 && c.Log.MaxAgeHours >= 0 && c.Log.MaxFiles >= 0, "log: limits cannot be negative")
+
+	if c.WebSocket.Enabled {
+		check(isValidIP(c.WebSocket.IP), "websocket.ip: invalid ip address %q", c.WebSocket.IP)
+		check(isValidPort(c.WebSocket.Port), "websocket.port: invalid port %d", c.WebSocket.Port)
+		// empty path means root like in the old config files
+		if c.WebSocket.Path == "" {
+			c.WebSocket.Path = "/"
+		}
+		for _, origin := range c.WebSocket.AllowedOrigins {
+			check(isValidOrigin(origin), "websocket.allowed_origins: invalid origin %q", origin)
+		}
+	}
+
+	if c.TLS.Enabled && !c.TLS.SelfSigned {
+		check(c.TLS.CertFile != "" && c.TLS.KeyFile != "", "tls: cert_file and key_file are required")
+	}
+
+	if c.HTTP.Enabled {
+		check(isValidIP(c.HTTP.IP), "http.ip: invalid ip address %q", c.HTTP.IP)
+		check(isValidPort(c.HTTP.Port), "http.port: invalid port %d", c.HTTP.Port)
+	}
+
+	if c.Metrics.Enabled {
+		check(isValidIP(c.Metrics.IP), "metrics.ip: invalid ip address %q", c.Metrics.IP)
+		check(isValidPort(c.Metrics.Port), "metrics.port: invalid port %d", c.Metrics.Port)
+	}
+
+	// process supervisor gets /healthz and /readyz only from health or metrics listener, http api does not serve them
+	if c.Health.Enabled {
+		check(isValidIP(c.Health.IP), "health.ip: invalid ip address %q", c.Health.IP)
+		check(isValidPort(c.Health.Port), "health.port: invalid port %d", c.Health.Port)
+		check(!c.Metrics.Enabled || c.Health.Port != c.Metrics.Port,
+			"health.port: metrics listener already serves health checks on port %d", c.Metrics.Port)
+	}
+
+	if c.Admin.Enabled {
+		check(c.Admin.Token != "", "admin.token: is required")
+		switch c.Admin.Network {
+		case "unix":
+			check(c.Admin.Address != "", "admin.address: socket path is required")
+		case "tcp":
+			check(isLoopbackAddress(c.Admin.Address), "admin.address: tcp address has to be localhost ip and port")
+		default:
+			check(false, "admin.network: invalid network %q", c.Admin.Network)
+		}
+	}
+
+	check(c.Journal.MaxMessagesPerSession >= 0 && c.Journal.RetentionMinutes >= 0, "journal: limits cannot be negative")
+	check(!c.Journal.SpillEnabled || c.Journal.Folder != "", "journal.folder: is empty")
+	check(c.Journal.MaxSizeMB >= 0 && c.Journal.MaxAgeHours >= 0 && c.Journal.MaxFiles >= 0, "journal: file limits cannot be negative")
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid config:\n%w", errors.Join(problems...))
+	}
+	return nil
+}
+
+func isLoopbackAddress(address string) bool {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return false
+	}
+
+	portNumber, err := strconv.Atoi(port)
+	if err != nil || !isValidPort(portNumber) {
+		return false
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func isValidPort(port int) bool {
+	return port > 0 && port <= 65535
+}
+
+func isValidIP(ip string) bool {
+	return net.ParseIP(ip) != nil
+}
+
+// isValidOrigin accepts "*" or origin with scheme and host, e.g. "https://example.com:8080"
+func isValidOrigin(origin string) bool {
+	if origin == "*" {
+		return true
+	}
+	originURL, err := url.Parse(origin)
+	return err == nil && originURL.Scheme != "" && originURL.Host != ""
+}
+
+//endregion
@@ -0,0 +1,232 @@
+package config
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+//region HELPERS
+
+// expectInvalid fails unless config is rejected with a problem containing every part of expected
+func expectInvalid(t *testing.T, config Config, expected ...string) {
+	t.Helper()
+
+	err := config.Validate()
+	if err == nil {
+		t.Fatalf("expected invalid config with %v", expected)
+	}
+	for _, part := range expected {
+		if !strings.Contains(err.Error(), part) {
+			t.Fatalf("expected %q in error, got %v", part, err)
+		}
+	}
+}
+
+// enableTestWebSocket enables websocket listener on a valid address, so only origins can be invalid
+func enableTestWebSocket(config *Config, origins ...string) {
+	config.WebSocket.Enabled = true
+	config.WebSocket.IP = "127.0.0.1"
+	config.WebSocket.Port = 10010
+	config.WebSocket.AllowedOrigins = origins
+}
+
+func writeTestConfigFile(t *testing.T, content string) string {
+	t.Helper()
+
+	filePath := filepath.Join(t.TempDir(), "config.json")
+	err := os.WriteFile(filePath, []byte(content), 0644)
+	if err != nil {
+		t.Fatalf("cannot write config file: %v", err)
+	}
+	return filePath
+}
+
+func parseTestFlags(t *testing.T, args ...string) *FlagOverrides {
+	t.Helper()
+
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	flagSet.SetOutput(io.Discard)
+	overrides := BindFlags(flagSet)
+	err := flagSet.Parse(args)
+	if err != nil {
+		t.Fatalf("cannot parse flags %v: %v", args, err)
+	}
+	return overrides
+}
+
+//endregion
+
+//region TESTS
+
+func TestDefaultIsValid(t *testing.T) {
+	config := Default()
+	if err := config.Validate(); err != nil {
+		t.Fatalf("expected valid default config, got %v", err)
+	}
+}
+
+func TestValidateRejectsInvalidValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		change   func(config *Config)
+		expected string
+	}{
+		{"invalid ip", func(c *Config) { c.Server.IP = "localhost:1" }, "server.ip"},
+		{"zero port", func(c *Config) { c.Server.Port = 0 }, "server.port"},
+		{"port out of range", func(c *Config) { c.Server.Port = 70000 }, "server.port"},
+		{"zero ping interval", func(c *Config) { c.Network.PingIntervalSeconds = 0 }, "network.ping_interval_seconds"},
+		{"unknown queue policy", func(c *Config) { c.Network.QueueFullPolicy = "block" }, "network.queue_full_policy"},
+		{"negative retransmits", func(c *Config) { c.Network.MaxRetransmits = -1 }, "network.max_retransmits"},
+		{"max name shorter than min", func(c *Config) { c.Messages.NameMaxChars = c.Messages.NameMinChars - 1 }, "messages.name_max_chars"},
+		{"negative turn timeout", func(c *Config) { c.Game.TurnTimeoutSeconds = -1 }, "game.turn_timeout_seconds"},
+		{"unknown log level", func(c *Config) { c.Log.Level = "verbose" }, "log.level"},
+		{"log file without folder", func(c *Config) { c.Log.ToFile, c.Log.Folder = true, "" }, "log.folder"},
+		{"origin without scheme", func(c *Config) { enableTestWebSocket(c, "example.com") }, "websocket.allowed_origins"},
+		{"tls without key", func(c *Config) { c.TLS.Enabled, c.TLS.CertFile = true, "cert.pem" }, "tls"},
+		{"health on metrics port", func(c *Config) {
+			c.Metrics.Enabled, c.Health.Enabled = true, true
+			c.Health.Port = c.Metrics.Port
+		}, "health"},
+		{"negative journal limit", func(c *Config) { c.Journal.MaxMessagesPerSession = -1 }, "journal"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := Default()
+			test.change(&config)
+			expectInvalid(t, config, test.expected)
+		})
+	}
+}
+
+func TestValidateAcceptsAnyOriginAndOriginWithPort(t *testing.T) {
+	config := Default()
+	enableTestWebSocket(&config, "*", "https://example.com:8080")
+
+	if err := config.Validate(); err != nil {
+		t.Fatalf("expected valid origins, got %v", err)
+	}
+}
+
+func TestValidateReportsAllProblems(t *testing.T) {
+	config := Default()
+	config.Server.Port = -1
+	config.Game.TurnTimeoutSeconds = -5
+	config.Network.QueueFullPolicy = ""
+
+	expectInvalid(t, config, "server.port", "game.turn_timeout_seconds", "network.queue_full_policy")
+}
+
+func TestApplyEnvironment(t *testing.T) {
+	config := Default()
+	environ := []string{
+		"GAMESERVER_SERVER_PORT=10100",
+		"GAMESERVER_HEALTH_ENABLED=true",
+		"GAMESERVER_LOG_LEVEL=debug",
+		"GAMESERVER_WEBSOCKET_ALLOWED_ORIGINS=https://a.com, https://b.com,",
+		// variables of unknown keys and other programs are ignored
+		"GAMESERVER_UNKNOWN_KEY=1",
+		"PATH=/usr/bin",
+	}
+
+	err := config.ApplyEnvironment(environ)
+	if err != nil {
+		t.Fatalf("cannot apply environment: %v", err)
+	}
+
+	if config.Server.Port != 10100 || !config.Health.Enabled || config.Log.Level != "debug" {
+		t.Fatalf("environment was not applied, got port %d, health %v, level %s", config.Server.Port, config.Health.Enabled, config.Log.Level)
+	}
+	if expected := []string{"https://a.com", "https://b.com"}; !reflect.DeepEqual(config.WebSocket.AllowedOrigins, expected) {
+		t.Fatalf("expected origins %v, got %v", expected, config.WebSocket.AllowedOrigins)
+	}
+}
+
+func TestApplyEnvironmentRejectsInvalidValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		variable string
+	}{
+		{"number", "GAMESERVER_SERVER_PORT=port"},
+		{"bool", "GAMESERVER_DEBUG=maybe"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := Default()
+			err := config.ApplyEnvironment([]string{test.variable})
+			name, _, _ := strings.Cut(test.variable, "=")
+			if err == nil || !strings.Contains(err.Error(), name) {
+				t.Fatalf("expected error naming %s, got %v", name, err)
+			}
+		})
+	}
+}
+
+func TestFlagsOverrideOnlyGivenKeys(t *testing.T) {
+	config := Default()
+	config.Server.MOTD = "from file"
+	flags := parseTestFlags(t, "-server.port", "10200", "-debug", "-game.turn_timeout_seconds=30")
+
+	err := flags.Apply(&config)
+	if err != nil {
+		t.Fatalf("cannot apply flags: %v", err)
+	}
+
+	if config.Server.Port != 10200 || !config.Debug || config.Game.TurnTimeoutSeconds != 30 {
+		t.Fatalf("flags were not applied, got port %d, debug %v, turn timeout %d", config.Server.Port, config.Debug, config.Game.TurnTimeoutSeconds)
+	}
+	if config.Server.MOTD != "from file" {
+		t.Fatalf("key without flag was changed to %q", config.Server.MOTD)
+	}
+
+	config = Default()
+	err = parseTestFlags(t, "-server.port", "high").Apply(&config)
+	if err == nil || !strings.Contains(err.Error(), "-server.port") {
+		t.Fatalf("expected error naming -server.port, got %v", err)
+	}
+}
+
+func TestLoadOrderIsFileEnvironmentFlags(t *testing.T) {
+	filePath := writeTestConfigFile(t, `{"server": {"port": 10300, "motd": "file"}, "log": {"level": "warn"}}`)
+	environ := []string{"GAMESERVER_SERVER_PORT=10301", "GAMESERVER_LOG_LEVEL=error"}
+	flags := parseTestFlags(t, "-server.port", "10302")
+
+	config, err := Load(filePath, environ, flags)
+	if err != nil {
+		t.Fatalf("cannot load config: %v", err)
+	}
+
+	if config.Server.Port != 10302 || config.Log.Level != "error" || config.Server.MOTD != "file" {
+		t.Fatalf("expected port of flag, level of environment and motd of file, got %d, %s, %q", config.Server.Port, config.Log.Level, config.Server.MOTD)
+	}
+}
+
+func TestLoadRejectsInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		environ  []string
+		expected string
+	}{
+		{"unknown key", `{"server": {"prot": 10000}}`, nil, "prot"},
+		{"invalid value in file", `{"game": {"turn_timeout_seconds": -1}}`, nil, "game.turn_timeout_seconds"},
+		{"invalid value in environment", `{}`, []string{"GAMESERVER_NETWORK_QUEUE_FULL_POLICY=wait"}, "network.queue_full_policy"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Load(writeTestConfigFile(t, test.content), test.environ, nil)
+			if err == nil || !strings.Contains(err.Error(), test.expected) {
+				t.Fatalf("expected error containing %q, got %v", test.expected, err)
+			}
+		})
+	}
+}
+
+//endregion
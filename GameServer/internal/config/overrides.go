@@ -0,0 +1,190 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// region CONSTANTS
+const (
+	// CEnvironmentPrefix starts names of environment overrides, e.g. GAMESERVER_SERVER_PORT for server.port
+	CEnvironmentPrefix = "GAMESERVER_"
+	// CEnvironmentConfigPath is environment variable with path of config file, -config flag overrides it
+	CEnvironmentConfigPath = CEnvironmentPrefix + "CONFIG"
+)
+
+//endregion
+
+//region DATA STRUCTURES
+
+// field is one value of Config, key is its path in config file, e.g. "network.ping_interval_seconds"
+type field struct {
+	key   string
+	value reflect.Value
+}
+
+// FlagOverrides remembers values of flags given on command line, flags which were not given keep config file values
+type FlagOverrides struct {
+	values map[string]string
+}
+
+// flagValue records value of one config key, Config is filled later because flags are parsed before config file is read
+type flagValue struct {
+	key       string
+	overrides *FlagOverrides
+	isBool    bool
+}
+
+//endregion
+
+//region FIELDS
+
+// fields returns every value of config with its key, sections are nested structs named by json tags
+func fields(config *Config) []field {
+	var result []field
+	collectFields(reflect.ValueOf(config).Elem(), "", &result)
+	return result
+}
+
+func collectFields(value reflect.Value, prefix string, result *[]field) {
+	valueType := value.Type()
+	for i := 0; i < valueType.NumField(); i++ {
+		name := strings.Split(valueType.Field(i).Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+
+		fieldValue := value.Field(i)
+		if fieldValue.Kind() == reflect.Struct {
+			collectFields(fieldValue, prefix+name+".", result)
+			continue
+		}
+		*result = append(*result, field{key: prefix + name, value: fieldValue})
+	}
+}
+
+// set parses text by type of the field, lists of strings are separated by commas
+func (f field) set(text string) error {
+	switch f.value.Kind() {
+	case reflect.String:
+		f.value.SetString(text)
+	case reflect.Bool:
+		value, err := strconv.ParseBool(text)
+		if err != nil {
+			return fmt.Errorf("invalid bool %q", text)
+		}
+		f.value.SetBool(value)
+	case reflect.Int:
+		value, err := strconv.Atoi(text)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", text)
+		}
+		f.value.SetInt(int64(value))
+	case reflect.Slice:
+		if f.value.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", f.value.Type())
+		}
+		// list is separated by commas, e.g. "https://a.com,https://b.com"
+		values := []string{}
+		for _, value := range strings.Split(text, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+		f.value.Set(reflect.ValueOf(values))
+	default:
+		return fmt.Errorf("unsupported type %s", f.value.Kind())
+	}
+	return nil
+}
+
+// environmentName returns name of environment variable of key, e.g. GAMESERVER_LOG_LEVEL for log.level
+func environmentName(key string) string {
+	return CEnvironmentPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+//endregion
+
+//region ENVIRONMENT
+
+// ApplyEnvironment overrides values by GAMESERVER_* variables, environ is in form of os.Environ
+func (c *Config) ApplyEnvironment(environ []string) error {
+	values := make(map[string]string)
+	for _, variable := range environ {
+		name, value, ok := strings.Cut(variable, "=")
+		if ok && strings.HasPrefix(name, CEnvironmentPrefix) {
+			values[name] = value
+		}
+	}
+
+	for _, f := range fields(c) {
+		value, ok := values[environmentName(f.key)]
+		if !ok {
+			continue
+		}
+
+		err := f.set(value)
+		if err != nil {
+			return fmt.Errorf("environment %s: %w", environmentName(f.key), err)
+		}
+	}
+
+	return nil
+}
+
+//endregion
+
+//region FLAGS
+
+// BindFlags defines flag for every config key on flagSet, e.g. -server.port 10005 or -debug
+func BindFlags(flagSet *flag.FlagSet) *FlagOverrides {
+	overrides := &FlagOverrides{values: make(map[string]string)}
+
+	defaults := Default()
+	for _, f := range fields(&defaults) {
+		value := &flagValue{key: f.key, overrides: overrides, isBool: f.value.Kind() == reflect.Bool}
+		usage := fmt.Sprintf("overrides %s of config file, also %s", f.key, environmentName(f.key))
+		flagSet.Var(value, f.key, usage)
+	}
+
+	return overrides
+}
+
+// Apply overrides values of config by flags given on command line
+func (o *FlagOverrides) Apply(config *Config) error {
+	for _, f := range fields(config) {
+		value, ok := o.values[f.key]
+		if !ok {
+			continue
+		}
+
+		err := f.set(value)
+		if err != nil {
+			return fmt.Errorf("flag -%s: %w", f.key, err)
+		}
+	}
+
+	return nil
+}
+
+func (v *flagValue) String() string {
+	if v == nil || v.overrides == nil {
+		return ""
+	}
+	return v.overrides.values[v.key]
+}
+
+func (v *flagValue) Set(text string) error {
+	v.overrides.values[v.key] = text
+	return nil
+}
+
+// IsBoolFlag lets bool keys be given without value, e.g. -tls.enabled
+func (v *flagValue) IsBoolFlag() bool {
+	return v.isBool
+}
+
+//endregion
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"reflect"
+)
+
+// reloadableKeys are keys which a running server applies without restart, they are read again on every use,
+// game.max_score is taken only by games created after reload
+var reloadableKeys = map[string]bool{
+	"server.motd":                       true,
+	"network.ack_timeout_seconds":       true,
+	"network.ping_interval_seconds":     true,
+	"network.reconnect_window_seconds":  true,
+	"network.shutdown_timeout_seconds":  true,
+	"network.handler_stuck_seconds":     true,
+	"network.max_retransmits":           true,
+	"network.rate_limit_messages":       true,
+	"network.rate_limit_window_seconds": true,
+	"game.max_score":                    true,
+	"game.turn_timeout_seconds":         true,
+	"snapshot.interval_seconds":         true,
+	"log.level":                         true,
+}
+
+// IsReloadable returns true if key can be changed without restart
+func IsReloadable(key string) bool {
+	return reloadableKeys[key]
+}
+
+// Reload compares configuration of running server with loaded one, it returns running configuration with changed
+// reloadable keys, the changed keys and changed keys which need restart. Keys needing restart keep the running value,
+// so they are reported again on the next reload until the server restarts.
+func Reload(running Config, loaded Config) (Config, []string, []string) {
+	result := running
+	applied := []string{}
+	restartRequired := []string{}
+
+	resultFields := fields(&result)
+	loadedFields := fields(&loaded)
+	for i, f := range resultFields {
+		loadedValue := loadedFields[i].value
+		if reflect.DeepEqual(f.value.Interface(), loadedValue.Interface()) {
+			continue
+		}
+
+		if !IsReloadable(f.key) {
+			restartRequired = append(restartRequired, f.key)
+			continue
+		}
+
+		f.value.Set(loadedValue)
+		applied = append(applied, f.key)
+	}
+
+	return result, applied, restartRequired
+}
@@ -0,0 +1,108 @@
+package health
+
+import (
+	"encoding/json"
+	"fmt"
+	"gameserver/internal/logger"
+	"gameserver/internal/models"
+	"gameserver/internal/utils/errorHandeling"
+	"net/http"
+	"time"
+)
+
+//region DATA STRUCTURES
+
+// statusView is answer of both checks, problems are empty when the check passes
+type statusView struct {
+	Status   string   `json:"status"`
+	Problems []string `json:"problems"`
+}
+
+//endregion
+
+//region CHECKS
+
+// CheckHealth finds wedged state of the server: stuck game goroutine, stopped accept loop or failing log.
+// Maintenance and shutdown are healthy, the process only should not get new players.
+func CheckHealth(server *models.Server) []string {
+	problems := []string{}
+
+	for _, game := range server.GameList.GetValuesArray() {
+		duration := game.GetHandlerDuration()
+		if duration > server.GetSettings().HandlerStuckTime {
+			problems = append(problems, fmt.Sprintf("game %s is stuck in handler for %s", game.GetName(), duration.Round(time.Second)))
+		}
+	}
+
+	// accept loop ends by itself only when server is shutting down
+	if server.GetListener() != nil && !server.IsAccepting() && !server.IsShuttingDown() {
+		problems = append(problems, "accept loop has stopped")
+	}
+
+	err := logger.GetSinkError()
+	if err != nil {
+		problems = append(problems, "log sink failed: "+err.Error())
+	}
+
+	return problems
+}
+
+// CheckReady returns why the server should not get new players, listener has to be bound and accepting
+func CheckReady(server *models.Server) []string {
+	problems := []string{}
+
+	if server.GetListener() == nil {
+		problems = append(problems, "listener is not bound")
+	} else if !server.IsAccepting() {
+		problems = append(problems, "server is not accepting connections")
+	}
+	if server.IsShuttingDown() {
+		problems = append(problems, "server is shutting down")
+	}
+	if server.IsMaintenance() {
+		problems = append(problems, "server is in maintenance")
+	}
+
+	return problems
+}
+
+//endregion
+
+//region HANDLERS
+
+// HandleHealth answers 200 when CheckHealth passes, otherwise 503 with the problems
+func HandleHealth(server *models.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeStatus(server, w, CheckHealth(server))
+	}
+}
+
+// HandleReady answers 200 when CheckReady passes, otherwise 503 with the problems
+func HandleReady(server *models.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeStatus(server, w, CheckReady(server))
+	}
+}
+
+func writeStatus(server *models.Server, w http.ResponseWriter, problems []string) {
+	view := statusView{Status: "ok", Problems: problems}
+	status := http.StatusOK
+	if len(problems) > 0 {
+		view.Status = "fail"
+		status = http.StatusServiceUnavailable
+	}
+
+	body, err := json.Marshal(view)
+	if err != nil {
+		errorHandeling.PrintError(server.Log, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
+
+//endregion
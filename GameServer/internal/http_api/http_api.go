@@ -0,0 +1,201 @@
+package http_api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gameserver/internal/models"
+	"gameserver/internal/utils/errorHandeling"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// region CONSTANTS
+const (
+	cReadHeaderTimeout = 5 * time.Second
+	// cMaxWait limits long-polling, ?wait= is in seconds
+	cMaxWait = 60 * time.Second
+)
+
+//endregion
+
+//region DATA STRUCTURES
+
+// viewBuilder builds JSON value of one endpoint, found is false for unknown game
+type viewBuilder func(server *models.Server, r *http.Request) (value interface{}, found bool)
+
+// changeNotifier wakes long-polling requests after every game event
+type changeNotifier struct {
+	changed chan struct{}
+	mutex   sync.Mutex
+}
+
+type errorView struct {
+	Error string `json:"error"`
+}
+
+//endregion
+
+//region SERVE
+
+// Serve serves read-only JSON API on the http listener, it returns after server.Close.
+// Handlers only read GameList and PlayerList, the game protocol is not touched.
+func Serve(server *models.Server) {
+	ln := server.GetHTTPListener()
+	if ln == nil {
+		return
+	}
+
+	notifier := &changeNotifier{changed: make(chan struct{})}
+	server.Events.SubscribeAll(func(event models.Event) error {
+		notifier.notify()
+		return nil
+	})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /games", handleView(server, notifier, buildGames))
+	mux.HandleFunc("GET /games/{name}", handleView(server, notifier, buildGame))
+	mux.HandleFunc("GET /players", handleView(server, notifier, buildPlayers))
+	mux.HandleFunc("GET /leaderboard", handleView(server, notifier, buildLeaderboard))
+
+	httpServer := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: cReadHeaderTimeout,
+		BaseContext: func(net.Listener) context.Context {
+			// long-polling requests end with the server
+			return server.Context()
+		},
+	}
+
+	fmt.Println("HTTP API is listening on " + ln.Addr().String())
+	err := httpServer.Serve(ln)
+	if err != nil && !errors.Is(err, net.ErrClosed) {
+		errorHandeling.PrintError(server.Log, err)
+		fmt.Println("Error serving http api:", err)
+	}
+}
+
+//endregion
+
+//region HANDLERS
+
+// handleView answers with JSON of the view and its ETag. Request with If-None-Match of the current ETag gets 304,
+// with ?wait=N it is held up to N seconds until the view changes (long-polling).
+func handleView(server *models.Server, notifier *changeNotifier, build viewBuilder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		wait, err := parseWait(r)
+		if err != nil {
+			writeJSON(server, w, http.StatusBadRequest, "", errorView{Error: err.Error()})
+			return
+		}
+		deadline := time.Now().Add(wait)
+
+		for {
+			// taken before building, so a change during building wakes the next wait
+			changed := notifier.wait()
+
+			value, found := build(server, r)
+			if !found {
+				writeJSON(server, w, http.StatusNotFound, "", errorView{Error: "not found"})
+				return
+			}
+
+			body, err := json.Marshal(value)
+			if err != nil {
+				errorHandeling.PrintError(server.Log, err)
+				writeJSON(server, w, http.StatusInternalServerError, "", errorView{Error: "internal server error"})
+				return
+			}
+			etag := createETag(body)
+
+			if r.Header.Get("If-None-Match") != etag {
+				writeBody(w, http.StatusOK, etag, body)
+				return
+			}
+
+			remaining := time.Until(deadline)
+			if remaining <= 0 {
+				w.Header().Set("ETag", etag)
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+
+			timer := time.NewTimer(remaining)
+			select {
+			case <-changed:
+				timer.Stop()
+			case <-timer.C:
+			case <-r.Context().Done():
+				timer.Stop()
+				return
+			}
+		}
+	}
+}
+
+func parseWait(r *http.Request) (time.Duration, error) {
+	value := r.URL.Query().Get("wait")
+	if value == "" {
+		return 0, nil
+	}
+
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 0 {
+		return 0, fmt.Errorf("wait has to be number of seconds")
+	}
+
+	return min(time.Duration(seconds)*time.Second, cMaxWait), nil
+}
+
+func createETag(body []byte) string {
+	hash := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(hash[:8]) + `"`
+}
+
+func writeJSON(server *models.Server, w http.ResponseWriter, status int, etag string, value interface{}) {
+	body, err := json.Marshal(value)
+	if err != nil {
+		errorHandeling.PrintError(server.Log, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	writeBody(w, status, etag, body)
+}
+
+func writeBody(w http.ResponseWriter, status int, etag string, body []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
+
+//endregion
+
+//region NOTIFIER
+
+func (n *changeNotifier) notify() {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	close(n.changed)
+	n.changed = make(chan struct{})
+}
+
+// wait returns channel closed by the next notify
+func (n *changeNotifier) wait() <-chan struct{} {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	return n.changed
+}
+
+//endregion
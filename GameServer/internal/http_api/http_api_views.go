@@ -0,0 +1,186 @@
+package http_api
+
+import (
+	"gameserver/internal/models"
+	"net/http"
+	"sort"
+)
+
+//region DATA STRUCTURES
+
+type gameView struct {
+	Name             string   `json:"name"`
+	State            string   `json:"state"`
+	MaxPlayers       int      `json:"max_players"`
+	ConnectedPlayers int      `json:"connected_players"`
+	Players          []string `json:"players"`
+}
+
+// gameDetailView adds GameData of running game, scores are empty before start
+type gameDetailView struct {
+	gameView
+	Round      int              `json:"round"`
+	TurnPlayer string           `json:"turn_player,omitempty"`
+	Scores     []playerGameView `json:"scores"`
+}
+
+type playerGameView struct {
+	Nickname    string `json:"nickname"`
+	IsConnected bool   `json:"is_connected"`
+	Score       int    `json:"score"`
+	IsTurn      bool   `json:"is_turn"`
+}
+
+type playerView struct {
+	Nickname    string `json:"nickname"`
+	State       string `json:"state"`
+	IsConnected bool   `json:"is_connected"`
+	Game        string `json:"game,omitempty"`
+}
+
+type leaderboardView struct {
+	Rank     int    `json:"rank"`
+	Nickname string `json:"nickname"`
+	Game     string `json:"game"`
+	Score    int    `json:"score"`
+}
+
+//endregion
+
+//region VIEWS
+
+func buildGames(server *models.Server, r *http.Request) (interface{}, bool) {
+	games := []gameView{}
+	for _, game := range server.GameList.GetValuesArray() {
+		games = append(games, createGameView(game))
+	}
+	sort.Slice(games, func(i, j int) bool { return games[i].Name < games[j].Name })
+	return games, true
+}
+
+func buildGame(server *models.Server, r *http.Request) (interface{}, bool) {
+	game, err := server.GameList.GetItemByName(r.PathValue("name"))
+	if err != nil || game == nil {
+		return nil, false
+	}
+
+	detail := gameDetailView{Scores: []playerGameView{}}
+	err = game.Execute(func() error {
+		detail.gameView = createGameView(game)
+		detail.Round = game.GetRoundNum()
+
+		scores, turnPlayer, err := getScores(game)
+		if err != nil {
+			return err
+		}
+		detail.Scores = scores
+		if turnPlayer != nil {
+			detail.TurnPlayer = turnPlayer.GetNickname()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false
+	}
+
+	return detail, true
+}
+
+func buildPlayers(server *models.Server, r *http.Request) (interface{}, bool) {
+	players := []playerView{}
+	for _, player := range server.PlayerList.GetValuesArray() {
+		view := playerView{
+			Nickname:    player.GetNickname(),
+			State:       player.GetCurrentStateName(),
+			IsConnected: player.IsConnected(),
+		}
+		if game := server.GameList.GetPlayersGame(player); game != nil {
+			view.Game = game.GetName()
+		}
+		players = append(players, view)
+	}
+	sort.Slice(players, func(i, j int) bool { return players[i].Nickname < players[j].Nickname })
+	return players, true
+}
+
+// buildLeaderboard ranks players of running games by their score
+func buildLeaderboard(server *models.Server, r *http.Request) (interface{}, bool) {
+	leaderboard := []leaderboardView{}
+	for _, game := range server.GameList.GetValuesArray() {
+		var scores []playerGameView
+		err := game.Execute(func() error {
+			var err error
+			scores, _, err = getScores(game)
+			return err
+		})
+		if err != nil {
+			continue
+		}
+
+		for _, score := range scores {
+			leaderboard = append(leaderboard, leaderboardView{
+				Nickname: score.Nickname,
+				Game:     game.GetName(),
+				Score:    score.Score,
+			})
+		}
+	}
+
+	sort.Slice(leaderboard, func(i, j int) bool {
+		if leaderboard[i].Score != leaderboard[j].Score {
+			return leaderboard[i].Score > leaderboard[j].Score
+		}
+		return leaderboard[i].Nickname < leaderboard[j].Nickname
+	})
+	for i := range leaderboard {
+		leaderboard[i].Rank = i + 1
+	}
+
+	return leaderboard, true
+}
+
+//endregion
+
+//region FUNCTIONS
+
+func createGameView(game *models.Game) gameView {
+	view := gameView{
+		Name:       game.GetName(),
+		State:      game.GetState().String(),
+		MaxPlayers: game.GetMaxPlayers(),
+		Players:    []string{},
+	}
+	for _, player := range game.GetPlayers() {
+		view.Players = append(view.Players, player.GetNickname())
+		if player.IsConnected() {
+			view.ConnectedPlayers++
+		}
+	}
+	return view
+}
+
+// getScores copies GameData of running game, it has to run on the game goroutine
+func getScores(game *models.Game) ([]playerGameView, *models.Player, error) {
+	scores := []playerGameView{}
+	if game.GetState() != models.Running {
+		return scores, nil, nil
+	}
+
+	gameData, err := game.GetGameData()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, playerGameData := range gameData.PlayerGameDataArr {
+		scores = append(scores, playerGameView{
+			Nickname:    playerGameData.Player.GetNickname(),
+			IsConnected: playerGameData.Player.IsConnected(),
+			Score:       playerGameData.Score,
+			IsTurn:      playerGameData.Player == gameData.TurnPlayer,
+		})
+	}
+
+	return scores, gameData.TurnPlayer, nil
+}
+
+//endregion
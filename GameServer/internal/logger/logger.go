@@ -1,24 +1,64 @@
 package logger
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"github.com/sirupsen/logrus"
 	"io"
 	"os"
 	"path/filepath"
 	"runtime"
+	"sync"
+	"sync/atomic"
 )
 
-var Log *logrus.Logger
+// region CONSTANTS
+
+// names of structured fields, so lines of one connection, player or game can be filtered
+const (
+	FieldSessionID     = "session_id"
+	FieldRemoteAddress = "remote_addr"
+	FieldNickname      = "nickname"
+	FieldGame          = "game"
+	FieldState         = "state"
+	FieldCorrelationID = "correlation_id"
+	FieldCommand       = "command"
+)
+
+//endregion
+
+// Log writes to stdout until InitLogger configures it
+var Log = logrus.New()
+
+// correlationPrefix differs between runs, correlationCounter numbers messages within a run
+var (
+	correlationPrefix  = createCorrelationPrefix()
+	correlationCounter atomic.Uint64
+)
+
+// logFile is nil when logging only to stdout
+var logFile *rotatingFile
+
+// sink remembers the last failed write, so health checks can report a broken log file
+var sink = &sinkWriter{}
+
+// sinkWriter writes log entries and keeps error of the last write, a successful write clears it
+type sinkWriter struct {
+	writer io.Writer
+	err    error
+	mutex  sync.Mutex
+}
 
 // LoggerConfig defines the configuration for the logger
 type LoggerConfig struct {
-	LogToFile       bool   // Enable logging to a file
-	FilePath        string // Path to the log file
-	UseJSONFormat   bool   // Use JSON format for logs
-	LogLevel        string // Log level (debug, info, warn, error, fatal, panic)
-	EnableCaller    bool   // Include file and line number in logs
-	TimestampFormat string // Custom timestamp format
+	LogToFile       bool           // Enable logging to a file
+	FolderPath      string         // Folder of log files, they are named log_<timestamp>.txt
+	Rotation        RotationConfig // Limits of log files
+	UseJSONFormat   bool           // Use JSON format for logs
+	LogLevel        string         // Log level (debug, info, warn, error, fatal, panic)
+	EnableCaller    bool           // Include file and line number in logs
+	TimestampFormat string         // Custom timestamp format
 }
 
 // InitLogger initializes the logger based on the provided configuration
@@ -59,23 +99,73 @@ func InitLogger(config LoggerConfig) error {
 	Log.SetReportCaller(config.EnableCaller)
 
 	// Set output
-	if config.LogToFile && config.FilePath != "" {
-		// Create the log file if it doesn't exist
-		err := os.MkdirAll(filepath.Dir(config.FilePath), 0755)
-		if err != nil {
-			return err
-		}
-		file, err := os.OpenFile(config.FilePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if config.LogToFile && config.FolderPath != "" {
+		file, err := openRotatingFile(config.FolderPath, cLogFilePrefix, cLogFileSuffix, config.Rotation)
 		if err != nil {
 			return err
 		}
 
+		logFile = file
+
 		// Multi-writer for logging to console and file
 		multiWriter := io.MultiWriter(os.Stdout, file)
-		Log.SetOutput(multiWriter)
+		sink.setWriter(multiWriter)
 	} else {
-		Log.SetOutput(os.Stdout)
+		sink.setWriter(os.Stdout)
 	}
+	Log.SetOutput(sink)
 
 	return nil
 }
+
+// Close flushes and closes the log file, logging continues to stdout only
+func Close() error {
+	if logFile == nil {
+		return nil
+	}
+
+	sink.setWriter(os.Stdout)
+
+	err := logFile.Close()
+	logFile = nil
+	return err
+}
+
+// GetSinkError returns error of the last write to the log, nil if it succeeded
+func GetSinkError() error {
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+
+	return sink.err
+}
+
+func (w *sinkWriter) setWriter(writer io.Writer) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.writer = writer
+	w.err = nil
+}
+
+func (w *sinkWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	n, err := w.writer.Write(p)
+	w.err = err
+	return n, err
+}
+
+// NewCorrelationID returns ID of received message, lines logged while processing it and its broadcasts carry it
+func NewCorrelationID() string {
+	return fmt.Sprintf("%s-%d", correlationPrefix, correlationCounter.Add(1))
+}
+
+func createCorrelationPrefix() string {
+	bytes := make([]byte, 4)
+	_, err := rand.Read(bytes)
+	if err != nil {
+		return "0"
+	}
+	return hex.EncodeToString(bytes)
+}
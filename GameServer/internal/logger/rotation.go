@@ -0,0 +1,285 @@
+package logger
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// region CONSTANTS
+const (
+	cLogFilePrefix   = "log_"
+	cLogFileSuffix   = ".txt"
+	cCompressSuffix  = ".gz"
+	cFileTimeFormat  = "2006-01-02_15-04-05.000"
+	cFilePermissions = 0644
+)
+
+//endregion
+
+//region DATA STRUCTURES
+
+// RotationConfig limits log files, zero value of a limit disables it
+type RotationConfig struct {
+	MaxSize  int64         // bytes of one file, it is rotated before it gets bigger
+	MaxAge   time.Duration // file is rotated when it has been open for this time
+	MaxFiles int           // rotated files beyond this count are removed, the oldest first
+	Compress bool          // rotated files are gzipped in background
+}
+
+// rotatingFile writes into <folder>/<prefix><timestamp><suffix> and starts a new file when a limit is reached
+type rotatingFile struct {
+	folder   string
+	prefix   string
+	suffix   string
+	config   RotationConfig
+	file     *os.File
+	size     int64
+	openedAt time.Time
+	// lastName is name of the newest file, names only grow, so a removed name is never used again
+	lastName string
+	mutex    sync.Mutex
+
+	// compressions are running gzips of rotated files
+	compressions sync.WaitGroup
+}
+
+//endregion
+
+//region FUNCTIONS
+
+// OpenRotatingFile opens file for writing in folder, files of the same prefix and suffix are rotated and removed
+// by config, e.g. log files or message journal
+func OpenRotatingFile(folder string, prefix string, suffix string, config RotationConfig) (io.WriteCloser, error) {
+	return openRotatingFile(folder, prefix, suffix, config)
+}
+
+func openRotatingFile(folder string, prefix string, suffix string, config RotationConfig) (*rotatingFile, error) {
+	err := os.MkdirAll(folder, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &rotatingFile{
+		folder: folder,
+		prefix: prefix,
+		suffix: suffix,
+		config: config,
+	}
+	err = f.open()
+	if err != nil {
+		return nil, err
+	}
+
+	f.removeOldFiles()
+	return f, nil
+}
+
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.file == nil {
+		return 0, fmt.Errorf("log file is closed")
+	}
+
+	if f.isRotationNeeded(len(p)) {
+		err := f.rotate()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Close syncs and closes the current file and waits for compressions of rotated files
+func (f *rotatingFile) Close() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+
+	err := f.file.Sync()
+	if err == nil {
+		err = f.file.Close()
+	} else {
+		_ = f.file.Close()
+	}
+	f.file = nil
+
+	f.compressions.Wait()
+	return err
+}
+
+//endregion
+
+//region ROTATION
+
+// isRotationNeeded returns true if writing n bytes would exceed size limit or the file is too old,
+// an empty file is never rotated, so one long entry does not rotate forever
+func (f *rotatingFile) isRotationNeeded(n int) bool {
+	if f.size == 0 {
+		return false
+	}
+	if f.config.MaxSize > 0 && f.size+int64(n) > f.config.MaxSize {
+		return true
+	}
+	if f.config.MaxAge > 0 && time.Since(f.openedAt) >= f.config.MaxAge {
+		return true
+	}
+	return false
+}
+
+func (f *rotatingFile) rotate() error {
+	rotatedPath := f.file.Name()
+	err := f.file.Close()
+	f.file = nil
+	if err != nil {
+		return fmt.Errorf("error closing log file: %w", err)
+	}
+
+	if f.config.Compress {
+		f.compressions.Add(1)
+		go func() {
+			defer f.compressions.Done()
+			// log file is the broken part, so the error only goes to stderr
+			err := compressFile(rotatedPath)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error compressing log file:", err)
+			}
+		}()
+	}
+
+	err = f.open()
+	if err != nil {
+		return err
+	}
+
+	f.removeOldFiles()
+	return nil
+}
+
+// open creates file named by the current time, a number is added if rotation happens twice in a millisecond
+func (f *rotatingFile) open() error {
+	now := time.Now()
+	baseName := f.prefix + now.Format(cFileTimeFormat)
+	name := baseName + f.suffix
+	for i := 1; name <= f.lastName || fileExists(filepath.Join(f.folder, name)) || fileExists(filepath.Join(f.folder, name+cCompressSuffix)); i++ {
+		name = fmt.Sprintf("%s_%d%s", baseName, i, f.suffix)
+	}
+	path := filepath.Join(f.folder, name)
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, cFilePermissions)
+	if err != nil {
+		return fmt.Errorf("error opening log file: %w", err)
+	}
+
+	f.file = file
+	f.size = 0
+	f.openedAt = now
+	f.lastName = name
+	return nil
+}
+
+// removeOldFiles keeps MaxFiles newest log files, the current one included
+func (f *rotatingFile) removeOldFiles() {
+	if f.config.MaxFiles <= 0 {
+		return
+	}
+
+	entries, err := os.ReadDir(f.folder)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error listing log files:", err)
+		return
+	}
+
+	currentName := filepath.Base(f.file.Name())
+	var names []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || name == currentName || !f.isRotatedFileName(name) {
+			continue
+		}
+		names = append(names, name)
+	}
+
+	// names start with the time, so they sort from the oldest
+	sort.Strings(names)
+	for len(names) > f.config.MaxFiles-1 {
+		err := os.Remove(filepath.Join(f.folder, names[0]))
+		if err != nil && !os.IsNotExist(err) {
+			fmt.Fprintln(os.Stderr, "Error removing old log file:", err)
+		}
+		names = names[1:]
+	}
+}
+
+//endregion
+
+//region UTILS
+
+func (f *rotatingFile) isRotatedFileName(name string) bool {
+	if !strings.HasPrefix(name, f.prefix) {
+		return false
+	}
+	return strings.HasSuffix(name, f.suffix) || strings.HasSuffix(name, f.suffix+cCompressSuffix)
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// compressFile gzips file into file.gz and removes the original
+func compressFile(path string) error {
+	source, err := os.Open(path)
+	if err != nil {
+		// already removed by retention
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer source.Close()
+
+	tmpPath := path + cCompressSuffix + ".tmp"
+	target, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, cFilePermissions)
+	if err != nil {
+		return err
+	}
+
+	writer := gzip.NewWriter(target)
+	_, err = io.Copy(writer, source)
+	if err == nil {
+		err = writer.Close()
+	}
+	closeErr := target.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	err = os.Rename(tmpPath, path+cCompressSuffix)
+	if err != nil {
+		return err
+	}
+
+	_ = source.Close()
+	return os.Remove(path)
+}
+
+//endregion
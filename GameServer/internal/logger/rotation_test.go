@@ -0,0 +1,204 @@
+package logger
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	cTestPrefix = "test_"
+	cTestSuffix = ".txt"
+)
+
+//region HELPERS
+
+func openTestFile(t *testing.T, folder string, config RotationConfig) *rotatingFile {
+	t.Helper()
+
+	f, err := openRotatingFile(folder, cTestPrefix, cTestSuffix, config)
+	if err != nil {
+		t.Fatalf("cannot open rotating file: %v", err)
+	}
+	t.Cleanup(func() { _ = f.Close() })
+	return f
+}
+
+func writeTestEntries(t *testing.T, f *rotatingFile, entries ...string) {
+	t.Helper()
+
+	for _, entry := range entries {
+		_, err := f.Write([]byte(entry))
+		if err != nil {
+			t.Fatalf("cannot write %q: %v", entry, err)
+		}
+	}
+}
+
+// listTestFiles returns names of files in folder from the oldest
+func listTestFiles(t *testing.T, folder string) []string {
+	t.Helper()
+
+	entries, err := os.ReadDir(folder)
+	if err != nil {
+		t.Fatalf("cannot list %s: %v", folder, err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func readTestFile(t *testing.T, path string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("cannot read %s: %v", path, err)
+	}
+	return string(data)
+}
+
+//endregion
+
+//region TESTS
+
+func TestRotationBySize(t *testing.T) {
+	folder := t.TempDir()
+	f := openTestFile(t, folder, RotationConfig{MaxSize: 10})
+
+	writeTestEntries(t, f, "first\n", "second\n", "third\n")
+	f.Close()
+
+	names := listTestFiles(t, folder)
+	if len(names) != 3 {
+		t.Fatalf("expected 3 files, got %v", names)
+	}
+	for i, expected := range []string{"first\n", "second\n", "third\n"} {
+		if content := readTestFile(t, filepath.Join(folder, names[i])); content != expected {
+			t.Fatalf("expected %q in %s, got %q", expected, names[i], content)
+		}
+	}
+}
+
+func TestEntryBiggerThanMaxSizeIsWrittenWhole(t *testing.T) {
+	folder := t.TempDir()
+	f := openTestFile(t, folder, RotationConfig{MaxSize: 4})
+
+	writeTestEntries(t, f, "long entry\n", "next\n")
+	f.Close()
+
+	names := listTestFiles(t, folder)
+	if len(names) != 2 {
+		t.Fatalf("expected 2 files, got %v", names)
+	}
+	if content := readTestFile(t, filepath.Join(folder, names[0])); content != "long entry\n" {
+		t.Fatalf("expected the long entry in the first file, got %q", content)
+	}
+}
+
+func TestRotationByAge(t *testing.T) {
+	folder := t.TempDir()
+	f := openTestFile(t, folder, RotationConfig{MaxAge: time.Hour})
+
+	writeTestEntries(t, f, "first\n", "second\n")
+	if names := listTestFiles(t, folder); len(names) != 1 {
+		t.Fatalf("expected 1 file before MaxAge, got %v", names)
+	}
+
+	// the file was opened before MaxAge
+	f.openedAt = time.Now().Add(-time.Hour)
+	writeTestEntries(t, f, "third\n")
+	f.Close()
+
+	names := listTestFiles(t, folder)
+	if len(names) != 2 {
+		t.Fatalf("expected 2 files after MaxAge, got %v", names)
+	}
+	if content := readTestFile(t, filepath.Join(folder, names[1])); content != "third\n" {
+		t.Fatalf("expected only the last entry in the new file, got %q", content)
+	}
+}
+
+func TestRotationRemovesOldestFiles(t *testing.T) {
+	folder := t.TempDir()
+	f := openTestFile(t, folder, RotationConfig{MaxSize: 5, MaxFiles: 2})
+
+	writeTestEntries(t, f, "a1\n", "a2\n", "a3\n", "a4\n")
+	f.Close()
+
+	names := listTestFiles(t, folder)
+	if len(names) != 2 {
+		t.Fatalf("expected 2 files, got %v", names)
+	}
+	if content := readTestFile(t, filepath.Join(folder, names[0])); content != "a3\n" {
+		t.Fatalf("expected the older kept file with a3, got %q", content)
+	}
+	if content := readTestFile(t, filepath.Join(folder, names[1])); content != "a4\n" {
+		t.Fatalf("expected the current file with a4, got %q", content)
+	}
+}
+
+func TestOpenRemovesOldFilesOfTheSamePrefixOnly(t *testing.T) {
+	folder := t.TempDir()
+	previous := []string{
+		cTestPrefix + "2020-01-01_00-00-00.000" + cTestSuffix,
+		cTestPrefix + "2020-01-02_00-00-00.000" + cTestSuffix + cCompressSuffix,
+		cTestPrefix + "2020-01-03_00-00-00.000" + cTestSuffix,
+		"other_2020-01-01_00-00-00.000" + cTestSuffix,
+	}
+	for _, name := range previous {
+		err := os.WriteFile(filepath.Join(folder, name), []byte("old\n"), cFilePermissions)
+		if err != nil {
+			t.Fatalf("cannot create %s: %v", name, err)
+		}
+	}
+
+	f := openTestFile(t, folder, RotationConfig{MaxFiles: 2})
+	currentName := filepath.Base(f.file.Name())
+	f.Close()
+
+	expected := []string{"other_2020-01-01_00-00-00.000" + cTestSuffix, previous[2], currentName}
+	sort.Strings(expected)
+	names := listTestFiles(t, folder)
+	if strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected %v, got %v", expected, names)
+	}
+}
+
+func TestRotatedFileIsCompressed(t *testing.T) {
+	folder := t.TempDir()
+	f := openTestFile(t, folder, RotationConfig{MaxSize: 8, Compress: true})
+
+	writeTestEntries(t, f, "rotated\n", "current\n")
+	// Close waits for the compression
+	f.Close()
+
+	names := listTestFiles(t, folder)
+	if len(names) != 2 || !strings.HasSuffix(names[0], cTestSuffix+cCompressSuffix) {
+		t.Fatalf("expected compressed rotated file and the current file, got %v", names)
+	}
+
+	file, err := os.Open(filepath.Join(folder, names[0]))
+	if err != nil {
+		t.Fatalf("cannot open %s: %v", names[0], err)
+	}
+	defer file.Close()
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("cannot read gzip %s: %v", names[0], err)
+	}
+	content, err := io.ReadAll(reader)
+	if err != nil || string(content) != "rotated\n" {
+		t.Fatalf("expected %q in compressed file, got %q, %v", "rotated\n", content, err)
+	}
+}
+
+//endregion
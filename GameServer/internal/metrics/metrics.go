@@ -0,0 +1,316 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//region DATA STRUCTURES
+
+// Registry holds metrics in order of registration and writes them in Prometheus text format
+type Registry struct {
+	metrics []metric
+	mutex   sync.Mutex
+}
+
+type metric interface {
+	writeText(w io.Writer) error
+}
+
+// Counter is monotonically increasing value, one series for every combination of label values
+type Counter struct {
+	name       string
+	help       string
+	labelNames []string
+	values     map[string]float64
+	labels     map[string][]string
+	mutex      sync.Mutex
+}
+
+// GaugeFunc is computed when metrics are scraped, collect returns value for every value of the label
+type GaugeFunc struct {
+	name      string
+	help      string
+	labelName string
+	collect   func() map[string]float64
+}
+
+// Histogram counts observations in cumulative buckets, one series for every combination of label values
+type Histogram struct {
+	name       string
+	help       string
+	labelNames []string
+	buckets    []float64
+	series     map[string]*histogramSeries
+	mutex      sync.Mutex
+}
+
+type histogramSeries struct {
+	labelValues  []string
+	bucketCounts []uint64
+	count        uint64
+	sum          float64
+}
+
+//endregion
+
+//region REGISTRY
+
+func CreateRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) register(m metric) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.metrics = append(r.metrics, m)
+}
+
+func (r *Registry) NewCounter(name string, help string, labelNames ...string) *Counter {
+	counter := &Counter{
+		name:       name,
+		help:       help,
+		labelNames: labelNames,
+		values:     make(map[string]float64),
+		labels:     make(map[string][]string),
+	}
+	r.register(counter)
+	return counter
+}
+
+func (r *Registry) NewGaugeFunc(name string, help string, labelName string, collect func() map[string]float64) *GaugeFunc {
+	gauge := &GaugeFunc{
+		name:      name,
+		help:      help,
+		labelName: labelName,
+		collect:   collect,
+	}
+	r.register(gauge)
+	return gauge
+}
+
+// NewHistogram creates histogram with upper bounds of buckets in ascending order, +Inf is added automatically
+func (r *Registry) NewHistogram(name string, help string, buckets []float64, labelNames ...string) *Histogram {
+	histogram := &Histogram{
+		name:       name,
+		help:       help,
+		labelNames: labelNames,
+		buckets:    buckets,
+		series:     make(map[string]*histogramSeries),
+	}
+	r.register(histogram)
+	return histogram
+}
+
+// WriteText writes all metrics in Prometheus text exposition format
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mutex.Lock()
+	metrics := append([]metric{}, r.metrics...)
+	r.mutex.Unlock()
+
+	for _, m := range metrics {
+		err := m.writeText(w)
+		if err != nil {
+			return fmt.Errorf("error writing metrics: %w", err)
+		}
+	}
+	return nil
+}
+
+// Handler serves metrics of the registry to Prometheus
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = r.WriteText(w)
+	})
+}
+
+//endregion
+
+//region COUNTER
+
+// Inc adds one to the series of label values, they have to match label names of the counter
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c *Counter) Add(value float64, labelValues ...string) {
+	key := strings.Join(labelValues, "\xff")
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, ok := c.labels[key]; !ok {
+		c.labels[key] = labelValues
+	}
+	c.values[key] += value
+}
+
+func (c *Counter) writeText(w io.Writer) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	err := writeHeader(w, c.name, c.help, "counter")
+	if err != nil {
+		return err
+	}
+
+	// counter without labels is reported as 0 before its first Inc
+	if len(c.labelNames) == 0 && len(c.values) == 0 {
+		_, err = fmt.Fprintf(w, "%s 0\n", c.name)
+		return err
+	}
+
+	for _, key := range sortedKeys(c.values) {
+		_, err = fmt.Fprintf(w, "%s%s %s\n", c.name, formatLabels(c.labelNames, c.labels[key]), formatValue(c.values[key]))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//endregion
+
+//region GAUGE
+
+func (g *GaugeFunc) writeText(w io.Writer) error {
+	err := writeHeader(w, g.name, g.help, "gauge")
+	if err != nil {
+		return err
+	}
+
+	values := g.collect()
+	for _, labelValue := range sortedKeys(values) {
+		_, err = fmt.Fprintf(w, "%s%s %s\n", g.name, formatLabels([]string{g.labelName}, []string{labelValue}), formatValue(values[labelValue]))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//endregion
+
+//region HISTOGRAM
+
+func (h *Histogram) Observe(value float64, labelValues ...string) {
+	key := strings.Join(labelValues, "\xff")
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	series, ok := h.series[key]
+	if !ok {
+		series = &histogramSeries{
+			labelValues:  labelValues,
+			bucketCounts: make([]uint64, len(h.buckets)),
+		}
+		h.series[key] = series
+	}
+
+	for i, bound := range h.buckets {
+		if value <= bound {
+			series.bucketCounts[i]++
+		}
+	}
+	series.count++
+	series.sum += value
+}
+
+func (h *Histogram) writeText(w io.Writer) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	err := writeHeader(w, h.name, h.help, "histogram")
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(h.series))
+	for key := range h.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	bucketLabelNames := append(append([]string{}, h.labelNames...), "le")
+	for _, key := range keys {
+		series := h.series[key]
+		for i, bound := range h.buckets {
+			labels := formatLabels(bucketLabelNames, append(append([]string{}, series.labelValues...), formatValue(bound)))
+			_, err = fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, labels, series.bucketCounts[i])
+			if err != nil {
+				return err
+			}
+		}
+
+		labels := formatLabels(bucketLabelNames, append(append([]string{}, series.labelValues...), "+Inf"))
+		_, err = fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, labels, series.count)
+		if err != nil {
+			return err
+		}
+
+		labels = formatLabels(h.labelNames, series.labelValues)
+		_, err = fmt.Fprintf(w, "%s_sum%s %s\n%s_count%s %d\n", h.name, labels, formatValue(series.sum), h.name, labels, series.count)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//endregion
+
+//region FORMAT
+
+func writeHeader(w io.Writer, name string, help string, metricType string) error {
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+	return err
+}
+
+func formatLabels(names []string, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+
+	pairs := make([]string, 0, len(names))
+	for i, name := range names {
+		value := ""
+		if i < len(values) {
+			value = values[i]
+		}
+		pairs = append(pairs, name+`="`+escapeLabelValue(value)+`"`)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func escapeLabelValue(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	return strings.ReplaceAll(value, "\n", `\n`)
+}
+
+func formatValue(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func sortedKeys(values map[string]float64) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+//endregion
@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"gameserver/internal/utils/constants"
+	"strconv"
+	"time"
+)
+
+// region CONSTANTS
+var (
+	// cLatencyBuckets are in seconds, from sub-millisecond handlers to slow clients
+	cLatencyBuckets = []float64{0.0005, 0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+	// cTurnBuckets are in seconds, a turn is limited by ping and ack timeouts of the player
+	cTurnBuckets = []float64{1, 2, 5, 10, 20, 30, 60, 120, 300}
+)
+
+//endregion
+
+//region DATA STRUCTURES
+
+// ServerMetrics are metrics recorded by one server, gauges of sessions and games are registered by the server
+// as they are computed from PlayerList and GameList
+type ServerMetrics struct {
+	Registry *Registry
+
+	MessagesReceived *Counter
+	MessagesSent     *Counter
+	ParseErrors      *Counter
+	AckTimeouts      *Counter
+	Disconnects      *Counter
+	TotalDisconnects *Counter
+
+	TurnDuration       *Histogram
+	SendDuration       *Histogram
+	ProcessingDuration *Histogram
+}
+
+//endregion
+
+//region FUNCTIONS
+
+func CreateServerMetrics() *ServerMetrics {
+	registry := CreateRegistry()
+
+	return &ServerMetrics{
+		Registry: registry,
+
+		MessagesReceived: registry.NewCounter("gameserver_messages_received_total", "Messages received from clients.", "command_id", "command"),
+		MessagesSent:     registry.NewCounter("gameserver_messages_sent_total", "Messages queued for clients, retransmits included.", "command_id", "command"),
+		ParseErrors:      registry.NewCounter("gameserver_parse_errors_total", "Received data which is not a valid message."),
+		AckTimeouts:      registry.NewCounter("gameserver_ack_timeouts_total", "Players disconnected because ResponseClientSuccess did not come in time."),
+		Disconnects:      registry.NewCounter("gameserver_disconnects_total", "Players which lost connection, they may still reconnect."),
+		TotalDisconnects: registry.NewCounter("gameserver_total_disconnects_total", "Players removed after the reconnect window or forced disconnect."),
+
+		TurnDuration:       registry.NewHistogram("gameserver_turn_duration_seconds", "Duration of player turns.", cTurnBuckets),
+		SendDuration:       registry.NewHistogram("gameserver_send_duration_seconds", "Time from queueing a message to writing it to the connection.", cLatencyBuckets),
+		ProcessingDuration: registry.NewHistogram("gameserver_processing_duration_seconds", "Time of processing client message, waiting for the game included.", cLatencyBuckets, "command_id", "command"),
+	}
+}
+
+// CommandLabels returns values of labels command_id and command
+func CommandLabels(commandID int) []string {
+	return []string{strconv.Itoa(commandID), constants.GetCommandName(commandID)}
+}
+
+// ObserveSince observes seconds elapsed since start
+func ObserveSince(histogram *Histogram, start time.Time, labelValues ...string) {
+	histogram.Observe(time.Since(start).Seconds(), labelValues...)
+}
+
+//endregion
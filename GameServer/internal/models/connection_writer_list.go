@@ -0,0 +1,84 @@
+package models
+
+import (
+	"net"
+	"sync"
+)
+
+//region DATA STRUCTURES
+
+// ConnectionWriter owns writes to one connection, it is implemented by network package
+type ConnectionWriter interface {
+	// Done is closed once the writer has closed its connection
+	Done() <-chan struct{}
+}
+
+// ConnectionWriterList maps connections of one server to their writers, so flushing a server waits only for its own writers
+type ConnectionWriterList struct {
+	writers map[net.Conn]ConnectionWriter
+	mutex   sync.Mutex
+}
+
+//endregion
+
+//region FUNCTIONS
+
+func CreateConnectionWriterList() *ConnectionWriterList {
+	return &ConnectionWriterList{
+		writers: make(map[net.Conn]ConnectionWriter),
+	}
+}
+
+// Get returns writer of the connection
+func (l *ConnectionWriterList) Get(connection net.Conn) (ConnectionWriter, bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	writer, ok := l.writers[connection]
+	return writer, ok
+}
+
+// GetOrAdd returns writer of the connection, writer returned by create is added when the connection has none.
+// create is called with the list locked.
+// Return: true if the writer has been added
+func (l *ConnectionWriterList) GetOrAdd(connection net.Conn, create func() ConnectionWriter) (ConnectionWriter, bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	writer, ok := l.writers[connection]
+	if ok {
+		return writer, false
+	}
+
+	writer = create()
+	l.writers[connection] = writer
+	return writer, true
+}
+
+// RemoveIf removes writer of the connection when isRemoved returns true, isRemoved is called with the list locked
+func (l *ConnectionWriterList) RemoveIf(connection net.Conn, isRemoved func(writer ConnectionWriter) bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	writer, ok := l.writers[connection]
+	if !ok {
+		return
+	}
+	if isRemoved(writer) {
+		delete(l.writers, connection)
+	}
+}
+
+// Values returns writers of all connections
+func (l *ConnectionWriterList) Values() []ConnectionWriter {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	writers := make([]ConnectionWriter, 0, len(l.writers))
+	for _, writer := range l.writers {
+		writers = append(writers, writer)
+	}
+	return writers
+}
+
+//endregion
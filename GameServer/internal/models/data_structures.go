@@ -2,7 +2,9 @@ package models
 
 import (
 	"fmt"
+	"gameserver/internal/logger"
 	"gameserver/internal/utils/constants"
+	"github.com/sirupsen/logrus"
 	"net"
 	"strings"
 	"time"
@@ -11,6 +13,7 @@ import (
 type ConnectionInfo struct {
 	Connection net.Conn
 	TimeStamp  string
+	SessionID  int // session of the connection handler, 0 for restored players
 }
 
 type MessageInfo struct {
@@ -31,6 +34,8 @@ type Message struct {
 	TimeStamp      string
 	PlayerNickname string
 	Parameters     []constants.Params
+	SequenceNumber int    // set only for server messages waiting for ResponseClientSuccess
+	CorrelationID  string // received message and messages sent because of it share it, it is not sent
 }
 
 //endregion
@@ -47,13 +52,31 @@ func CreateMessage(playerName string, commandID int, params []constants.Params)
 	}
 }
 
+// LogFields returns fields of the message for structured log
+func (m *Message) LogFields() logrus.Fields {
+	fields := logrus.Fields{
+		logger.FieldNickname: m.PlayerNickname,
+		logger.FieldCommand:  constants.GetCommandName(m.CommandID),
+	}
+	if m.CorrelationID != "" {
+		fields[logger.FieldCorrelationID] = m.CorrelationID
+	}
+	return fields
+}
+
 // is Message empty
 func (m *Message) IsEmpty() bool {
 	return m.Signature == "" && m.CommandID == 0 && m.TimeStamp == "" && m.PlayerNickname == "" && len(m.Parameters) == 0
 }
 
-// func to check if is valid message name
-func IsValidName(name string) bool {
+// NameLength limits names of players and games, names are checked also by the parser
+type NameLength struct {
+	MinChars int
+	MaxChars int
+}
+
+// IsValidName checks if name is alphanumeric and within the limits
+func (l NameLength) IsValidName(name string) bool {
 	// if name is none
 	if name == "" {
 		return false
@@ -64,7 +87,7 @@ func IsValidName(name string) bool {
 		return false
 	}
 
-	return len(name) >= constants.CMessageNameMinChars && len(name) <= constants.CMessageNameMaxChars
+	return len(name) >= l.MinChars && len(name) <= l.MaxChars
 }
 
 func CreateParams(names []string, values []string) ([]constants.Params, error) {
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"errors"
+	"gameserver/internal/utils/constants"
+)
+
+// sentinel errors of models, command processing maps them to error codes sent to clients
+var (
+	ErrGameFull          = errors.New("game is full")
+	ErrGameStarted       = errors.New("game has already started or ended")
+	ErrGameNotStarted    = errors.New("game has not started")
+	ErrGameNotFound      = errors.New("game not found")
+	ErrGameStopped       = errors.New("game has been removed")
+	ErrGameNameTaken     = errors.New("game name already exists")
+	ErrNicknameTaken     = errors.New("nickname already exists")
+	ErrPlayerNotFound    = errors.New("player not found")
+	ErrPlayerInGame      = errors.New("player is already in a game")
+	ErrNotEnoughPlayers  = errors.New("not enough players to start the game")
+	ErrNotYourTurn       = errors.New("not your turn")
+	ErrInvalidCubeValues = errors.New("invalid cube value")
+)
+
+var errorCodes = []struct {
+	err  error
+	code constants.ErrorCode
+}{
+	{ErrGameFull, constants.ErrorCodeGameFull},
+	{ErrGameStarted, constants.ErrorCodeGameStarted},
+	{ErrGameNotStarted, constants.ErrorCodeInvalidState},
+	{ErrGameNotFound, constants.ErrorCodeGameNotFound},
+	{ErrGameStopped, constants.ErrorCodeGameNotFound},
+	{ErrGameNameTaken, constants.ErrorCodeGameNameTaken},
+	{ErrNicknameTaken, constants.ErrorCodeNicknameTaken},
+	{ErrPlayerNotFound, constants.ErrorCodeNotInGame},
+	{ErrPlayerInGame, constants.ErrorCodeAlreadyInGame},
+	{ErrNotEnoughPlayers, constants.ErrorCodeInvalidState},
+	{ErrNotYourTurn, constants.ErrorCodeNotYourTurn},
+	{ErrInvalidCubeValues, constants.ErrorCodeInvalidCubes},
+}
+
+// GetErrorCode returns error code of sentinel error wrapped in err, ErrorCodeInternal for other errors
+func GetErrorCode(err error) constants.ErrorCode {
+	for _, errorCode := range errorCodes {
+		if errors.Is(err, errorCode.err) {
+			return errorCode.code
+		}
+	}
+	return constants.ErrorCodeInternal
+}
@@ -0,0 +1,141 @@
+package models
+
+import (
+	"errors"
+	"sync"
+)
+
+//region DATA STRUCTURES
+
+// Event is domain event published by command handlers, EventName identifies its type on the bus
+type Event interface {
+	EventName() string
+}
+
+// EventHandler is subscriber of the bus, it runs on the publishing goroutine (often the game goroutine),
+// so it must not call Execute of the event's game
+type EventHandler func(event Event) error
+
+// EventBus delivers events to subscribers in order of subscription
+type EventBus struct {
+	handlers    map[string][]EventHandler
+	allHandlers []EventHandler
+	mutex       sync.RWMutex
+}
+
+// PlayerJoined player has created or joined a game which has not started yet
+type PlayerJoined struct {
+	Player *Player
+	Game   *Game
+}
+
+// GameStarted player has started the game
+type GameStarted struct {
+	Player *Player
+	Game   *Game
+}
+
+// TurnStarted turn player has been sent ServerStartTurn
+type TurnStarted struct {
+	Player *Player
+	Game   *Game
+}
+
+// DiceRolled player has rolled, CanBePlayed is false when the roll ended the turn
+type DiceRolled struct {
+	Player      *Player
+	Game        *Game
+	CubeValues  []int
+	CanBePlayed bool
+}
+
+// ScoreChanged player has selected cubes and continues the turn, the final score is in GameEnded
+type ScoreChanged struct {
+	Player *Player
+	Game   *Game
+	Score  int
+}
+
+// GameEnded winner has reached the max score, the game is already removed from GameList
+type GameEnded struct {
+	Winner *Player
+	Game   *Game
+	Score  int
+}
+
+// GameAborted game has been ended without a winner, e.g. by admin, the game is already removed from GameList
+type GameAborted struct {
+	Game *Game
+}
+
+// PlayerDisconnected player has lost connection, IsTotal is true when the reconnect window is over.
+// Game is nil if the player was not in a game.
+type PlayerDisconnected struct {
+	Player  *Player
+	Game    *Game
+	IsTotal bool
+}
+
+func (PlayerJoined) EventName() string       { return "PlayerJoined" }
+func (GameStarted) EventName() string        { return "GameStarted" }
+func (TurnStarted) EventName() string        { return "TurnStarted" }
+func (DiceRolled) EventName() string         { return "DiceRolled" }
+func (ScoreChanged) EventName() string       { return "ScoreChanged" }
+func (GameEnded) EventName() string          { return "GameEnded" }
+func (GameAborted) EventName() string        { return "GameAborted" }
+func (PlayerDisconnected) EventName() string { return "PlayerDisconnected" }
+
+//endregion
+
+//region FUNCTIONS
+
+func CreateEventBus() *EventBus {
+	return &EventBus{
+		handlers: make(map[string][]EventHandler),
+	}
+}
+
+// Subscribe registers typed handler of one event type, e.g. Subscribe(bus, func(event GameEnded) error {...})
+func Subscribe[E Event](bus *EventBus, handler func(event E) error) {
+	var empty E
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+
+	bus.handlers[empty.EventName()] = append(bus.handlers[empty.EventName()], func(event Event) error {
+		return handler(event.(E))
+	})
+}
+
+//endregion
+
+//region BUS
+
+// SubscribeAll registers handler of every event, e.g. for logging
+func (b *EventBus) SubscribeAll(handler EventHandler) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.allHandlers = append(b.allHandlers, handler)
+}
+
+// Publish calls all subscribers of the event, a failing subscriber does not stop the others
+// Return: errors of all failed subscribers
+func (b *EventBus) Publish(event Event) error {
+	b.mutex.RLock()
+	handlers := make([]EventHandler, 0, len(b.allHandlers)+len(b.handlers[event.EventName()]))
+	handlers = append(handlers, b.allHandlers...)
+	handlers = append(handlers, b.handlers[event.EventName()]...)
+	b.mutex.RUnlock()
+
+	var errs []error
+	for _, handler := range handlers {
+		err := handler(event)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+//endregion
@@ -1,10 +1,14 @@
 package models
 
 import (
+	"errors"
 	"fmt"
 	"gameserver/internal/utils/constants"
 	"gameserver/internal/utils/errorHandeling"
+	"github.com/sirupsen/logrus"
+	"runtime/debug"
 	"sync"
+	"time"
 )
 
 // region CONSTANTS
@@ -29,6 +33,18 @@ const (
 	Ended
 )
 
+func (s GameState) String() string {
+	switch s {
+	case Running:
+		return "running"
+	case Created:
+		return "created"
+	case Ended:
+		return "ended"
+	}
+	return "unknown"
+}
+
 type Throw struct {
 	cubeValues          []int
 	selectedCubesValues []int
@@ -38,6 +54,16 @@ type Turn struct {
 	ThrowArr []Throw
 }
 
+// handlerPanic carries panic of a game handler to the goroutine which called Execute
+type handlerPanic struct {
+	value interface{}
+	stack []byte
+}
+
+func (p handlerPanic) Error() string {
+	return fmt.Sprintf("panic in game handler: %v\n%s", p.value, p.stack)
+}
+
 // Player Data Structure represents plyaer data for game
 type PlayerGameData struct {
 	Player      *Player
@@ -45,15 +71,38 @@ type PlayerGameData struct {
 	TurnHistory []Turn
 }
 
+// GameRules are set when the game is created, reloaded config changes rules only of new games
+type GameRules struct {
+	MaxScore int  // score which ends the game
+	Debug    bool // panic of handler stays fatal, it is not saved in snapshot
+}
+
 // Game represents a game with a unique ID, a list of g_players, and turn data.
 type Game struct {
 	gameID             int
 	name               string
 	playersGameDataArr []PlayerGameData
 	maxPlayers         int
+	rules              GameRules
 	turnCount          int
 	gameStateValue     GameState
 	mutex              sync.Mutex
+	inbox              chan gameCommand
+	stopped            chan struct{}
+	stopOnce           sync.Once
+	// handlerStartedAt is zero while the game goroutine waits for a handler
+	handlerStartedAt time.Time
+	// correlationID is of the message whose handler runs, empty for handlers of timers
+	correlationID string
+	// log is logger of the server
+	log *logrus.Logger
+}
+
+// gameCommand is handler waiting in the game inbox
+type gameCommand struct {
+	handler       func() error
+	result        chan error
+	correlationID string
 }
 
 type GameData struct {
@@ -65,22 +114,130 @@ type GameData struct {
 
 //region FUNCTIONS
 
-// CreateGame creates a new game with a unique ID and initializes Player and turn slices.
-func CreateGame(name string, maxPlayers int) (*Game, error) {
+// CreateGame creates a new game with a unique ID and initializes Player and turn slices,
+// the game keeps rules even if settings of the server are reloaded, log is logger of the server
+func CreateGame(name string, maxPlayers int, rules GameRules, log *logrus.Logger) (*Game, error) {
 	//Check if the arguments are valid
 	if name == "" || maxPlayers <= 1 {
 		return nil, fmt.Errorf("invalid arguments")
 	}
 
-	return &Game{
+	game := &Game{
 		name:               name,
 		playersGameDataArr: make([]PlayerGameData, 0),
 		maxPlayers:         maxPlayers,
+		rules:              rules,
 		turnCount:          0,
 		gameStateValue:     Created,
-	}, nil
+		inbox:              make(chan gameCommand),
+		stopped:            make(chan struct{}),
+		log:                log,
+	}
+	go game.run()
+
+	return game, nil
+}
+
+//region ACTOR
+
+// run is the game goroutine, it executes handlers from the inbox one by one
+func (g *Game) run() {
+	for {
+		select {
+		case command := <-g.inbox:
+			g.startHandler(time.Now(), command.correlationID)
+			err := runHandler(command.handler, g.rules.Debug)
+			g.startHandler(time.Time{}, "")
+			command.result <- err
+		case <-g.stopped:
+			return
+		}
+	}
 }
 
+// Execute runs handler on the game goroutine and waits for its result.
+// All turn, score and membership changes of the game and their broadcasts go through it, so they never interleave.
+// Handler must not call Execute of the same game.
+func (g *Game) Execute(handler func() error) error {
+	return g.ExecuteCorrelated("", handler)
+}
+
+// ExecuteCorrelated runs handler like Execute, messages sent by the handler carry correlationID of the received message.
+// Handler does not run on stopped game, ErrGameStopped is returned instead.
+func (g *Game) ExecuteCorrelated(correlationID string, handler func() error) error {
+	command := gameCommand{
+		handler:       handler,
+		result:        make(chan error, 1),
+		correlationID: correlationID,
+	}
+
+	select {
+	case g.inbox <- command:
+		err := <-command.result
+		// panic of the handler continues on the caller, so only the caller's connection is dropped
+		var panicErr handlerPanic
+		if errors.As(err, &panicErr) {
+			panic(panicErr)
+		}
+		return err
+	case <-g.stopped:
+		// game has been removed, running handler here would change it next to other callers
+		return ErrGameStopped
+	}
+}
+
+// runHandler runs handler and turns its panic into handlerPanic, in debug mode the panic stays fatal
+func runHandler(handler func() error, isDebug bool) (err error) {
+	if !isDebug {
+		defer func() {
+			recovered := recover()
+			if recovered != nil {
+				err = handlerPanic{value: recovered, stack: debug.Stack()}
+			}
+		}()
+	}
+
+	return handler()
+}
+
+// startHandler is called before and after handler with zero startedAt
+func (g *Game) startHandler(startedAt time.Time, correlationID string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	g.handlerStartedAt = startedAt
+	g.correlationID = correlationID
+}
+
+// GetCorrelationID returns correlation ID of the running handler, empty if it has none
+func (g *Game) GetCorrelationID() string {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	return g.correlationID
+}
+
+// GetHandlerDuration returns how long the current handler runs on the game goroutine, 0 if it waits for one.
+// Handlers only change the game and queue messages, so a long one means the game goroutine is stuck.
+func (g *Game) GetHandlerDuration() time.Duration {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.handlerStartedAt.IsZero() {
+		return 0
+	}
+	return time.Since(g.handlerStartedAt)
+}
+
+// Stop ends the game goroutine after the currently running handler
+func (g *Game) Stop() {
+	g.stopOnce.Do(func() {
+		close(g.stopped)
+	})
+}
+
+//endregion
+
 var switcher = true
 
 func generateCubeValues(count int) []int {
@@ -113,13 +270,13 @@ func generateCubeValues(count int) []int {
 	return array
 }
 
-// get turn number
-//func (g *Game) GetTurnNum() int {
-//	g.mutex.Lock()
-//	defer g.mutex.Unlock()
-//
-//	return g.turnCount
-//}
+// GetTurnNum returns number of turns played since start of the game
+func (g *Game) GetTurnNum() int {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	return g.turnCount
+}
 
 // public GetRoundNum
 func (g *Game) GetRoundNum() int {
@@ -173,7 +330,7 @@ func (g *Game) AddPlayer(pPlayer *Player) error {
 	defer g.mutex.Unlock()
 
 	if len(g.playersGameDataArr) >= g.maxPlayers {
-		return fmt.Errorf("game is full")
+		return ErrGameFull
 	}
 
 	playerGameData := PlayerGameData{
@@ -240,7 +397,7 @@ func (g *Game) RemovePlayer(player *Player) error {
 	}
 
 	if !isRemoved {
-		return fmt.Errorf("player not found")
+		return ErrPlayerNotFound
 	}
 
 	return nil
@@ -258,6 +415,11 @@ func (g *Game) GetName() string {
 	return g.name
 }
 
+// GetRules returns rules the game was created with, they never change
+func (g *Game) GetRules() GameRules {
+	return g.rules
+}
+
 // Get max players
 func (g *Game) GetMaxPlayers() int {
 	g.mutex.Lock()
@@ -340,7 +502,7 @@ func (g *Game) GetGameData() (GameData, error) {
 	gameData.PlayerGameDataArr = g.playersGameDataArr
 	turnPlayer, err := g.getTurnPlayer()
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(g.log, err)
 		return GameData{}, err
 	}
 
@@ -352,7 +514,7 @@ func (g *Game) GetGameData() (GameData, error) {
 func (g *Game) getScoreIncrease(cubeValuesList []int, player *Player) (int, error) {
 	playerLastThrowCubeValues, err := g.getLastThrowCubeValues(player)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(g.log, err)
 		return 0, fmt.Errorf("failed to get last throw")
 	}
 
@@ -366,7 +528,7 @@ func (g *Game) getScoreIncrease(cubeValuesList []int, player *Player) (int, erro
 			}
 		}
 		if !isInList {
-			return 0, fmt.Errorf("invalid cube value")
+			return 0, ErrInvalidCubeValues
 		}
 	}
 
@@ -382,7 +544,7 @@ func (g *Game) getScoreIncrease(cubeValuesList []int, player *Player) (int, erro
 		}
 
 		if !isScoreValue {
-			return 0, fmt.Errorf("invalid cube value")
+			return 0, ErrInvalidCubeValues
 		}
 	}
 
@@ -392,8 +554,8 @@ func (g *Game) getScoreIncrease(cubeValuesList []int, player *Player) (int, erro
 func (g *Game) getPlayerScore(player *Player) (int, error) {
 	playerGameData, err := g.getPlayerGameData(player)
 	if err != nil {
-		errorHandeling.PrintError(err)
-		return 0, fmt.Errorf("player not found")
+		errorHandeling.PrintError(g.log, err)
+		return 0, ErrPlayerNotFound
 	}
 
 	return playerGameData.Score, nil
@@ -418,7 +580,7 @@ func (g *Game) getPlayerGameData(player *Player) (*PlayerGameData, error) {
 		}
 	}
 
-	return &PlayerGameData{}, fmt.Errorf("player not found")
+	return &PlayerGameData{}, ErrPlayerNotFound
 }
 
 // GetLastThrow returns the last throw of the player
@@ -426,8 +588,8 @@ func (g *Game) getLastThrowCubeValues(player *Player) ([]int, error) {
 
 	playerGameData, err := g.getPlayerGameData(player)
 	if err != nil {
-		errorHandeling.PrintError(err)
-		return nil, fmt.Errorf("player not found")
+		errorHandeling.PrintError(g.log, err)
+		return nil, ErrPlayerNotFound
 	}
 
 	turnHistory := playerGameData.TurnHistory
@@ -461,11 +623,11 @@ func (g *Game) StartGame() error {
 	defer g.mutex.Unlock()
 
 	if g.gameStateValue != Created {
-		return fmt.Errorf("game has already started or ended")
+		return ErrGameStarted
 	}
 
 	if !g.isEnoughPlayers() {
-		return fmt.Errorf("not enough players to start the game")
+		return ErrNotEnoughPlayers
 	}
 
 	g.gameStateValue = Running
@@ -485,17 +647,17 @@ func (g *Game) NewThrow(player *Player) ([]int, error) {
 
 	turnPlayer, err := g.getTurnPlayer()
 	if err != nil {
-		errorHandeling.PrintError(err)
-		return nil, fmt.Errorf("not your turn")
+		errorHandeling.PrintError(g.log, err)
+		return nil, ErrNotYourTurn
 	}
 	if player != turnPlayer {
-		return nil, fmt.Errorf("not your turn")
+		return nil, ErrNotYourTurn
 	}
 
 	turnPlayerGameData, err := g.getPlayerGameData(turnPlayer)
 	if err != nil {
-		errorHandeling.PrintError(err)
-		return nil, fmt.Errorf("player not found")
+		errorHandeling.PrintError(g.log, err)
+		return nil, ErrPlayerNotFound
 	}
 
 	var cubeCount int
@@ -510,7 +672,7 @@ func (g *Game) NewThrow(player *Player) ([]int, error) {
 
 	cubeValues, err := g.addThrow(turnPlayer, cubeCount)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(g.log, err)
 		return nil, fmt.Errorf("failed to add throw")
 	}
 
@@ -521,8 +683,8 @@ func (g *Game) addThrow(player *Player, count int) ([]int, error) {
 
 	turnPlayerGameData, err := g.getPlayerGameData(player)
 	if err != nil {
-		errorHandeling.PrintError(err)
-		return nil, fmt.Errorf("player not found")
+		errorHandeling.PrintError(g.log, err)
+		return nil, ErrPlayerNotFound
 	}
 
 	var throw Throw
@@ -562,11 +724,11 @@ func (g *Game) addThrow(player *Player, count int) ([]int, error) {
 func (g *Game) getNewScore(player *Player, cubeValuesList []int) (int, error) {
 	currentScore, err := g.getPlayerScore(player)
 	if err != nil {
-		return 0, fmt.Errorf("player not found")
+		return 0, ErrPlayerNotFound
 	}
 	increaseScore, err := g.getScoreIncrease(cubeValuesList, player)
 	if err != nil {
-		return 0, fmt.Errorf("invalid cube value")
+		return 0, ErrInvalidCubeValues
 	}
 	score := currentScore + increaseScore
 
@@ -588,7 +750,7 @@ func (g *Game) SetPlayerScore(player *Player, selectedCubeValues []int) error {
 	//set score
 	score, err := g.getNewScore(player, selectedCubeValues)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(g.log, err)
 		return fmt.Errorf("failed to set score")
 	}
 
@@ -600,7 +762,7 @@ func (g *Game) SetPlayerScore(player *Player, selectedCubeValues []int) error {
 		}
 	}
 	if !isSet {
-		return fmt.Errorf("player not found")
+		return ErrPlayerNotFound
 	}
 	if score == 0 {
 		return nil
@@ -616,7 +778,7 @@ func (g *Game) SetPlayerScore(player *Player, selectedCubeValues []int) error {
 		}
 	}
 
-	return fmt.Errorf("player not found")
+	return ErrPlayerNotFound
 }
 
 func (g *Game) IsEnoughPlayersToContinueGame() bool {
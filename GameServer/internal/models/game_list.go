@@ -1,111 +1,113 @@
 package models
 
 import (
+	"errors"
 	"fmt"
 	"gameserver/internal/utils/errorHandeling"
+	"github.com/sirupsen/logrus"
 	"sync"
 )
 
 // region DATA STRUCTURES
 type GameList struct {
-	list *List
+	registry    *Registry[string, *Game]
+	playersGame *Index[*Player, string, *Game]
+	lastGameID  int
+	mutex       sync.Mutex
+	// log is logger of the server
+	log *logrus.Logger
 }
 
-var (
-	instanceGL *GameList
-	onceGL     sync.Once
-)
-
 //endregion
 
 //region FUNCTIONS
 
-func GetInstanceGameList() *GameList {
-	onceGL.Do(func() {
-		instanceGL = &GameList{
-			list: CreateList(),
-		}
-	})
-	return instanceGL
+func CreateGameList(log *logrus.Logger) *GameList {
+	registry := CreateRegistry[string, *Game]()
+
+	return &GameList{
+		registry: registry,
+		log:      log,
+		playersGame: AddIndex(registry, func(game *Game) []*Player {
+			return game.GetPlayers()
+		}),
+	}
 }
 
 // player is in game
 func (gl *GameList) PlayerIsInGame(player *Player) bool {
-	gl.list.mutex.Lock()
-	defer gl.list.mutex.Unlock()
-
-	for _, v := range gl.list.data {
-		game, ok := v.(*Game)
-		if !ok {
-			panic("item is not a game")
-			return false
-		}
-		for _, p := range game.playersGameDataArr {
-			if p.Player == player {
-				return true
-			}
-		}
-	}
-	return false
+	return gl.GetPlayersGame(player) != nil
 }
 
+// AddItem adds game to the list, players already added to the game are indexed too
 func (gl *GameList) AddItem(game *Game) (int, error) {
-	gl.list.mutex.Lock()
-	defer gl.list.mutex.Unlock()
+	gl.mutex.Lock()
+	defer gl.mutex.Unlock()
 
-	key, err := gl.list.AddItem(game)
+	err := gl.registry.Add(game.GetName(), game)
 	if err != nil {
-		errorHandeling.PrintError(err)
-		return -1, err
+		errorHandeling.PrintError(gl.log, err)
+		return -1, fmt.Errorf("%w: %w", ErrGameNameTaken, err)
 	}
-	gameItem := gl.list.GetItemWithoutLock(key)
-	if gameItem == nil {
-		return -1, fmt.Errorf("game not found")
-	}
-	game = gameItem.(*Game)
-	game.gameID = key
 
-	return key, nil
+	gl.lastGameID++
+	game.gameID = gl.lastGameID
+
+	return game.gameID, nil
 }
 
-// has item
-func (gl *GameList) HasItemName(gameName string) bool {
-	gl.list.mutex.Lock()
-	defer gl.list.mutex.Unlock()
+// AddPlayerToGame adds player to game in the list and indexes it
+func (gl *GameList) AddPlayerToGame(game *Game, player *Player) error {
+	if player == nil {
+		return fmt.Errorf("player is nil")
+	}
 
-	game, err := gl.getItemByName(gameName)
-	return err == nil && game != nil
+	err := game.AddPlayer(player)
+	if err != nil {
+		return err
+	}
+
+	// game might not be in the list yet
+	_ = gl.registry.Reindex(game.GetName())
+
+	return nil
 }
 
-func (gl *GameList) getItemByName(gameName string) (*Game, error) {
-	for _, v := range gl.list.data {
-		game, ok := v.(*Game)
-		if !ok {
-			return nil, fmt.Errorf("item is not a game")
-		}
-		if game.name == gameName {
-			return game, nil
-		}
-	}
-	return nil, fmt.Errorf("game not found")
+// has item
+func (gl *GameList) HasItemName(gameName string) bool {
+	return gl.registry.Has(gameName)
 }
 
 // remove item
 func (gl *GameList) RemoveItem(game *Game) error {
-	gl.list.mutex.Lock()
-	defer gl.list.mutex.Unlock()
-
-	gameKey := game.gameID
-
-	err := gl.list.RemoveItem(gameKey)
+	_, err := gl.registry.Remove(game.GetName())
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(gl.log, err)
 		return err
 	}
 
+	game.Stop()
+
 	return nil
 }
 
+// ExecuteInPlayersGame runs handler on the goroutine of the player's game, or directly if player is not in a game
+func (gl *GameList) ExecuteInPlayersGame(player *Player, handler func() error) error {
+	for {
+		game := gl.GetPlayersGame(player)
+		if game == nil {
+			return handler()
+		}
+
+		err := game.ExecuteCorrelated(player.GetCorrelationID(), handler)
+		// game was removed before handler ran, player is in another game or in none now
+		if errors.Is(err, ErrGameStopped) && gl.GetPlayersGame(player) != game {
+			continue
+		}
+		return err
+	}
+}
+
 //func (gl *GameList) GetItem(key int) (*Game, error) {
 //	gl.list.mutex.Lock()
 //	defer gl.list.mutex.Unlock()
@@ -123,16 +125,13 @@ func (gl *GameList) RemoveItem(game *Game) error {
 
 // Remove Player from Game
 func (gl *GameList) RemovePlayerFromGame(player *Player) error {
-	gl.list.mutex.Lock()
-	defer gl.list.mutex.Unlock()
-
 	if player == nil {
 		err := fmt.Errorf("player is nil")
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(gl.log, err)
 		return err
 	}
 
-	game := gl.getPlayersGame(player)
+	game := gl.GetPlayersGame(player)
 	if game == nil {
 		return nil
 	}
@@ -140,64 +139,43 @@ func (gl *GameList) RemovePlayerFromGame(player *Player) error {
 	err := game.RemovePlayer(player)
 	if err != nil {
 		err = fmt.Errorf("cannot remove player %w", err)
-		errorHandeling.PrintError(err)
-		panic(err)
+		errorHandeling.PrintError(gl.log, err)
+		return err
 	}
+	_ = gl.registry.Reindex(game.GetName())
 
 	return nil
 }
 
 // get item by game name
 func (gl *GameList) GetItemByName(name string) (*Game, error) {
-	gl.list.mutex.Lock()
-	defer gl.list.mutex.Unlock()
-
-	for _, v := range gl.list.data {
-		game, ok := v.(*Game)
-		if !ok {
-			return nil, fmt.Errorf("item is not a game")
-		}
-		if game.name == name {
-			return game, nil
-		}
+	game, ok := gl.registry.Get(name)
+	if !ok {
+		return nil, ErrGameNotFound
 	}
-	return nil, fmt.Errorf("game not found")
+	return game, nil
 }
 
 // Has Item in list
 func (gl *GameList) HasValue(game *Game) bool {
-	gl.list.mutex.Lock()
-	defer gl.list.mutex.Unlock()
+	if game == nil {
+		return false
+	}
 
-	return gl.list.HasValue(game)
+	item, ok := gl.registry.Get(game.GetName())
+	return ok && item == game
 }
 
-// GetValuesArray
+// GetValuesArray returns games in order of creation
 func (gl *GameList) GetValuesArray() []*Game {
-	gl.list.mutex.Lock()
-	defer gl.list.mutex.Unlock()
-
-	var values []*Game
-	for _, v := range gl.list.data {
-		values = append(values, v.(*Game))
-	}
-
-	return values
+	return gl.registry.Values()
 }
 
-// GetCreatedGameList
+// GetCreatedGameList returns games waiting for players in order of creation, so clients get stable list
 func (gl *GameList) GetCreatedGameList() []*Game {
-	gl.list.mutex.Lock()
-	defer gl.list.mutex.Unlock()
-
 	var values []*Game
-	for _, v := range gl.list.data {
-		game, ok := v.(*Game)
-		if !ok {
-			panic("item is not a game")
-			return nil
-		}
-		if game.gameStateValue == Created {
+	for _, game := range gl.registry.Values() {
+		if game.GetState() == Created {
 			values = append(values, game)
 		}
 	}
@@ -206,28 +184,12 @@ func (gl *GameList) GetCreatedGameList() []*Game {
 }
 
 // GetPlayersGame returns the game of the player
-func (gl *GameList) getPlayersGame(player *Player) *Game {
-	for _, v := range gl.list.data {
-		game, ok := v.(*Game)
-		if !ok {
-			panic("item is not a game")
-			return nil
-		}
-		for _, p := range game.playersGameDataArr {
-			if p.Player == player {
-				return game
-			}
-		}
-	}
-	return nil
-}
-
 func (gl *GameList) GetPlayersGame(player *Player) *Game {
-	//	logger.Log.Debugln("GetPlayersGame before lock")
-	gl.list.mutex.Lock()
-	defer gl.list.mutex.Unlock()
-
-	return gl.getPlayersGame(player)
+	game, ok := gl.playersGame.Get(player)
+	if !ok {
+		return nil
+	}
+	return game
 }
 
 //endregion
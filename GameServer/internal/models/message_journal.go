@@ -0,0 +1,247 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"gameserver/internal/logger"
+	"gameserver/internal/utils/constants"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// region CONSTANTS
+const (
+	cJournalFilePrefix = "journal_"
+	cJournalFileSuffix = ".jsonl"
+)
+
+//endregion
+
+//region DATA STRUCTURES
+
+type MessageType int
+
+func (mt MessageType) String() string {
+	switch mt {
+	case Send:
+		return "Send"
+	case Received:
+		return "Received"
+	default:
+		return fmt.Sprintf("Unknown(%d)", mt)
+	}
+}
+
+// enum which is send or received
+const (
+	Send MessageType = iota
+	Received
+)
+
+// JournalConfig bounds the journal, MaxMessages of every session and ended sessions are kept for Retention
+type JournalConfig struct {
+	MaxMessages int
+	Retention   time.Duration
+	Log         *logrus.Logger
+}
+
+// JournalEntry is one message written to or read from a connection, Raw is the message as it was on the wire,
+// so a spilled journal can be replayed
+type JournalEntry struct {
+	Time          time.Time `json:"time"`
+	SessionID     int       `json:"session_id"`
+	Direction     string    `json:"direction"`
+	Nickname      string    `json:"nickname"`
+	CommandID     int       `json:"command_id"`
+	Command       string    `json:"command"`
+	CorrelationID string    `json:"correlation_id,omitempty"`
+	Raw           string    `json:"raw"`
+}
+
+// MessageJournal keeps the last messages of every session in a ring buffer and optionally spills all of them
+// into rotating JSON lines files
+type MessageJournal struct {
+	config   JournalConfig
+	sessions map[net.Conn]*journalSession
+	spill    io.WriteCloser
+	mutex    sync.Mutex
+}
+
+type journalSession struct {
+	id      int
+	endedAt time.Time // zero while the session is open
+	entries []JournalEntry
+	next    int // index of the oldest entry once the ring is full
+}
+
+//endregion
+
+//region FUNCTIONS
+
+func CreateMessageJournal(config JournalConfig) *MessageJournal {
+	return &MessageJournal{
+		config:   config,
+		sessions: make(map[net.Conn]*journalSession),
+	}
+}
+
+// OpenSpill writes every following entry also into journal_<timestamp>.jsonl files in folder
+func (j *MessageJournal) OpenSpill(folder string, rotation logger.RotationConfig) error {
+	spill, err := logger.OpenRotatingFile(folder, cJournalFilePrefix, cJournalFileSuffix, rotation)
+	if err != nil {
+		return fmt.Errorf("error opening journal file: %w", err)
+	}
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	j.spill = spill
+	return nil
+}
+
+// CloseSpill closes journal file, entries are then kept only in memory
+func (j *MessageJournal) CloseSpill() error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if j.spill == nil {
+		return nil
+	}
+
+	err := j.spill.Close()
+	j.spill = nil
+	return err
+}
+
+// StartSession starts journal of connection, messages of the connection are recorded under sessionID
+func (j *MessageJournal) StartSession(sessionID int, connection net.Conn) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	j.removeExpiredSessions()
+	j.sessions[connection] = &journalSession{id: sessionID}
+}
+
+// EndSession marks session as ended, its messages stay queryable for Retention
+func (j *MessageJournal) EndSession(connection net.Conn) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	session, ok := j.sessions[connection]
+	if ok && session.endedAt.IsZero() {
+		session.endedAt = time.Now()
+	}
+	j.removeExpiredSessions()
+}
+
+// Record adds message of the connection to the journal, raw is the message on the wire
+func (j *MessageJournal) Record(connection net.Conn, direction MessageType, message Message, raw string) {
+	// pings and acks would flood the log
+	if message.CommandID != constants.CGCommands.ServerPingPlayer.CommandID && message.CommandID != constants.CGCommands.ResponseClientSuccess.CommandID {
+		j.config.Log.WithFields(message.LogFields()).Infof("Message type %v:\n%s", direction.String(), message.String())
+	}
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	entry := JournalEntry{
+		Time:          time.Now(),
+		Direction:     strings.ToLower(direction.String()),
+		Nickname:      message.PlayerNickname,
+		CommandID:     message.CommandID,
+		Command:       constants.GetCommandName(message.CommandID),
+		CorrelationID: message.CorrelationID,
+		Raw:           strings.TrimRight(raw, "\n"),
+	}
+
+	session, ok := j.sessions[connection]
+	if ok {
+		entry.SessionID = session.id
+		session.add(entry, j.config.MaxMessages)
+	}
+
+	j.writeSpill(entry)
+}
+
+// Query returns entries of player between from and to ordered by time, zero from or to is not limited
+func (j *MessageJournal) Query(nickname string, from time.Time, to time.Time) []JournalEntry {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	entries := []JournalEntry{}
+	for _, session := range j.sessions {
+		for _, entry := range session.ordered() {
+			if entry.Nickname != nickname {
+				continue
+			}
+			if !from.IsZero() && entry.Time.Before(from) {
+				continue
+			}
+			if !to.IsZero() && entry.Time.After(to) {
+				continue
+			}
+			entries = append(entries, entry)
+		}
+	}
+
+	sort.SliceStable(entries, func(a, b int) bool { return entries[a].Time.Before(entries[b].Time) })
+	return entries
+}
+
+// removeExpiredSessions removes sessions ended before Retention, journal has to be locked
+func (j *MessageJournal) removeExpiredSessions() {
+	for connection, session := range j.sessions {
+		if !session.endedAt.IsZero() && time.Since(session.endedAt) > j.config.Retention {
+			delete(j.sessions, connection)
+		}
+	}
+}
+
+// writeSpill writes entry as one JSON line, journal has to be locked
+func (j *MessageJournal) writeSpill(entry JournalEntry) {
+	if j.spill == nil {
+		return
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		j.config.Log.Errorf("JOURNAL: Cannot encode entry: %v", err)
+		return
+	}
+
+	_, err = j.spill.Write(append(line, '\n'))
+	if err != nil {
+		j.config.Log.Errorf("JOURNAL: Cannot write entry: %v", err)
+	}
+}
+
+//endregion
+
+//region RING BUFFER
+
+// add appends entry, when the session has maxMessages entries the oldest one is overwritten
+func (s *journalSession) add(entry JournalEntry, maxMessages int) {
+	if maxMessages <= 0 {
+		return
+	}
+
+	if len(s.entries) < maxMessages {
+		s.entries = append(s.entries, entry)
+		return
+	}
+
+	s.entries[s.next] = entry
+	s.next = (s.next + 1) % len(s.entries)
+}
+
+// ordered returns entries from the oldest
+func (s *journalSession) ordered() []JournalEntry {
+	return append(append([]JournalEntry{}, s.entries[s.next:]...), s.entries[:s.next]...)
+}
+
+//endregion
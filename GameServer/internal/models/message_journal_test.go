@@ -0,0 +1,192 @@
+package models
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"gameserver/internal/logger"
+	"gameserver/internal/utils/constants"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+//region HELPERS
+
+func createTestJournal(maxMessages int, retention time.Duration) *MessageJournal {
+	return CreateMessageJournal(JournalConfig{MaxMessages: maxMessages, Retention: retention, Log: createTestLog()})
+}
+
+// createTestConnection returns one end of a pipe, the journal uses it only as a key of the session
+func createTestConnection(t *testing.T) net.Conn {
+	t.Helper()
+
+	connection, other := net.Pipe()
+	t.Cleanup(func() {
+		_ = connection.Close()
+		_ = other.Close()
+	})
+	return connection
+}
+
+func recordTestMessages(journal *MessageJournal, connection net.Conn, nickname string, raws ...string) {
+	for _, raw := range raws {
+		message := Message{CommandID: constants.CGCommands.ClientJoinGame.CommandID, PlayerNickname: nickname}
+		journal.Record(connection, Received, message, raw+"\n")
+	}
+}
+
+func journalRaws(entries []JournalEntry) []string {
+	raws := []string{}
+	for _, entry := range entries {
+		raws = append(raws, entry.Raw)
+	}
+	return raws
+}
+
+// readSpilledEntries returns entries of all journal files in folder from the oldest file
+func readSpilledEntries(t *testing.T, folder string) []JournalEntry {
+	t.Helper()
+
+	paths, err := filepath.Glob(filepath.Join(folder, cJournalFilePrefix+"*"+cJournalFileSuffix))
+	if err != nil {
+		t.Fatalf("cannot list journal files: %v", err)
+	}
+	sort.Strings(paths)
+
+	entries := []JournalEntry{}
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			t.Fatalf("cannot open %s: %v", path, err)
+		}
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			var entry JournalEntry
+			err = json.Unmarshal(scanner.Bytes(), &entry)
+			if err != nil {
+				t.Fatalf("invalid line %q in %s: %v", scanner.Text(), path, err)
+			}
+			entries = append(entries, entry)
+		}
+		_ = file.Close()
+	}
+	return entries
+}
+
+//endregion
+
+//region TESTS
+
+func TestJournalRingKeepsLastMessages(t *testing.T) {
+	journal := createTestJournal(3, time.Hour)
+	connection := createTestConnection(t)
+	journal.StartSession(1, connection)
+
+	recordTestMessages(journal, connection, "alice", "m1", "m2")
+	if raws := journalRaws(journal.Query("alice", time.Time{}, time.Time{})); !reflect.DeepEqual(raws, []string{"m1", "m2"}) {
+		t.Fatalf("expected [m1 m2] before the ring is full, got %v", raws)
+	}
+
+	// the ring wraps around more than once
+	recordTestMessages(journal, connection, "alice", "m3", "m4", "m5", "m6", "m7")
+	expected := []string{"m5", "m6", "m7"}
+	if raws := journalRaws(journal.Query("alice", time.Time{}, time.Time{})); !reflect.DeepEqual(raws, expected) {
+		t.Fatalf("expected %v, got %v", expected, raws)
+	}
+}
+
+func TestJournalRingsOfSessionsAreSeparate(t *testing.T) {
+	journal := createTestJournal(2, time.Hour)
+	first := createTestConnection(t)
+	second := createTestConnection(t)
+	journal.StartSession(1, first)
+	journal.StartSession(2, second)
+
+	recordTestMessages(journal, first, "alice", "a1")
+	recordTestMessages(journal, second, "bob", "b1", "b2", "b3")
+
+	if raws := journalRaws(journal.Query("alice", time.Time{}, time.Time{})); !reflect.DeepEqual(raws, []string{"a1"}) {
+		t.Fatalf("expected [a1], got %v", raws)
+	}
+	if raws := journalRaws(journal.Query("bob", time.Time{}, time.Time{})); !reflect.DeepEqual(raws, []string{"b2", "b3"}) {
+		t.Fatalf("expected [b2 b3], got %v", raws)
+	}
+}
+
+func TestJournalWithoutMaxMessagesKeepsNothing(t *testing.T) {
+	journal := createTestJournal(0, time.Hour)
+	connection := createTestConnection(t)
+	journal.StartSession(1, connection)
+
+	recordTestMessages(journal, connection, "alice", "m1", "m2")
+
+	if entries := journal.Query("alice", time.Time{}, time.Time{}); len(entries) != 0 {
+		t.Fatalf("expected no entries, got %v", journalRaws(entries))
+	}
+}
+
+func TestJournalEndedSessionIsRemovedAfterRetention(t *testing.T) {
+	journal := createTestJournal(5, 0)
+	ended := createTestConnection(t)
+	journal.StartSession(1, ended)
+	recordTestMessages(journal, ended, "alice", "m1")
+
+	journal.EndSession(ended)
+	time.Sleep(time.Millisecond)
+	// expired sessions are removed when another session starts
+	journal.StartSession(2, createTestConnection(t))
+
+	if entries := journal.Query("alice", time.Time{}, time.Time{}); len(entries) != 0 {
+		t.Fatalf("expected ended session to be removed, got %v", journalRaws(entries))
+	}
+}
+
+func TestJournalSpillsEveryMessage(t *testing.T) {
+	folder := t.TempDir()
+	journal := createTestJournal(1, time.Hour)
+	// files are small, so the spill rotates
+	err := journal.OpenSpill(folder, logger.RotationConfig{MaxSize: 300})
+	if err != nil {
+		t.Fatalf("cannot open spill: %v", err)
+	}
+
+	connection := createTestConnection(t)
+	journal.StartSession(7, connection)
+	var raws []string
+	for i := 1; i <= 10; i++ {
+		raws = append(raws, fmt.Sprintf("m%d", i))
+	}
+	recordTestMessages(journal, connection, "alice", raws...)
+	// message of a connection without session is spilled too
+	recordTestMessages(journal, createTestConnection(t), "", "unknown")
+
+	err = journal.CloseSpill()
+	if err != nil {
+		t.Fatalf("cannot close spill: %v", err)
+	}
+	recordTestMessages(journal, connection, "alice", "after close")
+
+	entries := readSpilledEntries(t, folder)
+	expected := append(raws, "unknown")
+	if spilled := journalRaws(entries); !reflect.DeepEqual(spilled, expected) {
+		t.Fatalf("expected spilled %v, got %v", expected, spilled)
+	}
+	if entries[0].SessionID != 7 || entries[0].Nickname != "alice" || entries[0].Direction != "received" || entries[0].Command != "ClientJoinGame" {
+		t.Fatalf("unexpected spilled entry %+v", entries[0])
+	}
+	if entries[len(entries)-1].SessionID != 0 {
+		t.Fatalf("expected no session id of unknown connection, got %d", entries[len(entries)-1].SessionID)
+	}
+
+	paths, _ := filepath.Glob(filepath.Join(folder, cJournalFilePrefix+"*"))
+	if len(paths) < 2 {
+		t.Fatalf("expected rotated journal files, got %v", paths)
+	}
+}
+
+//endregion
@@ -7,6 +7,7 @@ import (
 	"gameserver/internal/utils/constants"
 	"gameserver/internal/utils/errorHandeling"
 	"gameserver/pkg/stateless"
+	"github.com/sirupsen/logrus"
 	"sync"
 	"time"
 )
@@ -29,13 +30,46 @@ var ConnectionStates = ConnectionStateStruct{
 	TotalDisconnect: 2,
 }
 
+func (s ConnectionStateType) String() string {
+	switch s {
+	case ConnectionStates.Connected:
+		return "connected"
+	case ConnectionStates.Disconnected:
+		return "disconnected"
+	case ConnectionStates.TotalDisconnect:
+		return "total_disconnect"
+	}
+	return "unknown"
+}
+
+// PendingAck is server message waiting for ResponseClientSuccess
+type PendingAck struct {
+	Message     Message
+	Deadline    time.Time
+	IsCritical  bool
+	Retransmits int
+}
+
+// ClientMessageRecord holds responses sent to client message with given ID, so a retry can be answered from cache
+type ClientMessageRecord struct {
+	ID        string
+	CommandID int
+	Responses []Message
+}
+
 // Player represents a Player with a unique ID and nickname.
 type Player struct {
 	nickname                 string
+	log                      *logrus.Logger
 	connectionState          ConnectionStateType
 	connectionInfo           ConnectionInfo
 	stateMachine             *stateless.StateMachine
-	responseSuccessExpected  []Message
+	pendingAcks              []PendingAck
+	lastSequenceNumber       int
+	lastSentTimeStamp        string
+	clientMessages           []ClientMessageRecord
+	currentClientMessage     *ClientMessageRecord
+	correlationID            string
 	mutex                    sync.Mutex
 	lastPingTime             time.Time
 	totalDisconnectTime      time.Time
@@ -45,13 +79,14 @@ type Player struct {
 
 //endregion
 
-// CreatePlayer creates a new Player with a unique ID and nickname.
-func CreatePlayer(nickname string, connectionInfo ConnectionInfo) *Player {
+// CreatePlayer creates a new Player with a unique ID and nickname, log is logger of his server
+func CreatePlayer(nickname string, connectionInfo ConnectionInfo, log *logrus.Logger) *Player {
 	return &Player{
 		nickname:                 nickname,
+		log:                      log,
 		connectionState:          ConnectionStates.Connected,
 		connectionInfo:           connectionInfo,
-		responseSuccessExpected:  []Message{},
+		pendingAcks:              []PendingAck{},
 		stateMachine:             state_machine.CreateStateMachine(),
 		isSetTotalDisconnect:     false,
 		wasTotalDisconnectCalled: false,
@@ -100,22 +135,15 @@ func (p *Player) IsConnected() bool {
 	return p.connectionState == ConnectionStates.Connected
 }
 
-//func (p *Player) GetResponseSuccessExpected() int {
-//	p.lock()
-//	defer p.unlock()
-//
-//	return p.responseSuccessExpected
-//}
-
 // is player expecting a response
 func (p *Player) IsResponseSuccessExpected() bool {
 	p.lock()
 	defer p.unlock()
 
-	lenList := len(p.responseSuccessExpected)
+	lenList := len(p.pendingAcks)
 
 	if lenList >= 2 {
-		logger.Log.Infof("RESPONSE_EXPECTED: Player %s has %d responses expected", p.nickname, lenList)
+		p.logWithoutLock().Debugf("RESPONSE_EXPECTED: Player %s has %d responses expected", p.nickname, lenList)
 	}
 
 	return lenList > 0
@@ -125,11 +153,14 @@ func (p *Player) IsResponseSuccessExpected() bool {
 
 //region SETTERS
 
-// Reset ResponseSuccessExpected
-func (p *Player) resetResponseSuccessExpected() {
-	logger.Log.Infof("RESPONSE_EXPECTED: Reseting Player %s has %d responses expected", p.nickname, len(p.responseSuccessExpected))
+// ResetResponseSuccessExpected forgets all messages waiting for ack, used when the session is replaced
+func (p *Player) ResetResponseSuccessExpected() {
+	p.lock()
+	defer p.unlock()
+
+	p.logWithoutLock().Infof("RESPONSE_EXPECTED: Reseting Player %s has %d responses expected", p.nickname, len(p.pendingAcks))
 
-	p.responseSuccessExpected = []Message{}
+	p.pendingAcks = []PendingAck{}
 }
 
 // SetConnectionInfo sets the connection info of the Player
@@ -153,103 +184,184 @@ func (p *Player) SetConnectedByBool(isConnected bool) {
 	}
 }
 
-func diffWithCurrentTime(timeStr string) time.Duration {
-	messageTime, err := time.Parse(constants.CMessageTimeFormat, timeStr)
-	if err != nil {
-		errorHandeling.AssertError(fmt.Errorf("Error parsing message time: %w", err))
+// GetExpiredResponses returns messages which should be retransmitted and whether some message ran out of retransmits,
+// retransmitted messages wait for ack another ackTimeout
+func (p *Player) GetExpiredResponses(ackTimeout time.Duration, maxRetransmits int) ([]Message, bool) {
+	p.lock()
+	defer p.unlock()
+
+	now := time.Now()
+	var retransmit []Message
+
+	for i := range p.pendingAcks {
+		pending := &p.pendingAcks[i]
+		if now.Before(pending.Deadline) {
+			continue
+		}
+
+		if !pending.IsCritical || pending.Retransmits >= maxRetransmits {
+			p.logWithoutLock().Infof("TIME_OUT: - Response from Player %s for message %d seq %d", p.nickname, pending.Message.CommandID, pending.Message.SequenceNumber)
+			return nil, true
+		}
+
+		pending.Retransmits++
+		pending.Deadline = now.Add(ackTimeout)
+		p.logWithoutLock().Infof("RETRANSMIT: Player %s message %d seq %d attempt %d", p.nickname, pending.Message.CommandID, pending.Message.SequenceNumber, pending.Retransmits)
+		retransmit = append(retransmit, pending.Message)
 	}
 
-	currentTime := time.Now()
-	formattedCurrentTimeStr := currentTime.Format(constants.CMessageTimeFormat)
-	formattedCurrentTime, err := time.Parse(constants.CMessageTimeFormat, formattedCurrentTimeStr)
-	if err != nil {
-		errorHandeling.AssertError(fmt.Errorf("Error parsing current time: %w", err))
+	return retransmit, false
+}
+
+// GetNextResponseDeadline returns the earliest deadline of messages waiting for ack
+func (p *Player) GetNextResponseDeadline() (time.Time, bool) {
+	p.lock()
+	defer p.unlock()
+
+	if len(p.pendingAcks) == 0 {
+		return time.Time{}, false
 	}
 
-	diff := formattedCurrentTime.Sub(messageTime)
+	deadline := p.pendingAcks[0].Deadline
+	for _, pending := range p.pendingAcks[1:] {
+		if pending.Deadline.Before(deadline) {
+			deadline = pending.Deadline
+		}
+	}
 
-	return diff
+	return deadline, true
 }
 
-// is response expected timeout
-func (p *Player) IsResponseExpectedTimeout() (bool, error) {
+// IncreaseResponseSuccessExpected assigns the message next sequence number and unique timestamp and waits ackTimeout for its ack
+func (p *Player) IncreaseResponseSuccessExpected(message *Message, isCritical bool, ackTimeout time.Duration) {
 	p.lock()
 	defer p.unlock()
 
-	timeOut := constants.CTimeout
+	p.lastSequenceNumber++
+	message.SequenceNumber = p.lastSequenceNumber
 
-	for _, message := range p.responseSuccessExpected {
+	// timestamp is echoed back by clients which do not send seq, so it has to be unique within the session
+	if message.TimeStamp <= p.lastSentTimeStamp {
+		lastTime, err := time.Parse(constants.CMessageTimeFormat, p.lastSentTimeStamp)
+		if err == nil {
+			message.TimeStamp = lastTime.Add(time.Microsecond).Format(constants.CMessageTimeFormat)
+		}
+	}
+	p.lastSentTimeStamp = message.TimeStamp
 
-		diff := diffWithCurrentTime(message.TimeStamp)
+	p.pendingAcks = append(p.pendingAcks, PendingAck{
+		Message:    *message,
+		Deadline:   time.Now().Add(ackTimeout),
+		IsCritical: isCritical,
+	})
 
-		isTimeout := diff > timeOut
-		if isTimeout {
-			logger.Log.Infof("TIME_OUT: - Response from Player %s for message %s", p.nickname, message.CommandID)
-			//time values in log
+	if len(p.pendingAcks) >= 2 {
+		p.logWithoutLock().Debugf("RESPONSE_EXPECTED: When Increased Player %s has %d responses expected", p.nickname, len(p.pendingAcks))
+	}
+}
 
-			return true, nil
+// DecreaseResponseSuccessExpected acks message with sequenceNumber, when client does not send it (0) the timestamp is used
+func (p *Player) DecreaseResponseSuccessExpected(sequenceNumber int, timeStamp string) error {
+	p.lock()
+	defer p.unlock()
+
+	for i, pending := range p.pendingAcks {
+		isMatch := pending.Message.SequenceNumber == sequenceNumber
+		if sequenceNumber == 0 {
+			isMatch = pending.Message.TimeStamp == timeStamp
 		}
 
+		if isMatch {
+			p.pendingAcks = append(p.pendingAcks[:i], p.pendingAcks[i+1:]...)
+			return nil
+		}
 	}
 
-	return false, nil
+	// duplicate ack of retransmitted message
+	p.logWithoutLock().Debugf("RESPONSE_EXPECTED: Player %s acked unknown message seq %d timestamp %s", p.nickname, sequenceNumber, timeStamp)
+	return nil
 }
 
-// increase the number of expected responses
-func (p *Player) IncreaseResponseSuccessExpected(message Message) {
+// RemoveResponseSuccessExpected stops waiting for message which was never delivered
+func (p *Player) RemoveResponseSuccessExpected(sequenceNumber int) {
 	p.lock()
 	defer p.unlock()
-	lenList := len(p.responseSuccessExpected)
 
-	if lenList+1 >= 2 {
-		logger.Log.Infof("RESPONSE_EXPECTED: When Increased Player %s has %d responses expected", p.nickname, lenList+1)
+	for i, pending := range p.pendingAcks {
+		if pending.Message.SequenceNumber == sequenceNumber {
+			p.pendingAcks = append(p.pendingAcks[:i], p.pendingAcks[i+1:]...)
+			return
+		}
 	}
+}
 
-	//Add to list
+// GetClientMessageResponses returns cached responses if client message with id has already been processed
+func (p *Player) GetClientMessageResponses(id string, commandID int) ([]Message, bool) {
+	p.lock()
+	defer p.unlock()
 
-	//todo add multiple to list not just the last
-	//p.responseSuccessExpected = append(p.responseSuccessExpected, message)
+	for _, record := range p.clientMessages {
+		if record.ID == id && record.CommandID == commandID {
+			return record.Responses, true
+		}
+	}
 
-	//crate new list where is only one element
-	p.responseSuccessExpected = []Message{message}
+	return nil, false
 }
 
-// decrease the number of expected responses
-func (p *Player) DecreaseResponseSuccessExpected(timeStamp string) error {
+// StartClientMessage starts recording responses sent to client message with id
+func (p *Player) StartClientMessage(id string, commandID int) {
 	p.lock()
 	defer p.unlock()
-	len_list := len(p.responseSuccessExpected)
-	if len_list >= 2 {
-		//player has that many responses expected
-		logger.Log.Infof("RESPONSE_EXPECTED: Player %s has %d responses expected", p.nickname, len_list)
+
+	p.currentClientMessage = &ClientMessageRecord{
+		ID:        id,
+		CommandID: commandID,
+		Responses: []Message{},
 	}
+}
 
-	//if len_list-1 < 0 {
-	//	err := fmt.Errorf("Player has already expected a response")
-	//	errorHandeling.PrintError(err)
-	//	return err
-	//}
+// AddClientMessageResponse records response to currently processed client message, if there is one
+func (p *Player) AddClientMessageResponse(message Message) {
+	p.lock()
+	defer p.unlock()
 
-	//Remove with same timestamp
-	//todo
-	//for i, message := range p.responseSuccessExpected {
-	//	if message.TimeStamp == timeStamp {
-	//		p.responseSuccessExpected = append(p.responseSuccessExpected[:i], p.responseSuccessExpected[i+1:]...)
-	//		return nil
-	//	}
-	//}
+	if p.currentClientMessage == nil {
+		return
+	}
 
-	if len_list == 0 {
-		return nil
+	p.currentClientMessage.Responses = append(p.currentClientMessage.Responses, message)
+}
+
+// FinishClientMessage stores recorded responses, only the last cacheSize messages are remembered
+func (p *Player) FinishClientMessage(cacheSize int) {
+	p.lock()
+	defer p.unlock()
+
+	if p.currentClientMessage == nil {
+		return
 	}
 
-	//remove last element
-	p.responseSuccessExpected = p.responseSuccessExpected[:len(p.responseSuccessExpected)-1]
-	return nil
+	p.clientMessages = append(p.clientMessages, *p.currentClientMessage)
+	if len(p.clientMessages) > cacheSize {
+		p.clientMessages = p.clientMessages[len(p.clientMessages)-cacheSize:]
+	}
+	p.currentClientMessage = nil
+}
 
-	//err := fmt.Errorf("No response with timestamp %s found", timeStamp)
-	//errorHandeling.PrintError(err)
-	//return err
+// SetCorrelationID sets ID of the message of the player being processed, empty when processing has finished
+func (p *Player) SetCorrelationID(correlationID string) {
+	p.lock()
+	defer p.unlock()
+
+	p.correlationID = correlationID
+}
+
+func (p *Player) GetCorrelationID() string {
+	p.lock()
+	defer p.unlock()
+
+	return p.correlationID
 }
 
 // Fires the state machine
@@ -261,7 +373,7 @@ func (p *Player) FireStateMachine(trigger stateless.Trigger) error {
 
 	err := p.stateMachine.Fire(trigger)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(p.log, err)
 		return err
 	}
 
@@ -272,7 +384,7 @@ func (p *Player) FireStateMachine(trigger stateless.Trigger) error {
 	//	//p.resetResponseSuccessExpected()
 	//}
 
-	logger.Log.Infof("AUTOMATA: Player %s changed state from %s : - %s - : %s", p.nickname, beforeState, trigger, afterState)
+	p.logWithoutLock().Infof("AUTOMATA: Player %s changed state from %s : - %s - : %s", p.nickname, beforeState, trigger, afterState)
 	return nil
 }
 
@@ -284,7 +396,7 @@ func (p *Player) GetCurrentStateName() string {
 	currentState := p.stateMachine.MustState()
 	currentStateName, ok := currentState.(string)
 	if !ok {
-		errorHandeling.AssertError(fmt.Errorf("cannot assert state name"))
+		errorHandeling.AssertError(p.log, fmt.Errorf("cannot assert state name"))
 	}
 
 	return currentStateName
@@ -310,6 +422,21 @@ func (p *Player) unlock() {
 	p.mutex.Unlock()
 }
 
+// logWithoutLock returns log entry with fields of the player, the player has to be locked
+func (p *Player) logWithoutLock() *logrus.Entry {
+	fields := logrus.Fields{
+		logger.FieldNickname: p.nickname,
+		logger.FieldState:    p.stateMachine.MustState(),
+	}
+	if p.connectionInfo.SessionID != 0 {
+		fields[logger.FieldSessionID] = p.connectionInfo.SessionID
+	}
+	if p.correlationID != "" {
+		fields[logger.FieldCorrelationID] = p.correlationID
+	}
+	return p.log.WithFields(fields)
+}
+
 func (p *Player) IsInTurn() bool {
 	allowedStatesName := []string{
 		state_machine.StateNameMap.StateMyTurn,
@@ -342,20 +469,20 @@ func (p *Player) SetLastPingCurrentTime() {
 	p.lastPingTime = time.Now()
 }
 
-func (p *Player) IsTimeForNewPing() bool {
+func (p *Player) IsTimeForNewPing(pingInterval time.Duration) bool {
 	p.lock()
 	defer p.unlock()
 
 	diff := time.Since(p.lastPingTime)
 
-	return diff > constants.CPingTime
+	return diff > pingInterval
 }
 
 func (p *Player) SetTotalDisconnectStartTime() {
 	p.lock()
 	defer p.unlock()
 
-	logger.Log.Debugf("TOTAL_DISCONNECT: Player %s total disconnect start time set", p.nickname)
+	p.logWithoutLock().Debugf("TOTAL_DISCONNECT: Player %s total disconnect start time set", p.nickname)
 
 	p.totalDisconnectTime = time.Now()
 	p.isSetTotalDisconnect = true
@@ -378,8 +505,8 @@ func (p *Player) IsSetTotalDisconnect() bool {
 	return p.isSetTotalDisconnect
 }
 
-// is player total disconnect timeout
-func (p *Player) IsTotalDisconnectTimeout() bool {
+// is player total disconnect timeout, reconnectWindow has elapsed since disconnect
+func (p *Player) IsTotalDisconnectTimeout(reconnectWindow time.Duration) bool {
 	p.lock()
 	defer p.unlock()
 
@@ -389,7 +516,7 @@ func (p *Player) IsTotalDisconnectTimeout() bool {
 
 	diff := time.Since(p.totalDisconnectTime)
 
-	return diff > constants.CTotalDisconnectTime
+	return diff > reconnectWindow
 }
 
 // set was total disconnect called
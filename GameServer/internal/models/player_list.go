@@ -3,36 +3,38 @@ package models
 import (
 	"fmt"
 	"gameserver/internal/utils/errorHandeling"
+	"github.com/sirupsen/logrus"
 	"net"
-	"sync"
 )
 
 type PlayerList struct {
-	list *List
+	registry     *Registry[string, *Player]
+	byConnection *Index[net.Conn, string, *Player]
+	// log is logger of the server
+	log *logrus.Logger
 }
 
-var (
-	instancePL *PlayerList
-	oncePL     sync.Once
-)
-
-func GetInstancePlayerList() *PlayerList {
-	oncePL.Do(func() {
-		instancePL = &PlayerList{
-			list: CreateList(),
-		}
-	})
-	return instancePL
+func CreatePlayerList(log *logrus.Logger) *PlayerList {
+	registry := CreateRegistry[string, *Player]()
+
+	return &PlayerList{
+		registry: registry,
+		log:      log,
+		byConnection: AddIndex(registry, func(player *Player) []net.Conn {
+			connection := player.GetConnectionInfo().Connection
+			if connection == nil {
+				return nil
+			}
+			return []net.Conn{connection}
+		}),
+	}
 }
 
 // get player list where player is in state in argument
+// players are filtered on a snapshot, so player locks are never taken while holding the list lock
 func (pl *PlayerList) GetActivePlayersInState(state string) []*Player {
-	pl.list.mutex.Lock()
-	defer pl.list.mutex.Unlock()
-
 	var players []*Player
-	for _, v := range pl.list.data {
-		player := v.(*Player)
+	for _, player := range pl.GetValuesArray() {
 		if player.GetCurrentStateName() == state && player.IsConnected() {
 			players = append(players, player)
 		}
@@ -42,30 +44,31 @@ func (pl *PlayerList) GetActivePlayersInState(state string) []*Player {
 }
 
 func (pl *PlayerList) AddItem(player *Player) error {
-	pl.list.mutex.Lock()
-	defer pl.list.mutex.Unlock()
-
-	key := player.GetNickname()
-
-	err := pl.list.AddItemKey(key, player)
+	err := pl.registry.Add(player.GetNickname(), player)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		err = fmt.Errorf("%w: %w", ErrNicknameTaken, err)
+		errorHandeling.PrintError(pl.log, err)
 		return err
 	}
 	return nil
 }
 
-func (pl *PlayerList) GetItem(key string) (*Player, error) {
-	pl.list.mutex.Lock()
-	defer pl.list.mutex.Unlock()
+// SetPlayerConnection sets connection info of the player and keeps the connection index consistent
+func (pl *PlayerList) SetPlayerConnection(player *Player, connectionInfo ConnectionInfo) {
+	player.SetConnectionInfo(connectionInfo)
 
+	if !pl.HasValue(player) {
+		return
+	}
+	_ = pl.registry.Reindex(player.GetNickname())
+}
+
+func (pl *PlayerList) GetItem(key string) (*Player, error) {
 	if key == "" {
 		return nil, fmt.Errorf("key is empty")
 	}
 
-	item := pl.list.GetItemWithoutLock(key)
-
-	player, ok := item.(*Player)
+	player, ok := pl.registry.Get(key)
 	if !ok {
 		return nil, fmt.Errorf("item is not a Player")
 	}
@@ -73,53 +76,36 @@ func (pl *PlayerList) GetItem(key string) (*Player, error) {
 }
 
 func (pl *PlayerList) HasItemName(playerName string) bool {
-	pl.list.mutex.Lock()
-	defer pl.list.mutex.Unlock()
-
-	item := pl.list.GetItemWithoutLock(playerName)
-	if item == nil {
-		return false
-	}
-
-	return true
+	return pl.registry.Has(playerName)
 }
 
 // GetPlayerByConnection
 func (pl *PlayerList) GetPlayerByConnection(connection net.Conn) *Player {
-	pl.list.mutex.Lock()
-	defer pl.list.mutex.Unlock()
-
-	for _, v := range pl.list.data {
-		player := v.(*Player)
-		if player.GetConnectionInfo().Connection == connection {
-			return player
-		}
+	player, ok := pl.byConnection.Get(connection)
+	if !ok {
+		return nil
 	}
-
-	return nil
+	return player
 }
 
 // Has Item in list
 func (pl *PlayerList) HasValue(player *Player) bool {
-	pl.list.mutex.Lock()
-	defer pl.list.mutex.Unlock()
+	if player == nil {
+		return false
+	}
 
-	return pl.list.HasValue(player)
+	item, ok := pl.registry.Get(player.GetNickname())
+	return ok && item == player
 }
 
 func (pl *PlayerList) RemoveItem(player *Player) error {
-	pl.list.mutex.Lock()
-	defer pl.list.mutex.Unlock()
-
 	if player == nil {
 		return fmt.Errorf("player is nil")
 	}
 
-	key := player.GetNickname()
-
-	err := pl.list.RemoveItem(key)
+	_, err := pl.registry.Remove(player.GetNickname())
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(pl.log, err)
 		return err
 	}
 	return nil
@@ -128,29 +114,18 @@ func (pl *PlayerList) RemoveItem(player *Player) error {
 
 // GetValuesArrayWithoutOnePlayer
 func (pl *PlayerList) GetValuesArrayWithoutOnePlayer(player *Player) []*Player {
-	pl.list.mutex.Lock()
-	defer pl.list.mutex.Unlock()
-
 	var values []*Player
-	for _, v := range pl.list.data {
-		if v.(*Player) == player {
+	for _, v := range pl.registry.Values() {
+		if v == player {
 			continue
 		}
-		values = append(values, v.(*Player))
+		values = append(values, v)
 	}
 
 	return values
 }
 
-// GetValuesArray
+// GetValuesArray returns players in order of login
 func (pl *PlayerList) GetValuesArray() []*Player {
-	pl.list.mutex.Lock()
-	defer pl.list.mutex.Unlock()
-
-	var values []*Player
-	for _, v := range pl.list.data {
-		values = append(values, v.(*Player))
-	}
-
-	return values
+	return pl.registry.Values()
 }
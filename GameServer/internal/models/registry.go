@@ -0,0 +1,229 @@
+package models
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+)
+
+//region DATA STRUCTURES
+
+type registryEntry[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+// registryIndex is secondary index kept consistent by Registry, called with registry lock held
+type registryIndex[K comparable, V any] interface {
+	add(key K, value V)
+	remove(key K)
+}
+
+// Registry is typed map which remembers insertion order and keeps its secondary indexes consistent
+type Registry[K comparable, V any] struct {
+	items   map[K]*list.Element
+	order   *list.List
+	indexes []registryIndex[K, V]
+	mutex   sync.Mutex
+}
+
+// Index maps index key derived from the value to registry key, e.g. connection to player nickname
+type Index[IK comparable, K comparable, V any] struct {
+	registry   *Registry[K, V]
+	keysOf     func(value V) []IK
+	data       map[IK]K
+	keysByItem map[K][]IK
+}
+
+//endregion
+
+//region FUNCTIONS
+
+func CreateRegistry[K comparable, V any]() *Registry[K, V] {
+	return &Registry[K, V]{
+		items: make(map[K]*list.Element),
+		order: list.New(),
+	}
+}
+
+// AddIndex creates secondary index of registry, keysOf returns index keys of a value
+// and must not call back into the registry
+func AddIndex[IK comparable, K comparable, V any](registry *Registry[K, V], keysOf func(value V) []IK) *Index[IK, K, V] {
+	index := &Index[IK, K, V]{
+		registry:   registry,
+		keysOf:     keysOf,
+		data:       make(map[IK]K),
+		keysByItem: make(map[K][]IK),
+	}
+
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	for e := registry.order.Front(); e != nil; e = e.Next() {
+		entry := e.Value.(*registryEntry[K, V])
+		index.add(entry.key, entry.value)
+	}
+	registry.indexes = append(registry.indexes, index)
+
+	return index
+}
+
+//endregion
+
+//region REGISTRY
+
+// Add adds value under key, key must not be in registry yet
+func (r *Registry[K, V]) Add(key K, value V) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.items[key]; ok {
+		return fmt.Errorf("key already exists")
+	}
+
+	r.items[key] = r.order.PushBack(&registryEntry[K, V]{key: key, value: value})
+	for _, index := range r.indexes {
+		index.add(key, value)
+	}
+
+	return nil
+}
+
+// Remove removes key from registry and returns its value
+func (r *Registry[K, V]) Remove(key K) (V, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	element, ok := r.items[key]
+	if !ok {
+		var empty V
+		return empty, fmt.Errorf("key does not exist")
+	}
+
+	for _, index := range r.indexes {
+		index.remove(key)
+	}
+	delete(r.items, key)
+	entry := r.order.Remove(element).(*registryEntry[K, V])
+
+	return entry.value, nil
+}
+
+// Reindex updates secondary indexes of key after its value has changed
+func (r *Registry[K, V]) Reindex(key K) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	element, ok := r.items[key]
+	if !ok {
+		return fmt.Errorf("key does not exist")
+	}
+
+	entry := element.Value.(*registryEntry[K, V])
+	for _, index := range r.indexes {
+		index.remove(key)
+		index.add(key, entry.value)
+	}
+
+	return nil
+}
+
+func (r *Registry[K, V]) Get(key K) (V, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	element, ok := r.items[key]
+	if !ok {
+		var empty V
+		return empty, false
+	}
+
+	return element.Value.(*registryEntry[K, V]).value, true
+}
+
+func (r *Registry[K, V]) Has(key K) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	_, ok := r.items[key]
+	return ok
+}
+
+func (r *Registry[K, V]) Len() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return len(r.items)
+}
+
+// Values returns snapshot of values in insertion order
+func (r *Registry[K, V]) Values() []V {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	values := make([]V, 0, len(r.items))
+	for e := r.order.Front(); e != nil; e = e.Next() {
+		values = append(values, e.Value.(*registryEntry[K, V]).value)
+	}
+
+	return values
+}
+
+// ForEach calls callback for snapshot of items in insertion order, the lock is not held during callbacks,
+// so callback may use the registry. Iteration stops when callback returns false.
+func (r *Registry[K, V]) ForEach(callback func(key K, value V) bool) {
+	r.mutex.Lock()
+	entries := make([]registryEntry[K, V], 0, len(r.items))
+	for e := r.order.Front(); e != nil; e = e.Next() {
+		entries = append(entries, *e.Value.(*registryEntry[K, V]))
+	}
+	r.mutex.Unlock()
+
+	for _, entry := range entries {
+		if !callback(entry.key, entry.value) {
+			return
+		}
+	}
+}
+
+//endregion
+
+//region INDEX
+
+// Get returns value indexed by indexKey
+func (i *Index[IK, K, V]) Get(indexKey IK) (V, bool) {
+	i.registry.mutex.Lock()
+	defer i.registry.mutex.Unlock()
+
+	var empty V
+	key, ok := i.data[indexKey]
+	if !ok {
+		return empty, false
+	}
+	element, ok := i.registry.items[key]
+	if !ok {
+		return empty, false
+	}
+
+	return element.Value.(*registryEntry[K, V]).value, true
+}
+
+func (i *Index[IK, K, V]) add(key K, value V) {
+	indexKeys := i.keysOf(value)
+	for _, indexKey := range indexKeys {
+		i.data[indexKey] = key
+	}
+	i.keysByItem[key] = indexKeys
+}
+
+func (i *Index[IK, K, V]) remove(key K) {
+	for _, indexKey := range i.keysByItem[key] {
+		// index key may have been taken over by another item
+		if i.data[indexKey] == key {
+			delete(i.data, indexKey)
+		}
+	}
+	delete(i.keysByItem, key)
+}
+
+//endregion
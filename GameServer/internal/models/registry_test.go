@@ -0,0 +1,165 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+//region HELPERS
+
+// testItem is value of the test registry, tags are its index keys
+type testItem struct {
+	name string
+	tags []string
+}
+
+func createTestRegistry(t *testing.T, names ...string) (*Registry[string, *testItem], *Index[string, string, *testItem]) {
+	t.Helper()
+
+	registry := CreateRegistry[string, *testItem]()
+	index := AddIndex(registry, func(item *testItem) []string {
+		return item.tags
+	})
+	for _, name := range names {
+		err := registry.Add(name, &testItem{name: name, tags: []string{"tag-" + name}})
+		if err != nil {
+			t.Fatalf("cannot add %s: %v", name, err)
+		}
+	}
+
+	return registry, index
+}
+
+func registryNames(registry *Registry[string, *testItem]) []string {
+	names := []string{}
+	for _, item := range registry.Values() {
+		names = append(names, item.name)
+	}
+	return names
+}
+
+//endregion
+
+//region TESTS
+
+func TestRegistryKeepsInsertionOrder(t *testing.T) {
+	registry, _ := createTestRegistry(t, "charlie", "alice", "bob")
+
+	expected := []string{"charlie", "alice", "bob"}
+	if names := registryNames(registry); !reflect.DeepEqual(names, expected) {
+		t.Fatalf("expected %v, got %v", expected, names)
+	}
+
+	var visited []string
+	registry.ForEach(func(key string, item *testItem) bool {
+		visited = append(visited, key)
+		return key != "alice"
+	})
+	if expected := []string{"charlie", "alice"}; !reflect.DeepEqual(visited, expected) {
+		t.Fatalf("expected ForEach to stop after %v, got %v", expected, visited)
+	}
+}
+
+func TestRegistryRemoveKeepsOrderOfOthers(t *testing.T) {
+	registry, _ := createTestRegistry(t, "a", "b", "c", "d")
+
+	removed, err := registry.Remove("b")
+	if err != nil || removed.name != "b" {
+		t.Fatalf("expected removed b, got %v, %v", removed, err)
+	}
+	err = registry.Add("b", &testItem{name: "b"})
+	if err != nil {
+		t.Fatalf("cannot add b again: %v", err)
+	}
+
+	// added again is appended to the end
+	expected := []string{"a", "c", "d", "b"}
+	if names := registryNames(registry); !reflect.DeepEqual(names, expected) {
+		t.Fatalf("expected %v, got %v", expected, names)
+	}
+	if registry.Len() != len(expected) {
+		t.Fatalf("expected length %d, got %d", len(expected), registry.Len())
+	}
+}
+
+func TestRegistryRejectsDuplicateAndUnknownKeys(t *testing.T) {
+	registry, _ := createTestRegistry(t, "a")
+
+	if err := registry.Add("a", &testItem{name: "a"}); err == nil {
+		t.Fatalf("expected error adding duplicate key")
+	}
+	if _, err := registry.Remove("unknown"); err == nil {
+		t.Fatalf("expected error removing unknown key")
+	}
+	if err := registry.Reindex("unknown"); err == nil {
+		t.Fatalf("expected error reindexing unknown key")
+	}
+}
+
+func TestIndexIsConsistentOnRemove(t *testing.T) {
+	registry, index := createTestRegistry(t, "a", "b")
+
+	_, err := registry.Remove("a")
+	if err != nil {
+		t.Fatalf("cannot remove a: %v", err)
+	}
+
+	if _, ok := index.Get("tag-a"); ok {
+		t.Fatalf("removed item is still indexed")
+	}
+	if item, ok := index.Get("tag-b"); !ok || item.name != "b" {
+		t.Fatalf("expected b by its index key, got %v", item)
+	}
+}
+
+func TestIndexKeyTakenOverIsKeptOnRemove(t *testing.T) {
+	registry, index := createTestRegistry(t, "a")
+
+	// b takes over index key of a, e.g. player reconnected on a reused connection
+	err := registry.Add("b", &testItem{name: "b", tags: []string{"tag-a"}})
+	if err != nil {
+		t.Fatalf("cannot add b: %v", err)
+	}
+	_, err = registry.Remove("a")
+	if err != nil {
+		t.Fatalf("cannot remove a: %v", err)
+	}
+
+	if item, ok := index.Get("tag-a"); !ok || item.name != "b" {
+		t.Fatalf("expected index key to stay with b, got %v", item)
+	}
+}
+
+func TestIndexFollowsReindex(t *testing.T) {
+	registry, index := createTestRegistry(t, "a")
+
+	item, _ := registry.Get("a")
+	item.tags = []string{"new-tag"}
+	err := registry.Reindex("a")
+	if err != nil {
+		t.Fatalf("cannot reindex a: %v", err)
+	}
+
+	if _, ok := index.Get("tag-a"); ok {
+		t.Fatalf("old index key is still indexed")
+	}
+	if found, ok := index.Get("new-tag"); !ok || found != item {
+		t.Fatalf("expected a by its new index key, got %v", found)
+	}
+}
+
+func TestIndexAddedLaterContainsExistingItems(t *testing.T) {
+	registry, _ := createTestRegistry(t, "a", "b")
+
+	byName := AddIndex(registry, func(item *testItem) []string {
+		return []string{item.name}
+	})
+
+	for _, name := range []string{"a", "b"} {
+		if item, ok := byName.Get(name); !ok || item.name != name {
+			t.Fatalf("expected %s in index added later, got %v", name, item)
+		}
+	}
+}
+
+//endregion
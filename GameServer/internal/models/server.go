@@ -0,0 +1,501 @@
+package models
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"gameserver/internal/logger"
+	"gameserver/internal/metrics"
+	"gameserver/internal/scheduler"
+	"github.com/sirupsen/logrus"
+	"net"
+	"sync"
+	"time"
+)
+
+//region DATA STRUCTURES
+
+// Settings are timeouts and limits of connections, players and games, see config.NetworkConfig for their meaning
+type Settings struct {
+	AckTimeout             time.Duration
+	PingInterval           time.Duration
+	ReconnectWindow        time.Duration
+	WriteTimeout           time.Duration
+	ShutdownTimeout        time.Duration
+	SnapshotInterval       time.Duration
+	HandlerStuckTime       time.Duration
+	SendQueueSize          int
+	QueueFullPolicy        string
+	MaxRetransmits         int
+	MaxMessageSize         int
+	MessageBufferSize      int
+	ClientMessageCacheSize int
+	RateLimitMessages      int
+	RateLimitWindow        time.Duration
+	// MOTD is announced after login, empty disables it
+	MOTD string
+	// MaxScore is rule of games created from now on, see GameRules
+	MaxScore int
+	// TurnTimeout ends turn of player who does not roll in time, 0 disables it
+	TurnTimeout time.Duration
+	// NameLength limits names of players and games
+	NameLength NameLength
+	// Debug keeps panics of game handlers and timers fatal, so bugs are found in development
+	Debug bool
+}
+
+// ReloadResult lists config keys changed by reload, keys which need restart keep their old values
+type ReloadResult struct {
+	Applied         []string `json:"applied"`
+	RestartRequired []string `json:"restart_required"`
+}
+
+// ReloadHandler reads configuration again and applies keys which can change while the server runs
+type ReloadHandler func() (ReloadResult, error)
+
+// ServerConfig is configuration of one server instance, port "0" picks a free port
+type ServerConfig struct {
+	IP   string
+	Port string
+
+	// Settings have to be filled, e.g. from config.Config, they are read by GetSettings
+	Settings Settings
+
+	// Log is logger of the server, nil creates logger writing to stdout
+	Log *logrus.Logger
+
+	WebSocketEnabled bool
+	WebSocketIP      string
+	WebSocketPort    string
+	WebSocketPath    string
+	// WebSocketAllowedOrigins are browser origins accepted besides the host of the request, "*" accepts every origin
+	WebSocketAllowedOrigins []string
+
+	// TLSConfig is nil when listeners use plain tcp
+	TLSConfig *tls.Config
+
+	// SnapshotFilePath is file with games saved for restart, empty disables snapshots
+	SnapshotFilePath string
+
+	// HTTP serves read-only JSON API about games and players
+	HTTPEnabled bool
+	HTTPIP      string
+	HTTPPort    string
+
+	// Metrics are served in Prometheus text format on /metrics
+	MetricsEnabled bool
+	MetricsIP      string
+	MetricsPort    string
+
+	// Health serves /healthz and /readyz for process supervisor, also served by metrics listener
+	HealthEnabled bool
+	HealthIP      string
+	HealthPort    string
+
+	// Journal keeps JournalMaxMessages of every session, ended sessions for JournalRetention,
+	// with JournalSpillEnabled all messages are written also into rotating files in JournalFolder
+	JournalMaxMessages  int
+	JournalRetention    time.Duration
+	JournalSpillEnabled bool
+	JournalFolder       string
+	JournalRotation     logger.RotationConfig
+
+	// AdminNetwork is "tcp" (localhost only) or "unix", AdminToken has to be sent before any admin command
+	AdminEnabled bool
+	AdminNetwork string
+	AdminAddress string
+	AdminToken   string
+}
+
+// Server owns everything of one game server instance, so several servers can run in one process
+type Server struct {
+	Config     ServerConfig
+	PlayerList *PlayerList
+	GameList   *GameList
+	Scheduler  *scheduler.Scheduler
+	Events     *EventBus
+	Metrics    *metrics.ServerMetrics
+	Journal    *MessageJournal
+	Log        *logrus.Logger
+	// ConnectionWriters are writers of connections of this server
+	ConnectionWriters *ConnectionWriterList
+
+	listener          net.Listener
+	webSocketListener net.Listener
+	httpListener      net.Listener
+	metricsListener   net.Listener
+	healthListener    net.Listener
+	adminListener     net.Listener
+	isShuttingDown    bool
+	isMaintenance     bool
+	isAccepting       bool
+	lastSessionID     int
+	settings          Settings
+	reloadHandler     ReloadHandler
+	mutex             sync.Mutex
+	// snapshotMutex serializes saving of snapshots, so an older snapshot never replaces a newer one
+	snapshotMutex sync.Mutex
+
+	// ctx is root context of connection handlers and timers, it is cancelled after shutdown drain
+	ctx         context.Context
+	cancel      context.CancelFunc
+	connections sync.WaitGroup
+}
+
+//endregion
+
+//region FUNCTIONS
+
+func CreateServer(config ServerConfig) *Server {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	log := config.Log
+	if log == nil {
+		log = logrus.New()
+	}
+
+	return &Server{
+		Config:     config,
+		PlayerList: CreatePlayerList(log),
+		GameList:   CreateGameList(log),
+		Scheduler:  scheduler.CreateScheduler(ctx, config.Settings.Debug, log),
+		Events:     CreateEventBus(),
+		Metrics:    metrics.CreateServerMetrics(),
+		Journal: CreateMessageJournal(JournalConfig{
+			MaxMessages: config.JournalMaxMessages,
+			Retention:   config.JournalRetention,
+			Log:         log,
+		}),
+		Log:               log,
+		ConnectionWriters: CreateConnectionWriterList(),
+		settings:          config.Settings,
+		ctx:               ctx,
+		cancel:            cancel,
+	}
+}
+
+//endregion
+
+//region LIFECYCLE
+
+// Context is done once the server has stopped, connection handlers and timers end with it
+func (s *Server) Context() context.Context {
+	return s.ctx
+}
+
+// Cancel stops connection handlers and timers
+func (s *Server) Cancel() {
+	s.cancel()
+}
+
+func (s *Server) SetShuttingDown() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.isShuttingDown = true
+}
+
+// IsShuttingDown returns true after shutdown has started, no new turns are started then
+func (s *Server) IsShuttingDown() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.isShuttingDown
+}
+
+// SetMaintenance switches maintenance mode, in maintenance no new players log in and no new games are created
+func (s *Server) SetMaintenance(isMaintenance bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.isMaintenance = isMaintenance
+}
+
+func (s *Server) IsMaintenance() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.isMaintenance
+}
+
+// SetAccepting is called by the accept loop of the server listener when it starts and stops
+func (s *Server) SetAccepting(isAccepting bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.isAccepting = isAccepting
+}
+
+// IsAccepting returns true while the accept loop of the server listener runs
+func (s *Server) IsAccepting() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.isAccepting
+}
+
+// NextSessionID returns ID of a new connection handler, IDs are unique within the server
+func (s *Server) NextSessionID() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.lastSessionID++
+	return s.lastSessionID
+}
+
+// AddConnection registers running connection handler, it has to call DoneConnection when it returns
+func (s *Server) AddConnection() {
+	s.connections.Add(1)
+}
+
+func (s *Server) DoneConnection() {
+	s.connections.Done()
+}
+
+// WaitConnections waits for connection handlers to return
+// Return: false if timeout elapsed first
+func (s *Server) WaitConnections(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		s.connections.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+//endregion
+
+//region LISTENERS
+
+func (s *Server) SetListener(listener net.Listener) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.listener = listener
+}
+
+func (s *Server) SetWebSocketListener(listener net.Listener) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.webSocketListener = listener
+}
+
+func (s *Server) SetHTTPListener(listener net.Listener) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.httpListener = listener
+}
+
+func (s *Server) SetMetricsListener(listener net.Listener) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.metricsListener = listener
+}
+
+func (s *Server) SetHealthListener(listener net.Listener) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.healthListener = listener
+}
+
+func (s *Server) SetAdminListener(listener net.Listener) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.adminListener = listener
+}
+
+// GetSettings returns timeouts and limits of the server, they may change by reload, so they are read on every use
+func (s *Server) GetSettings() Settings {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.settings
+}
+
+// SetSettings replaces timeouts and limits of the running server, running games keep their GameRules
+func (s *Server) SetSettings(settings Settings) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.settings = settings
+}
+
+// GameRules returns rules of games created with these settings
+func (s Settings) GameRules() GameRules {
+	return GameRules{MaxScore: s.MaxScore, Debug: s.Debug}
+}
+
+func (s *Server) SetReloadHandler(handler ReloadHandler) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.reloadHandler = handler
+}
+
+// Reload reads configuration again by the reload handler, e.g. on SIGHUP or admin command
+func (s *Server) Reload() (ReloadResult, error) {
+	s.mutex.Lock()
+	handler := s.reloadHandler
+	s.mutex.Unlock()
+
+	if handler == nil {
+		return ReloadResult{}, fmt.Errorf("reload is not supported")
+	}
+	return handler()
+}
+
+func (s *Server) GetListener() net.Listener {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.listener
+}
+
+func (s *Server) GetWebSocketListener() net.Listener {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.webSocketListener
+}
+
+func (s *Server) GetHTTPListener() net.Listener {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.httpListener
+}
+
+func (s *Server) GetMetricsListener() net.Listener {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.metricsListener
+}
+
+func (s *Server) GetHealthListener() net.Listener {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.healthListener
+}
+
+func (s *Server) GetAdminListener() net.Listener {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.adminListener
+}
+
+// GetAddress returns address the server really listens on, nil before listening
+func (s *Server) GetAddress() net.Addr {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// GetWebSocketAddress returns address of WebSocket listener, nil if it is not running
+func (s *Server) GetWebSocketAddress() net.Addr {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.webSocketListener == nil {
+		return nil
+	}
+	return s.webSocketListener.Addr()
+}
+
+// Close closes listeners, so the server stops accepting new connections.
+// Metrics and health listeners stay open, so readiness is reported during shutdown drain, they are closed by CloseMetrics.
+func (s *Server) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var closeErr error
+	for _, listener := range []net.Listener{s.listener, s.webSocketListener, s.httpListener, s.adminListener} {
+		if listener == nil {
+			continue
+		}
+		err := listener.Close()
+		if err != nil && closeErr == nil {
+			closeErr = fmt.Errorf("error closing listener: %w", err)
+		}
+	}
+
+	return closeErr
+}
+
+// CloseMetrics closes metrics and health listeners after shutdown drain
+func (s *Server) CloseMetrics() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var closeErr error
+	for _, listener := range []net.Listener{s.metricsListener, s.healthListener} {
+		if listener == nil {
+			continue
+		}
+		err := listener.Close()
+		if err != nil && closeErr == nil {
+			closeErr = fmt.Errorf("error closing metrics listener: %w", err)
+		}
+	}
+
+	return closeErr
+}
+
+//endregion
+
+//region LOGGING
+
+// PlayerLog returns log entry with fields of the player: session, nickname, game, state machine state
+// and correlation ID of the message being processed
+func (s *Server) PlayerLog(player *Player) *logrus.Entry {
+	connectionInfo := player.GetConnectionInfo()
+
+	fields := logrus.Fields{
+		logger.FieldNickname: player.GetNickname(),
+		logger.FieldState:    player.GetCurrentStateName(),
+	}
+	if connectionInfo.SessionID != 0 {
+		fields[logger.FieldSessionID] = connectionInfo.SessionID
+	}
+	if connectionInfo.Connection != nil {
+		fields[logger.FieldRemoteAddress] = connectionInfo.Connection.RemoteAddr().String()
+	}
+	if game := s.GameList.GetPlayersGame(player); game != nil {
+		fields[logger.FieldGame] = game.GetName()
+	}
+	if correlationID := s.GetCorrelationID(player); correlationID != "" {
+		fields[logger.FieldCorrelationID] = correlationID
+	}
+
+	return s.Log.WithFields(fields)
+}
+
+// GetCorrelationID returns correlation ID for messages sent to the player. Broadcast of a game handler
+// carries ID of the message being handled by the game, otherwise it is ID of the player's own message.
+func (s *Server) GetCorrelationID(player *Player) string {
+	if game := s.GameList.GetPlayersGame(player); game != nil {
+		if correlationID := game.GetCorrelationID(); correlationID != "" {
+			return correlationID
+		}
+	}
+	return player.GetCorrelationID()
+}
+
+//endregion
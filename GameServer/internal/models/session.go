@@ -0,0 +1,143 @@
+package models
+
+import (
+	"gameserver/internal/logger"
+	"github.com/sirupsen/logrus"
+	"net"
+	"sync"
+	"time"
+)
+
+//region DATA STRUCTURES
+
+// Session belongs to one connection handler and remembers the player logged in on that connection,
+// so the handler never has to search PlayerList or GameList for it
+type Session struct {
+	id         int
+	server     *Server
+	connection net.Conn
+	player     *Player
+	// windowStart and windowMessages count messages of the client for rate limit
+	windowStart    time.Time
+	windowMessages int
+	mutex          sync.Mutex
+}
+
+//endregion
+
+//region FUNCTIONS
+
+// CreateSession creates session of a new connection and starts its journal, the handler has to end it by EndSession
+func CreateSession(server *Server, connection net.Conn) *Session {
+	session := &Session{
+		id:         server.NextSessionID(),
+		server:     server,
+		connection: connection,
+	}
+	server.Journal.StartSession(session.id, connection)
+
+	return session
+}
+
+// EndSession ends journal of the session, its messages are kept for retention
+func (s *Session) EndSession() {
+	s.server.Journal.EndSession(s.connection)
+}
+
+//endregion
+
+//region GETTERS
+
+func (s *Session) GetID() int {
+	return s.id
+}
+
+func (s *Session) GetServer() *Server {
+	return s.server
+}
+
+func (s *Session) GetConnection() net.Conn {
+	return s.connection
+}
+
+// GetPlayer returns player of the session, nil if nobody has logged in yet
+// or the player has reconnected on another connection
+func (s *Session) GetPlayer() *Player {
+	s.mutex.Lock()
+	player := s.player
+	s.mutex.Unlock()
+
+	if player == nil {
+		return nil
+	}
+	if player.GetConnectionInfo().Connection != s.connection {
+		return nil
+	}
+
+	return player
+}
+
+// GetGame returns current game of the session player, nil if player is not in a game
+func (s *Session) GetGame() *Game {
+	player := s.GetPlayer()
+	if player == nil {
+		return nil
+	}
+
+	return s.server.GameList.GetPlayersGame(player)
+}
+
+//endregion
+
+//region RATE LIMIT
+
+// AllowMessage counts message of the client, it returns false when more than limit messages came within window,
+// limit 0 disables the limit
+func (s *Session) AllowMessage(limit int, window time.Duration) bool {
+	if limit <= 0 {
+		return true
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.windowStart) >= window {
+		s.windowStart = now
+		s.windowMessages = 0
+	}
+	s.windowMessages++
+
+	return s.windowMessages <= limit
+}
+
+//endregion
+
+//region LOGGING
+
+// Log returns log entry with fields of the session, with player fields once somebody has logged in
+func (s *Session) Log() *logrus.Entry {
+	player := s.GetPlayer()
+	if player != nil {
+		return s.server.PlayerLog(player)
+	}
+
+	entry := s.server.Log.WithField(logger.FieldSessionID, s.id)
+	if s.connection != nil {
+		entry = entry.WithField(logger.FieldRemoteAddress, s.connection.RemoteAddr().String())
+	}
+	return entry
+}
+
+//endregion
+
+//region SETTERS
+
+func (s *Session) SetPlayer(player *Player) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.player = player
+}
+
+//endregion
@@ -0,0 +1,366 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gameserver/internal/models/state_machine"
+	"github.com/sirupsen/logrus"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+//region DATA STRUCTURES
+
+// Snapshot is serializable copy of all players and games of the server
+type Snapshot struct {
+	CreatedAt time.Time        `json:"created_at"`
+	Players   []PlayerSnapshot `json:"players"`
+	Games     []GameSnapshot   `json:"games"`
+}
+
+type PlayerSnapshot struct {
+	Nickname string `json:"nickname"`
+	State    string `json:"state"`
+}
+
+type GameSnapshot struct {
+	Name       string                   `json:"name"`
+	MaxPlayers int                      `json:"max_players"`
+	MaxScore   int                      `json:"max_score,omitempty"` // rule of the game, missing in old snapshots
+	TurnCount  int                      `json:"turn_count"`
+	State      GameState                `json:"state"`
+	Players    []PlayerGameDataSnapshot `json:"players"`
+}
+
+// PlayerGameDataSnapshot is seat of player in game, seats keep order of players
+type PlayerGameDataSnapshot struct {
+	Nickname    string         `json:"nickname"`
+	Score       int            `json:"score"`
+	TurnHistory []TurnSnapshot `json:"turn_history"`
+}
+
+type TurnSnapshot struct {
+	Throws []ThrowSnapshot `json:"throws"`
+}
+
+type ThrowSnapshot struct {
+	CubeValues          []int `json:"cube_values"`
+	SelectedCubesValues []int `json:"selected_cubes_values"`
+}
+
+//endregion
+
+//region CREATE
+
+// CreateSnapshot copies all players and games, every game is copied on its own goroutine so no turn is half done
+func (s *Server) CreateSnapshot() Snapshot {
+	snapshot := Snapshot{
+		CreatedAt: time.Now(),
+		Players:   []PlayerSnapshot{},
+		Games:     []GameSnapshot{},
+	}
+
+	for _, player := range s.PlayerList.GetValuesArray() {
+		snapshot.Players = append(snapshot.Players, PlayerSnapshot{
+			Nickname: player.GetNickname(),
+			State:    player.GetCurrentStateName(),
+		})
+	}
+
+	for _, game := range s.GameList.GetValuesArray() {
+		var gameSnapshot GameSnapshot
+		err := game.Execute(func() error {
+			gameSnapshot = game.CreateSnapshot()
+			return nil
+		})
+		// game removed meanwhile is not saved
+		if err != nil {
+			continue
+		}
+		snapshot.Games = append(snapshot.Games, gameSnapshot)
+	}
+
+	return snapshot
+}
+
+// SaveSnapshot creates snapshot and writes it to filePath, saves running at once wait for each other,
+// so the last written snapshot is always the newest one
+func (s *Server) SaveSnapshot(filePath string) error {
+	s.snapshotMutex.Lock()
+	defer s.snapshotMutex.Unlock()
+
+	return WriteSnapshotFile(filePath, s.CreateSnapshot())
+}
+
+// CreateSnapshot copies game with turn history of every player, it has to run on the game goroutine
+func (g *Game) CreateSnapshot() GameSnapshot {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	gameSnapshot := GameSnapshot{
+		Name:       g.name,
+		MaxPlayers: g.maxPlayers,
+		MaxScore:   g.rules.MaxScore,
+		TurnCount:  g.turnCount,
+		State:      g.gameStateValue,
+		Players:    []PlayerGameDataSnapshot{},
+	}
+
+	for _, playerGameData := range g.playersGameDataArr {
+		seat := PlayerGameDataSnapshot{
+			Nickname:    playerGameData.Player.GetNickname(),
+			Score:       playerGameData.Score,
+			TurnHistory: []TurnSnapshot{},
+		}
+		for _, turn := range playerGameData.TurnHistory {
+			turnSnapshot := TurnSnapshot{Throws: []ThrowSnapshot{}}
+			for _, throw := range turn.ThrowArr {
+				turnSnapshot.Throws = append(turnSnapshot.Throws, ThrowSnapshot{
+					CubeValues:          append([]int{}, throw.cubeValues...),
+					SelectedCubesValues: append([]int{}, throw.selectedCubesValues...),
+				})
+			}
+			seat.TurnHistory = append(seat.TurnHistory, turnSnapshot)
+		}
+		gameSnapshot.Players = append(gameSnapshot.Players, seat)
+	}
+
+	return gameSnapshot
+}
+
+//endregion
+
+//region RESTORE
+
+// RestoreSnapshot fills empty server with players and games from snapshot.
+// Invalid players and games are skipped and logged, so one corrupt entry does not stop the server,
+// players seated in a skipped game are skipped too. Game seating a player who is already seated in another game is invalid.
+// Restored players are disconnected, caller has to start their reconnect window.
+// Return: restored players and number of restored games
+func (s *Server) RestoreSnapshot(snapshot Snapshot) ([]*Player, int, error) {
+	if len(s.PlayerList.GetValuesArray()) != 0 || len(s.GameList.GetValuesArray()) != 0 {
+		return nil, 0, fmt.Errorf("server is not empty")
+	}
+
+	players := make(map[string]*Player)
+	for _, playerSnapshot := range snapshot.Players {
+		if _, ok := players[playerSnapshot.Nickname]; ok {
+			s.Log.Warnf("SNAPSHOT: Skipped player %s: duplicate nickname", playerSnapshot.Nickname)
+			continue
+		}
+
+		player, err := restorePlayer(playerSnapshot, s.GetSettings().NameLength, s.Log)
+		if err != nil {
+			s.Log.Warnf("SNAPSHOT: Skipped player %s: %v", playerSnapshot.Nickname, err)
+			continue
+		}
+		players[playerSnapshot.Nickname] = player
+	}
+
+	// seated are players of restored games, rejected are players of skipped games
+	seated := make(map[string]string)
+	rejected := make(map[string]bool)
+	gameCount := 0
+	for _, gameSnapshot := range snapshot.Games {
+		game, err := s.restoreSnapshotGame(gameSnapshot, players, seated)
+		if err != nil {
+			s.Log.Warnf("SNAPSHOT: Skipped game %s: %v", gameSnapshot.Name, err)
+			for _, seat := range gameSnapshot.Players {
+				rejected[seat.Nickname] = true
+			}
+			continue
+		}
+
+		for _, seat := range gameSnapshot.Players {
+			seated[seat.Nickname] = game.GetName()
+		}
+		gameCount++
+	}
+
+	var restored []*Player
+	for _, playerSnapshot := range snapshot.Players {
+		player, ok := players[playerSnapshot.Nickname]
+		if !ok || s.PlayerList.HasValue(player) {
+			continue
+		}
+		if _, isSeated := seated[playerSnapshot.Nickname]; rejected[playerSnapshot.Nickname] && !isSeated {
+			s.Log.Warnf("SNAPSHOT: Skipped player %s: his game was skipped", playerSnapshot.Nickname)
+			continue
+		}
+
+		err := s.PlayerList.AddItem(player)
+		if err != nil {
+			s.Log.Warnf("SNAPSHOT: Skipped player %s: %v", playerSnapshot.Nickname, err)
+			continue
+		}
+		restored = append(restored, player)
+	}
+
+	return restored, gameCount, nil
+}
+
+// restoreSnapshotGame restores game and adds it to the game list, seated maps nicknames to games they are seated in
+func (s *Server) restoreSnapshotGame(gameSnapshot GameSnapshot, players map[string]*Player, seated map[string]string) (*Game, error) {
+	seats := make(map[string]bool)
+	for _, seat := range gameSnapshot.Players {
+		if gameName, ok := seated[seat.Nickname]; ok {
+			return nil, fmt.Errorf("player %s is already seated in game %s", seat.Nickname, gameName)
+		}
+		if seats[seat.Nickname] {
+			return nil, fmt.Errorf("player %s is seated twice", seat.Nickname)
+		}
+		seats[seat.Nickname] = true
+	}
+
+	game, err := restoreGame(gameSnapshot, players, s.GetSettings().GameRules(), s.Log)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.GameList.AddItem(game)
+	if err != nil {
+		game.Stop()
+		return nil, err
+	}
+
+	return game, nil
+}
+
+func restorePlayer(playerSnapshot PlayerSnapshot, nameLength NameLength, log *logrus.Logger) (*Player, error) {
+	if !nameLength.IsValidName(playerSnapshot.Nickname) {
+		return nil, fmt.Errorf("invalid nickname")
+	}
+
+	stateMachine, err := state_machine.CreateStateMachineInState(playerSnapshot.State)
+	if err != nil {
+		return nil, err
+	}
+
+	player := CreatePlayer(playerSnapshot.Nickname, ConnectionInfo{}, log)
+	player.stateMachine = stateMachine
+	player.connectionState = ConnectionStates.Disconnected
+
+	return player, nil
+}
+
+// restoreGame creates game of snapshot, defaultRules are used for rules missing in the snapshot, log is logger of the server
+func restoreGame(gameSnapshot GameSnapshot, players map[string]*Player, defaultRules GameRules, log *logrus.Logger) (*Game, error) {
+	if len(gameSnapshot.Players) > gameSnapshot.MaxPlayers {
+		return nil, fmt.Errorf("more players than max players")
+	}
+	if gameSnapshot.TurnCount < 0 {
+		return nil, fmt.Errorf("negative turn count %d", gameSnapshot.TurnCount)
+	}
+	switch gameSnapshot.State {
+	case Created, Ended:
+	case Running:
+		if len(gameSnapshot.Players) < cMinimumPlayers {
+			return nil, fmt.Errorf("running game has fewer than %d players", cMinimumPlayers)
+		}
+	default:
+		return nil, fmt.Errorf("invalid state %d", gameSnapshot.State)
+	}
+
+	rules := defaultRules
+	if gameSnapshot.MaxScore > 0 {
+		rules.MaxScore = gameSnapshot.MaxScore
+	}
+
+	game, err := CreateGame(gameSnapshot.Name, gameSnapshot.MaxPlayers, rules, log)
+	if err != nil {
+		return nil, err
+	}
+
+	game.turnCount = gameSnapshot.TurnCount
+	game.gameStateValue = gameSnapshot.State
+
+	for _, seat := range gameSnapshot.Players {
+		player, ok := players[seat.Nickname]
+		if !ok {
+			game.Stop()
+			return nil, fmt.Errorf("player %s not found", seat.Nickname)
+		}
+
+		playerGameData := PlayerGameData{
+			Player:      player,
+			Score:       seat.Score,
+			TurnHistory: make([]Turn, 0),
+		}
+		for _, turnSnapshot := range seat.TurnHistory {
+			turn := Turn{}
+			for _, throwSnapshot := range turnSnapshot.Throws {
+				turn.ThrowArr = append(turn.ThrowArr, Throw{
+					cubeValues:          throwSnapshot.CubeValues,
+					selectedCubesValues: throwSnapshot.SelectedCubesValues,
+				})
+			}
+			playerGameData.TurnHistory = append(playerGameData.TurnHistory, turn)
+		}
+		game.playersGameDataArr = append(game.playersGameDataArr, playerGameData)
+	}
+
+	return game, nil
+}
+
+//endregion
+
+//region FILE
+
+// ErrSnapshotCorrupt is returned for snapshot file which cannot be decoded
+var ErrSnapshotCorrupt = errors.New("snapshot file is corrupt")
+
+// WriteSnapshotFile writes snapshot to temporary file and renames it, so a crash never leaves half written snapshot
+func WriteSnapshotFile(filePath string, snapshot Snapshot) error {
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return fmt.Errorf("cannot encode snapshot: %w", err)
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(filePath), filepath.Base(filePath)+".tmp")
+	if err != nil {
+		return fmt.Errorf("cannot create snapshot file: %w", err)
+	}
+	tmpPath := tmpFile.Name()
+
+	_, err = tmpFile.Write(data)
+	if err == nil {
+		err = tmpFile.Sync()
+	}
+	closeErr := tmpFile.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, filePath)
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("cannot write snapshot file: %w", err)
+	}
+
+	return nil
+}
+
+// ReadSnapshotFile reads snapshot written by WriteSnapshotFile
+// Return: false if there is no snapshot file
+func ReadSnapshotFile(filePath string) (Snapshot, bool, error) {
+	data, err := os.ReadFile(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return Snapshot{}, false, nil
+	}
+	if err != nil {
+		return Snapshot{}, false, fmt.Errorf("cannot read snapshot file: %w", err)
+	}
+
+	var snapshot Snapshot
+	err = json.Unmarshal(data, &snapshot)
+	if err != nil {
+		return Snapshot{}, false, fmt.Errorf("%w: %v", ErrSnapshotCorrupt, err)
+	}
+
+	return snapshot, true, nil
+}
+
+//endregion
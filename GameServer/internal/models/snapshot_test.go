@@ -0,0 +1,129 @@
+package models
+
+import (
+	"github.com/sirupsen/logrus"
+	"io"
+	"strings"
+	"testing"
+)
+
+//region HELPERS
+
+func createTestLog() *logrus.Logger {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	return log
+}
+
+func createTestPlayers(log *logrus.Logger, nicknames ...string) map[string]*Player {
+	players := make(map[string]*Player)
+	for _, nickname := range nicknames {
+		players[nickname] = CreatePlayer(nickname, ConnectionInfo{}, log)
+	}
+	return players
+}
+
+func createTestGameSnapshot(state GameState, turnCount int, nicknames ...string) GameSnapshot {
+	gameSnapshot := GameSnapshot{
+		Name:       "game",
+		MaxPlayers: 3,
+		TurnCount:  turnCount,
+		State:      state,
+		Players:    []PlayerGameDataSnapshot{},
+	}
+	for _, nickname := range nicknames {
+		gameSnapshot.Players = append(gameSnapshot.Players, PlayerGameDataSnapshot{Nickname: nickname})
+	}
+	return gameSnapshot
+}
+
+//endregion
+
+//region TESTS
+
+func TestRestoreGame(t *testing.T) {
+	tests := []struct {
+		name         string
+		gameSnapshot GameSnapshot
+		// expectedError is part of the error, empty when the game is restored
+		expectedError string
+	}{
+		{"created game", createTestGameSnapshot(Created, 0, "alice"), ""},
+		{"running game", createTestGameSnapshot(Running, 5, "alice", "bob"), ""},
+		{"ended game", createTestGameSnapshot(Ended, 9, "alice", "bob"), ""},
+		{"negative turn count", createTestGameSnapshot(Running, -1, "alice", "bob"), "negative turn count"},
+		{"unknown state", createTestGameSnapshot(GameState(7), 0, "alice"), "invalid state"},
+		{"negative state", createTestGameSnapshot(GameState(-1), 0, "alice"), "invalid state"},
+		{"running game with one player", createTestGameSnapshot(Running, 1, "alice"), "fewer than"},
+		{"running game without players", createTestGameSnapshot(Running, 0), "fewer than"},
+		{"more players than max players", createTestGameSnapshot(Created, 0, "alice", "bob", "carol", "dave"), "max players"},
+		{"unknown player", createTestGameSnapshot(Running, 0, "alice", "mallory"), "not found"},
+	}
+
+	log := createTestLog()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			players := createTestPlayers(log, "alice", "bob", "carol", "dave")
+
+			game, err := restoreGame(test.gameSnapshot, players, GameRules{MaxScore: 100}, log)
+			if test.expectedError != "" {
+				if err == nil {
+					game.Stop()
+					t.Fatalf("expected error containing %q, game was restored", test.expectedError)
+				}
+				if !strings.Contains(err.Error(), test.expectedError) {
+					t.Fatalf("expected error containing %q, got %v", test.expectedError, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected restored game, got %v", err)
+			}
+			defer game.Stop()
+			if game.turnCount != test.gameSnapshot.TurnCount || game.gameStateValue != test.gameSnapshot.State {
+				t.Fatalf("expected turn %d and state %v, got %d and %v", test.gameSnapshot.TurnCount, test.gameSnapshot.State, game.turnCount, game.gameStateValue)
+			}
+			if len(game.GetPlayers()) != len(test.gameSnapshot.Players) {
+				t.Fatalf("expected %d players, got %d", len(test.gameSnapshot.Players), len(game.GetPlayers()))
+			}
+		})
+	}
+}
+
+func TestRestoreSnapshotSkipsInvalidGameAndItsPlayers(t *testing.T) {
+	server := CreateServer(ServerConfig{
+		Settings: Settings{NameLength: NameLength{MinChars: 3, MaxChars: 20}},
+		Log:      createTestLog(),
+	})
+	defer func() {
+		for _, game := range server.GameList.GetValuesArray() {
+			game.Stop()
+		}
+		server.Cancel()
+	}()
+
+	invalid := createTestGameSnapshot(Running, 2, "carol")
+	invalid.Name = "invalid"
+	snapshot := Snapshot{
+		Players: []PlayerSnapshot{
+			{Nickname: "alice", State: "Running_Game"},
+			{Nickname: "bob", State: "Running_Game"},
+			{Nickname: "carol", State: "Running_Game"},
+		},
+		Games: []GameSnapshot{createTestGameSnapshot(Running, 2, "alice", "bob"), invalid},
+	}
+
+	players, gameCount, err := server.RestoreSnapshot(snapshot)
+	if err != nil {
+		t.Fatalf("cannot restore snapshot: %v", err)
+	}
+	if gameCount != 1 || server.GameList.HasItemName("invalid") {
+		t.Fatalf("expected only the valid game, got %d games", gameCount)
+	}
+	if len(players) != 2 || server.PlayerList.HasItemName("carol") {
+		t.Fatalf("expected players of the valid game only, got %d players", len(players))
+	}
+}
+
+//endregion
@@ -1,8 +1,10 @@
 package state_machine
 
 import (
+	"fmt"
 	"gameserver/internal/utils/constants"
 	"gameserver/pkg/stateless"
+	"reflect"
 )
 
 
@@ -52,6 +54,27 @@ func CreateStateMachine() *stateless.StateMachine {
 	return stateMachine
 }
 
+// CreateStateMachineInState creates state machine which starts in state with stateName, used when restoring players
+func CreateStateMachineInState(stateName string) (*stateless.StateMachine, error) {
+	if !isValidStateName(stateName) {
+		return nil, fmt.Errorf("unknown state %q", stateName)
+	}
+
+	stateMachine := stateless.NewStateMachine(stateless.State(stateName))
+	initilize(stateMachine)
+	return stateMachine, nil
+}
+
+func isValidStateName(stateName string) bool {
+	v := reflect.ValueOf(StateNameMap)
+	for i := 0; i < v.NumField(); i++ {
+		if v.Field(i).String() == stateName {
+			return true
+		}
+	}
+	return false
+}
+
 func initilize(stateMachine *stateless.StateMachine) {
 
 	stateMachine.Configure(stateStart).
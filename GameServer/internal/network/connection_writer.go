@@ -0,0 +1,292 @@
+package network
+
+import (
+	"fmt"
+	"gameserver/internal/logger"
+	"gameserver/internal/metrics"
+	"gameserver/internal/models"
+	"gameserver/internal/utils/constants"
+	"gameserver/internal/utils/errorHandeling"
+	"github.com/sirupsen/logrus"
+	"net"
+	"sync"
+	"time"
+)
+
+//region DATA STRUCTURES
+
+type outboundMessage struct {
+	message    models.Message
+	messageStr string
+	queuedAt   time.Time
+}
+
+// connectionWriter owns all writes to one connection, so a slow client never blocks the broadcasting goroutine
+type connectionWriter struct {
+	connection net.Conn
+	writers    *models.ConnectionWriterList
+	metrics    *metrics.ServerMetrics
+	journal    *models.MessageJournal
+	log        *logrus.Logger
+	// queueSize, queueFullPolicy and writeTimeout are taken from server settings when the writer is created
+	queueSize       int
+	queueFullPolicy string
+	writeTimeout    time.Duration
+	// isDebug lets panic of the writer crash the server, see Settings.Debug
+	isDebug   bool
+	queue     []outboundMessage
+	isClosing bool
+	// isClosed writer stays in writers of the server as tombstone until handler of the connection ends
+	isClosed    bool
+	isForgotten bool
+	mutex       sync.Mutex
+	signal      chan struct{}
+	done        chan struct{}
+}
+
+//endregion
+
+//region FUNCTIONS
+
+// getConnectionWriter returns writer of the connection, it starts one for the first message,
+// closed connection returns error instead of starting writer on it again
+func getConnectionWriter(connection net.Conn, server *models.Server) (*connectionWriter, error) {
+	found, isAdded := server.ConnectionWriters.GetOrAdd(connection, func() models.ConnectionWriter {
+		settings := server.GetSettings()
+		return &connectionWriter{
+			connection:      connection,
+			writers:         server.ConnectionWriters,
+			metrics:         server.Metrics,
+			journal:         server.Journal,
+			log:             server.Log,
+			queueSize:       settings.SendQueueSize,
+			queueFullPolicy: settings.QueueFullPolicy,
+			writeTimeout:    settings.WriteTimeout,
+			isDebug:         settings.Debug,
+			queue:           make([]outboundMessage, 0, settings.SendQueueSize),
+			signal:          make(chan struct{}, 1),
+			done:            make(chan struct{}),
+		}
+	})
+	writer := found.(*connectionWriter)
+
+	if isAdded {
+		go writer.run()
+		return writer, nil
+	}
+	if writer.IsClosed() {
+		return nil, fmt.Errorf("connection is closed")
+	}
+	return writer, nil
+}
+
+// findConnectionWriter returns writer of the connection, nil if it has none
+func findConnectionWriter(connection net.Conn, server *models.Server) *connectionWriter {
+	writer, ok := server.ConnectionWriters.Get(connection)
+	if !ok {
+		return nil
+	}
+	return writer.(*connectionWriter)
+}
+
+// addClosedConnection remembers connection closed without writer, so no writer is started on it later
+func addClosedConnection(connection net.Conn, server *models.Server) {
+	server.ConnectionWriters.GetOrAdd(connection, func() models.ConnectionWriter {
+		writer := &connectionWriter{
+			connection: connection,
+			writers:    server.ConnectionWriters,
+			log:        server.Log,
+			isClosing:  true,
+			isClosed:   true,
+			done:       make(chan struct{}),
+		}
+		close(writer.done)
+		return writer
+	})
+}
+
+// ForgetConnection is called when handler of the connection ends, it closes the writer
+// and removes it once it has flushed its queue
+func ForgetConnection(server *models.Server, connection net.Conn) {
+	server.ConnectionWriters.RemoveIf(connection, func(found models.ConnectionWriter) bool {
+		writer := found.(*connectionWriter)
+
+		writer.mutex.Lock()
+		defer writer.mutex.Unlock()
+
+		if writer.isClosed {
+			return true
+		}
+		writer.isForgotten = true
+		writer.isClosing = true
+		writer.notify()
+		return false
+	})
+}
+
+// isContinuousUpdate returns true for updates which are superseded by the next update of the same command
+func isContinuousUpdate(commandID int) bool {
+	switch commandID {
+	case constants.CGCommands.ServerUpdateGameList.CommandID,
+		constants.CGCommands.ServerUpdatePlayerList.CommandID,
+		constants.CGCommands.ServerUpdateGameData.CommandID:
+		return true
+	}
+	return false
+}
+
+// FlushConnections waits until writers of closing connections of the server have sent their queues
+// Return: false if deadline elapsed first
+func FlushConnections(server *models.Server, deadline time.Time) bool {
+	timeout := time.After(time.Until(deadline))
+	for _, writer := range server.ConnectionWriters.Values() {
+		select {
+		case <-writer.Done():
+		case <-timeout:
+			return false
+		}
+	}
+
+	return true
+}
+
+//endregion
+
+//region WRITER
+
+// enqueue adds message to the queue, it never blocks on the socket
+// Return: message dropped from the queue because of full queue policy
+func (w *connectionWriter) enqueue(message models.Message, messageStr string) (*models.Message, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.isClosing {
+		return nil, fmt.Errorf("connection is closing")
+	}
+
+	item := outboundMessage{message: message, messageStr: messageStr, queuedAt: time.Now()}
+
+	if len(w.queue) >= w.queueSize {
+		if w.queueFullPolicy == constants.CQueueFullDisconnect || !isContinuousUpdate(message.CommandID) {
+			w.log.WithFields(message.LogFields()).Errorf("SEND_QUEUE: Queue full for %s, disconnecting", message.PlayerNickname)
+			w.isClosing = true
+			w.queue = nil
+			w.notify()
+			return nil, fmt.Errorf("send queue full")
+		}
+
+		for i, queued := range w.queue {
+			if queued.message.CommandID == message.CommandID {
+				w.log.WithFields(message.LogFields()).Infof("SEND_QUEUE: Queue full for %s, replacing queued %s", message.PlayerNickname, constants.GetCommandName(message.CommandID))
+				w.queue[i] = item
+				return &queued.message, nil
+			}
+		}
+
+		w.log.WithFields(message.LogFields()).Infof("SEND_QUEUE: Queue full for %s, dropping %s", message.PlayerNickname, constants.GetCommandName(message.CommandID))
+		return &message, nil
+	}
+
+	w.queue = append(w.queue, item)
+	w.notify()
+
+	return nil, nil
+}
+
+// Done is closed once the writer has closed the connection
+func (w *connectionWriter) Done() <-chan struct{} {
+	return w.done
+}
+
+// IsClosed returns true once the writer has closed the connection
+func (w *connectionWriter) IsClosed() bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.isClosed
+}
+
+// close lets the writer flush already queued messages and then closes the connection
+func (w *connectionWriter) close() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.isClosing = true
+	w.notify()
+}
+
+func (w *connectionWriter) notify() {
+	select {
+	case w.signal <- struct{}{}:
+	default:
+	}
+}
+
+// run writes queued messages until the connection is closing, panic of the writer closes the connection
+func (w *connectionWriter) run() {
+	defer w.finish()
+	defer errorHandeling.RecoverPanic(w.log, w.isDebug, nil)
+
+	for range w.signal {
+		w.mutex.Lock()
+		queue := w.queue
+		w.queue = make([]outboundMessage, 0, w.queueSize)
+		isClosing := w.isClosing
+		w.mutex.Unlock()
+
+		for _, item := range queue {
+			err := w.write(item)
+			if err != nil {
+				errorHandeling.LogError(w.log.WithFields(item.message.LogFields()), fmt.Errorf("error writing to %s: %w", item.message.PlayerNickname, err))
+				isClosing = true
+				break
+			}
+		}
+
+		if isClosing {
+			return
+		}
+	}
+}
+
+// finish closes the connection, tombstone is kept until the handler forgets the connection
+func (w *connectionWriter) finish() {
+	defer close(w.done)
+
+	w.mutex.Lock()
+	w.isClosing = true
+	w.isClosed = true
+	w.queue = nil
+	isForgotten := w.isForgotten
+	w.mutex.Unlock()
+	if isForgotten {
+		w.writers.RemoveIf(w.connection, func(found models.ConnectionWriter) bool {
+			return found == w
+		})
+	}
+
+	err := w.connection.Close()
+	if err != nil {
+		errorHandeling.PrintError(w.log, err)
+	}
+	w.log.WithField(logger.FieldRemoteAddress, w.connection.RemoteAddr().String()).Info("Connection closed")
+}
+
+func (w *connectionWriter) write(item outboundMessage) error {
+	err := w.connection.SetWriteDeadline(time.Now().Add(w.writeTimeout))
+	if err != nil {
+		return fmt.Errorf("error setting write deadline: %w", err)
+	}
+
+	_, err = w.connection.Write([]byte(item.messageStr))
+	if err != nil {
+		return err
+	}
+
+	w.journal.Record(w.connection, models.Send, item.message, item.messageStr)
+	metrics.ObserveSince(w.metrics.SendDuration, item.queuedAt)
+
+	return nil
+}
+
+//endregion
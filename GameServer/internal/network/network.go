@@ -4,14 +4,15 @@ import (
 	"errors"
 	"fmt"
 	"gameserver/internal/logger"
+	"gameserver/internal/metrics"
 	"gameserver/internal/models"
 	"gameserver/internal/models/state_machine"
-	"gameserver/internal/network/network_utils"
 	"gameserver/internal/parser"
 	"gameserver/internal/utils/constants"
 	"gameserver/internal/utils/errorHandeling"
 	"gameserver/internal/utils/helpers"
 	"net"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -29,22 +30,28 @@ const (
 )
 
 // region PRIVATE SHARED WITH - SERVER_LISTEN
-func CloseConnection(connection net.Conn) error {
+func CloseConnection(server *models.Server, connection net.Conn) error {
+	// writer flushes queued messages (e.g. the error response) before closing
+	writer := findConnectionWriter(connection, server)
+	if writer != nil {
+		writer.close()
+		return nil
+	}
+
+	addClosedConnection(connection, server)
 	err := connection.Close()
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("error closing connection %w", err)
 	}
-	errorHandeling.PrintError(fmt.Errorf("Connection closed"))
-
-	logger.Log.Info("Connection closed")
+	server.Log.WithField(logger.FieldRemoteAddress, connection.RemoteAddr().String()).Info("Connection closed")
 	return nil
 }
 
-func Read(connection net.Conn) ([]models.Message, bool, error) {
-	messageList, isTimeout, err := connectionReadTimeout(connection)
+func Read(server *models.Server, connection net.Conn) ([]models.Message, bool, error) {
+	messageList, isTimeout, err := connectionReadTimeout(server, connection)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return []models.Message{}, isTimeout, fmt.Errorf("error reading %w", err)
 	}
 
@@ -55,117 +62,224 @@ func Read(connection net.Conn) ([]models.Message, bool, error) {
 
 // region PRIVATE FUNCTIONS
 
-func sendResponseServerSelectCubes(command constants.Command, values []int, responseInfo models.MessageInfo) error {
+func sendResponseServerSelectCubes(server *models.Server, command constants.Command, values []int, responseInfo models.MessageInfo) error {
 	paramsValue := parser.ConvertListCubeValuesToNetworkString(values)
 	params, err := models.CreateParams(command.ParamsNames, []string{paramsValue})
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return err
 	}
 
-	err = sendMessageWrapper(responseInfo, command, params)
+	err = sendMessageWrapper(server, responseInfo, command, params)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("error sending response %w", err)
 	}
 
 	return nil
 }
 
-func sendMessageWrapper(messageInfo models.MessageInfo, command constants.Command, params []constants.Params) error {
-	_, err := sendMessage(messageInfo, command, params)
+func sendMessageWrapper(server *models.Server, messageInfo models.MessageInfo, command constants.Command, params []constants.Params) error {
+	_, err := sendMessage(server, messageInfo, command, params)
 	return err
 }
 
-func sendMessage(messageInfo models.MessageInfo, command constants.Command, params []constants.Params) (models.Message, error) {
+func sendMessage(server *models.Server, messageInfo models.MessageInfo, command constants.Command, params []constants.Params) (models.Message, error) {
 	connection := messageInfo.ConnectionInfo.Connection
 	playerName := messageInfo.PlayerNickname
 
 	//convert to message
 	message := models.CreateMessage(playerName, command.CommandID, params)
 
-	err := connectionWrite(connection, message)
+	err := connectionWrite(server, connection, message)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return models.Message{}, fmt.Errorf("error writing %w", err)
 	}
+
+	recordClientMessageResponse(server, message)
+
 	return message, nil
 }
 
-// sendMessageWithSuccessResponse
-// Return: bool isSuccess - if communication was successful
-func sendMessageWithSuccessResponse(player *models.Player, command constants.Command, params []constants.Params) error {
-	responseInfo := models.MessageInfo{
-		ConnectionInfo: player.GetConnectionInfo(),
-		PlayerNickname: player.GetNickname(),
+// recordClientMessageResponse caches response for client message ID, so a retried command gets the same answer
+func recordClientMessageResponse(server *models.Server, message models.Message) {
+	if !isResponseCommand(message.CommandID) {
+		return
 	}
 
-	message, err := sendMessage(responseInfo, command, params)
+	player, err := server.PlayerList.GetItem(message.PlayerNickname)
+	if err != nil || player == nil {
+		return
+	}
+
+	player.AddClientMessageResponse(message)
+}
+
+func isResponseCommand(commandID int) bool {
+	switch commandID {
+	case constants.CGCommands.ResponseServerSuccess.CommandID,
+		constants.CGCommands.ResponseServerError.CommandID,
+		constants.CGCommands.ResponseServerGameList.CommandID,
+		constants.CGCommands.ResponseServerSelectCubes.CommandID,
+		constants.CGCommands.ResponseServerEndTurn.CommandID,
+		constants.CGCommands.ResponseServerEndScore.CommandID,
+		constants.CGCommands.ResponseServerDiceSuccess.CommandID,
+		constants.CGCommands.ResponseServerReconnectBeforeGame.CommandID,
+		constants.CGCommands.ResponseServerReconnectRunningGame.CommandID:
+		return true
+	}
+	return false
+}
+
+// SendCachedResponses answers retried client message with responses sent to the first one
+func SendCachedResponses(server *models.Server, connection net.Conn, responses []models.Message) error {
+	for _, message := range responses {
+		err := connectionWrite(server, connection, message)
+		if err != nil {
+			errorHandeling.PrintError(server.Log, err)
+			return fmt.Errorf("error writing %w", err)
+		}
+	}
+	return nil
+}
+
+// isCriticalCommand returns true for messages which are sent only once and must not be lost
+func isCriticalCommand(commandID int) bool {
+	switch commandID {
+	case constants.CGCommands.ServerStartTurn.CommandID,
+		constants.CGCommands.ServerUpdateStartGame.CommandID,
+		constants.CGCommands.ServerUpdateEndScore.CommandID,
+		constants.CGCommands.ServerUpdateNotEnoughPlayers.CommandID:
+		return true
+	}
+	return false
+}
+
+// sendMessageWithSuccessResponse sends message which the client has to ack with ResponseClientSuccess
+func sendMessageWithSuccessResponse(server *models.Server, player *models.Player, command constants.Command, params []constants.Params) error {
+	connection := player.GetConnectionInfo().Connection
+	message := models.CreateMessage(player.GetNickname(), command.CommandID, params)
+
+	// registered before writing, so the ack can never arrive before the server expects it
+	player.IncreaseResponseSuccessExpected(&message, isCriticalCommand(command.CommandID), server.GetSettings().AckTimeout)
+
+	err := connectionWrite(server, connection, message)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		player.RemoveResponseSuccessExpected(message.SequenceNumber)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("error sending response %w", err)
 	}
 
-	//increase expected response
-	player.IncreaseResponseSuccessExpected(message)
-
-	//connection := responseInfo.ConnectionInfo.Connection
-	//
-	////wait for client response
-	//
-	//clientResponse, isTimeout, err := ReadSingleTimeout(connection)
-	//if err != nil {
-	//	errorHandeling.PrintError(err)
-	//	return false, fmt.Errorf("error reading %w", err)
-	//}
-	//if isTimeout {
-	//	err = DisconnectPlayerConnection(player)
-	//	if err != nil {
-	//		errorHandeling.PrintError(err)
-	//		return false, fmt.Errorf("error handling timeout %w", err)
-	//	}
-	//	return false, nil
-	//}
-	//
-	////check if client responded with success
-	//if !isClientResponseCommand(clientResponse, responseInfo.PlayerNickname, constants.CGCommands.ResponseClientSuccess) {
-	//	return false, _handleClienResponseNotSuccess(player)
-	//}
-	//
-	//return true, nil
+	scheduleResponseTimeout(server, player)
 
 	return nil
 }
 
-func sendUpdateList(player *models.Player, command constants.Command, params []constants.Params) error {
+func responseTimerKey(player *models.Player) string {
+	return "ack:" + player.GetNickname()
+}
+
+// scheduleResponseTimeout sets timer to the earliest deadline of messages waiting for ack
+func scheduleResponseTimeout(server *models.Server, player *models.Player) {
+	deadline, ok := player.GetNextResponseDeadline()
+	if !ok {
+		return
+	}
+
+	server.Scheduler.Schedule(responseTimerKey(player), time.Until(deadline), func() {
+		processResponseTimeout(server, player)
+	})
+}
+
+// CancelResponseTimeout stops ack timer of player whose session was replaced
+func CancelResponseTimeout(server *models.Server, player *models.Player) {
+	player.ResetResponseSuccessExpected()
+	server.Scheduler.Cancel(responseTimerKey(player))
+}
+
+func processResponseTimeout(server *models.Server, player *models.Player) {
+	if !player.IsConnected() {
+		return
+	}
+
+	isTimeout, err := ProcessResponseTimeouts(server, player)
+	if err != nil {
+		errorHandeling.PrintError(server.Log, err)
+	}
+
+	if isTimeout {
+		server.Metrics.AckTimeouts.Inc()
+	}
+	if isTimeout || err != nil {
+		err = server.GameList.ExecuteInPlayersGame(player, func() error {
+			return DisconnectPlayerConnection(server, player)
+		})
+		if err != nil {
+			errorHandeling.PrintError(server.Log, fmt.Errorf("Error disconnecting player: %w", err))
+		}
+		return
+	}
+
+	scheduleResponseTimeout(server, player)
+}
+
+// forgetDroppedMessage stops waiting for ack of message dropped from the send queue
+func forgetDroppedMessage(server *models.Server, message models.Message) {
+	if message.SequenceNumber == 0 {
+		return
+	}
+
+	player, err := server.PlayerList.GetItem(message.PlayerNickname)
+	if err != nil || player == nil {
+		return
+	}
+
+	player.RemoveResponseSuccessExpected(message.SequenceNumber)
+}
+
+// ProcessResponseTimeouts retransmits critical messages whose ack timed out
+// Return: bool isTimeout - if player should be disconnected
+func ProcessResponseTimeouts(server *models.Server, player *models.Player) (bool, error) {
+	settings := server.GetSettings()
+	retransmitList, isTimeout := player.GetExpiredResponses(settings.AckTimeout, settings.MaxRetransmits)
+	if isTimeout {
+		return true, nil
+	}
+
+	connection := player.GetConnectionInfo().Connection
+	for _, message := range retransmitList {
+		err := connectionWrite(server, connection, message)
+		if err != nil {
+			errorHandeling.PrintError(server.Log, err)
+			return false, fmt.Errorf("error retransmitting %w", err)
+		}
+	}
+
+	return false, nil
+}
+
+func sendUpdateList(server *models.Server, player *models.Player, command constants.Command, params []constants.Params) error {
 
 	canFire, err := player.GetStateMachine().CanFire(command.Trigger)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("error checking if can fire %w", err)
 	}
 	if !canFire {
 		return nil
 	}
 
-	responseInfo := models.MessageInfo{
-		ConnectionInfo: player.GetConnectionInfo(),
-		PlayerNickname: player.GetNickname(),
-	}
-
-	var message models.Message
-	message, err = sendMessage(responseInfo, command, params)
+	// ClientResponseSuccess is handled in server_listen.go
+	err = sendMessageWithSuccessResponse(server, player, command, params)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("error sending response %w", err)
 	}
 
-	// set for player expected ClientResponseSuccess which is handled in server_listen.go
-	player.IncreaseResponseSuccessExpected(message)
-
 	err = player.FireStateMachine(command.Trigger)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("error firing state machine %w", err)
 	}
 
@@ -173,35 +287,31 @@ func sendUpdateList(player *models.Player, command constants.Command, params []c
 }
 
 
-func DisconnectPlayerConnection(player *models.Player) error {
+func DisconnectPlayerConnection(server *models.Server, player *models.Player) error {
 	//commandError := constants.CGCommands.ErrorPlayerUnreachable
 	//err := player.FireStateMachine(commandError.Trigger)
 	//if err != nil {
-	//	errorHandeling.PrintError(err)
+	//	errorHandeling.PrintError(server.Log, err)
 	//	return fmt.Errorf("error firing state machine %w", err)
 	//}
 	player.SetConnectedByBool(false)
+	server.Metrics.Disconnects.Inc()
 
-	player.SetTotalDisconnectStartTime()
-	go processTotalDisconnect(player)
+	ScheduleTotalDisconnect(server, player)
 
 	//close connection
-	err := CloseConnection(player.GetConnectionInfo().Connection)
+	err := CloseConnection(server, player.GetConnectionInfo().Connection)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("error closing connection %w", err)
 	}
 
 	//region Send updates to all players
-	game := models.GetInstanceGameList().GetPlayersGame(player)
-	if game == nil {
-		return nil
-	}
-
-	err = SendGameUpdates(game)
+	game := server.GameList.GetPlayersGame(player)
+	err = server.Events.Publish(models.PlayerDisconnected{Player: player, Game: game})
 	if err != nil {
 		err = fmt.Errorf("Error sending game updates: %w", err)
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return err
 	}
 	//endregion
@@ -211,37 +321,42 @@ func DisconnectPlayerConnection(player *models.Player) error {
 	return nil
 }
 
-func totalDisconnect(player *models.Player) {
+func totalDisconnect(server *models.Server, player *models.Player) {
 
-	logger.Log.Infof("TOTAL_DISCONNECT: Player %s has not reconnected in time", player.GetNickname())
+	server.PlayerLog(player).Infof("TOTAL_DISCONNECT: Player %s has not reconnected in time", player.GetNickname())
 
 	//player havent reconnected in the wait -> total disconnect
-	playerFromList, err := models.GetInstancePlayerList().GetItem(player.GetNickname())
+	playerFromList, err := server.PlayerList.GetItem(player.GetNickname())
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return
 	}
 	if playerFromList == nil {
-		errorHandeling.AssertError(fmt.Errorf("error player is nil"))
+		errorHandeling.AssertError(server.Log, fmt.Errorf("error player is nil"))
 	}
 
 	playerFromList.SetConnected(models.ConnectionStates.TotalDisconnect)
+	server.Metrics.TotalDisconnects.Inc()
 
 	//remove player from game
-	game := models.GetInstanceGameList().GetPlayersGame(player)
+	game := server.GameList.GetPlayersGame(player)
 	if game == nil {
+		err = server.Events.Publish(models.PlayerDisconnected{Player: player, IsTotal: true})
+		if err != nil {
+			errorHandeling.PrintError(server.Log, err)
+		}
 		return
 	}
 
-	err = helpers.RemovePlayerFromLists(player)
+	err = helpers.RemovePlayerFromLists(server, player)
 
-	logger.Log.Infof("TOTAL_DISCONNECT: Player %s has not reconnected in time", player.GetNickname())
+	server.PlayerLog(player).Infof("TOTAL_DISCONNECT: Player %s has not reconnected in time", player.GetNickname())
 
 	//send updates
-	err = SendAllUpdates(game)
+	err = server.Events.Publish(models.PlayerDisconnected{Player: player, Game: game, IsTotal: true})
 	if err != nil {
 		err = fmt.Errorf("Error sending game updates: %w", err)
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return
 	}
 
@@ -254,49 +369,76 @@ func totalDisconnect(player *models.Player) {
 		//ServerUpdateNotEnoughPlayers
 		playerList := game.GetPlayers()
 		playerList = helpers.PlayerListGetActivePlayers(playerList)
-		err = CommunicationServerUpdateNotEnoughPlayers(playerList)
+		err = CommunicationServerUpdateNotEnoughPlayers(server, playerList)
 		if err != nil {
-			errorHandeling.PrintError(err)
+			errorHandeling.PrintError(server.Log, err)
 			return
 		}
 
 		//remove game
-		err = models.GetInstanceGameList().RemoveItem(game)
+		err = server.GameList.RemoveItem(game)
 		if err != nil {
-			errorHandeling.PrintError(err)
+			errorHandeling.PrintError(server.Log, err)
 			return
 		}
 	}
 }
 
-func processTotalDisconnect(player *models.Player) {
-	time.Sleep(constants.CTotalDisconnectTime)
+func totalDisconnectTimerKey(player *models.Player) string {
+	return "disconnect:" + player.GetNickname()
+}
+
+// ScheduleTotalDisconnect starts reconnect window of disconnected player, player is removed if it does not reconnect in time
+func ScheduleTotalDisconnect(server *models.Server, player *models.Player) {
+	player.SetTotalDisconnectStartTime()
+	server.Scheduler.Schedule(totalDisconnectTimerKey(player), server.GetSettings().ReconnectWindow, func() {
+		processTotalDisconnect(server, player)
+	})
+}
 
-	if player.IsTotalDisconnectTimeout() {
+// CancelTotalDisconnect stops waiting for total disconnect of reconnected player
+func CancelTotalDisconnect(server *models.Server, player *models.Player) {
+	player.NullifyTotalDisconnectTime()
+	server.Scheduler.Cancel(totalDisconnectTimerKey(player))
+}
+
+func processTotalDisconnect(server *models.Server, player *models.Player) {
+	if server.Context().Err() != nil {
+		return
+	}
+
+	if player.IsTotalDisconnectTimeout(server.GetSettings().ReconnectWindow) {
 		if player.WasTotalDisconnecTimeoutCalled() {
 			return
 		}
 
-		ImidiateDisconnectPlayer(player.GetNickname())
+		err := server.GameList.ExecuteInPlayersGame(player, func() error {
+			ImidiateDisconnectPlayer(server, player.GetNickname())
+			return nil
+		})
+		if err != nil {
+			errorHandeling.PrintError(server.Log, err)
+		}
 	}
 	//logger.Log.Error("Total disconnect: from timeout")
-	//totalDisconnect(player)
+	//totalDisconnect(server, player)
 }
 
-func connectionReadTimeout(connection net.Conn) ([]models.Message, bool, error) {
+func connectionReadTimeout(server *models.Server, connection net.Conn) ([]models.Message, bool, error) {
 	isTimeout := false
-	timeout := constants.CTimeout
+	settings := server.GetSettings()
+	timeout := settings.AckTimeout
 	// Set the timeout
 	deadline := time.Now().Add(timeout)
 	err := connection.SetReadDeadline(deadline)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return []models.Message{}, isTimeout, fmt.Errorf("error setting read deadline: %w", err)
 	}
 
-	buffer := make([]byte, constants.CMessageBufferSize)
+	buffer := make([]byte, settings.MessageBufferSize)
 	messageStr := ""
-	maxMessageLength := constants.CMessageMaxSize // Define the maximum messageList length
+	maxMessageLength := settings.MaxMessageSize // Define the maximum messageList length
 
 	for {
 		_, err = connection.Read(buffer)
@@ -326,31 +468,50 @@ func connectionReadTimeout(connection net.Conn) ([]models.Message, bool, error)
 	messageList, err := parser.ParseReceiveMessageStr(messageStr)
 	if err != nil {
 		//if error when reading client message
-		logger.Log.Errorf("TOTAL_DISCONNECT: Wrong format message")
-		ImidiateDisconnectPlayerByConnection(connection)
+		server.Metrics.ParseErrors.Inc()
+		server.Log.Errorf("TOTAL_DISCONNECT: Wrong format message: %v", err)
+		ImidiateDisconnectPlayerByConnection(server, connection)
 	}
 
-	//Save to logger
-	for _, message := range messageList {
-		network_utils.GReceivedMessageList.AddItem(message)
+	//Save to journal, every received message starts its own correlation
+	for i := range messageList {
+		messageList[i].CorrelationID = logger.NewCorrelationID()
+		message := messageList[i]
+		raw, _ := parser.ConvertMessageToNetworkString(message)
+		server.Journal.Record(connection, models.Received, message, raw)
+		server.Metrics.MessagesReceived.Inc(metrics.CommandLabels(message.CommandID)...)
 	}
 
 	return messageList, false, nil
 }
 
-func connectionWrite(connection net.Conn, message models.Message) error {
+func connectionWrite(server *models.Server, connection net.Conn, message models.Message) error {
 	messageStr, err := parser.ConvertMessageToNetworkString(message)
 	if err != nil {
-		errorHandeling.AssertError(fmt.Errorf("error converting message to network string"))
+		errorHandeling.AssertError(server.Log, fmt.Errorf("error converting message to network string: %w", err))
+	}
+
+	// player with the nickname may be on another connection when login is refused
+	player, _ := server.PlayerList.GetItem(message.PlayerNickname)
+	if player != nil && player.GetConnectionInfo().Connection == connection {
+		message.CorrelationID = server.GetCorrelationID(player)
 	}
 
-	_, err = connection.Write([]byte(messageStr))
+	writer, err := getConnectionWriter(connection, server)
 	if err != nil {
-		errorHandeling.PrintError(err)
-		return fmt.Errorf("error writing", err)
+		errorHandeling.PrintError(server.Log, err)
+		return fmt.Errorf("error writing %w", err)
 	}
 
-	network_utils.GSendMessageList.AddItem(message)
+	dropped, err := writer.enqueue(message, messageStr)
+	if dropped != nil {
+		forgetDroppedMessage(server, *dropped)
+	}
+	if err != nil {
+		errorHandeling.PrintError(server.Log, err)
+		return fmt.Errorf("error writing %w", err)
+	}
+	server.Metrics.MessagesSent.Inc(metrics.CommandLabels(message.CommandID)...)
 
 	return nil
 }
@@ -359,205 +520,195 @@ func connectionWrite(connection net.Conn, message models.Message) error {
 //endregion
 
 // region SEND RESPONSE FUNCTIONS
-func sendResponseEmpty(playerName string, connection net.Conn, commandID int) error {
+func sendResponseEmpty(server *models.Server, playerName string, connection net.Conn, commandID int) error {
 	//convert to message
 	message := models.CreateMessage(playerName, commandID, constants.CGNetworkEmptyParams)
 
-	err := connectionWrite(connection, message)
+	err := connectionWrite(server, connection, message)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("error writing %w", err)
 	}
 
-	return nil
-}
-
-func SendResponseServerSuccess(responseInfo models.MessageInfo) error {
-	return sendResponseEmpty(responseInfo.PlayerNickname, responseInfo.ConnectionInfo.Connection, constants.CGCommands.ResponseServerSuccess.CommandID)
-}
+	recordClientMessageResponse(server, message)
 
-func ProcessSendResponseServerErrDuplicitNickname(responseInfo models.MessageInfo) error {
-	err := processResponseServerError(responseInfo, "error duplicate nickname")
-	if err != nil {
-		errorHandeling.PrintError(err)
-		return fmt.Errorf("error sending response %w", err)
-	}
 	return nil
 }
 
-// SendResponseServerErrorDupolicitGameName
-func ProcessSendResponseServerErrorDuplicitGameName(responseInfo models.MessageInfo) error {
-	err := processResponseServerError(responseInfo, "error duplicate game name")
-	if err != nil {
-		errorHandeling.PrintError(err)
-		return fmt.Errorf("error sending response %w", err)
-	}
-	return nil
+func SendResponseServerSuccess(server *models.Server, responseInfo models.MessageInfo) error {
+	return sendResponseEmpty(server, responseInfo.PlayerNickname, responseInfo.ConnectionInfo.Connection, constants.CGCommands.ResponseServerSuccess.CommandID)
 }
 
-// ProcessSendResponseServerErrorDisconnected
-func ProcessSendResponseServerErrorDisconnected(responseInfo models.MessageInfo) error {
-	err := processResponseServerError(responseInfo, "you have been disconnected")
+// ProcessSendResponseServerError sends error code with its message and disconnects the player
+func ProcessSendResponseServerError(server *models.Server, responseInfo models.MessageInfo, code constants.ErrorCode) error {
+	err := processResponseServerError(server, responseInfo, code)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("error sending response %w", err)
 	}
 	return nil
 }
 
-func _sendResponseServerError(responseInfo models.MessageInfo, errorStr string) error {
+// SendResponseServerError sends error code with its message, the player stays connected
+func SendResponseServerError(server *models.Server, responseInfo models.MessageInfo, code constants.ErrorCode) error {
 	command := constants.CGCommands.ResponseServerError
 
-	params, err := models.CreateParams(command.ParamsNames, []string{errorStr})
+	params, err := models.CreateParams(command.ParamsNames, []string{strconv.Itoa(int(code)), constants.GetErrorMessage(code)})
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return err
 	}
-	err = sendMessageWrapper(responseInfo, command, params)
+	err = sendMessageWrapper(server, responseInfo, command, params)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("error sending response %w", err)
 	}
 	return nil
 }
 
-func processResponseServerError(responseInfo models.MessageInfo, errorStr string) error {
-	err := _sendResponseServerError(responseInfo, errorStr)
+func processResponseServerError(server *models.Server, responseInfo models.MessageInfo, code constants.ErrorCode) error {
+	err := SendResponseServerError(server, responseInfo, code)
 	if err != nil {
 		err = fmt.Errorf("error sending response %w", err)
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return err
 	}
 
 	//disconnect player
-	player, err := models.GetInstancePlayerList().GetItem(responseInfo.PlayerNickname)
+	player, err := server.PlayerList.GetItem(responseInfo.PlayerNickname)
 	if err != nil {
-		//fatal
 		err = fmt.Errorf("error getting player %w", err)
-		errorHandeling.PrintError(err)
-		panic(err)
+		errorHandeling.PrintError(server.Log, err)
+		return err
 	}
 
 	if player == nil {
-		errorHandeling.AssertError(fmt.Errorf("error player is nil"))
+		errorHandeling.AssertError(server.Log, fmt.Errorf("error player is nil"))
 	}
 
-	err = DisconnectPlayerConnection(player)
+	err = DisconnectPlayerConnection(server, player)
 	if err != nil {
 		err = fmt.Errorf("error disconnecting player %w", err)
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return err
 	}
 
 	return nil
 }
 
-func ImidiateDisconnectPlayer(playerNickname string) {
-	player, err := models.GetInstancePlayerList().GetItem(playerNickname)
+func ImidiateDisconnectPlayer(server *models.Server, playerNickname string) {
+	player, err := server.PlayerList.GetItem(playerNickname)
 	if err != nil {
 		//fatal
 		err = fmt.Errorf("error getting player %w", err)
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return
 	}
 	if player == nil {
-		errorHandeling.PrintError(fmt.Errorf("error player is nil"))
+		errorHandeling.PrintError(server.Log, fmt.Errorf("error player is nil"))
 		return
 	}
 
 	player.SetWasTotalDisconnectCalled()
 
-	logger.Log.Errorf("Imidiate Disconnect not from timeout but forces")
-	totalDisconnect(player)
+	server.PlayerLog(player).Errorf("Imidiate Disconnect not from timeout but forces")
+	totalDisconnect(server, player)
 }
 
-func ImidiateDisconnectPlayerByConnection(connection net.Conn) {
-	player := models.GetInstancePlayerList().GetPlayerByConnection(connection)
+func ImidiateDisconnectPlayerByConnection(server *models.Server, connection net.Conn) {
+	player := server.PlayerList.GetPlayerByConnection(connection)
 	if player == nil {
-		err := CloseConnection(connection)
+		err := CloseConnection(server, connection)
 		if err != nil {
-			errorHandeling.PrintError(err)
-			return
+			errorHandeling.PrintError(server.Log, err)
 		}
+		return
+	}
+
+	err := server.GameList.ExecuteInPlayersGame(player, func() error {
+		ImidiateDisconnectPlayer(server, player.GetNickname())
+		return nil
+	})
+	if err != nil {
+		errorHandeling.PrintError(server.Log, err)
 	}
-	ImidiateDisconnectPlayer(player.GetNickname())
 }
 
-func sendGameList(command constants.Command, responseInfo models.MessageInfo, paramsValues []*models.Game) error {
+func sendGameList(server *models.Server, command constants.Command, responseInfo models.MessageInfo, paramsValues []*models.Game) error {
 	value := parser.ConvertListGameListToNetworkString(paramsValues)
 
 	params, err := models.CreateParams(command.ParamsNames, []string{value})
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("error creating params %w", err)
 	}
-	err = sendMessageWrapper(responseInfo, command, params)
+	err = sendMessageWrapper(server, responseInfo, command, params)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("error sending response %w", err)
 	}
 
 	return nil
 }
 
-func SendResponseServerGameList(responseInfo models.MessageInfo, paramsValues []*models.Game) error {
+func SendResponseServerGameList(server *models.Server, responseInfo models.MessageInfo, paramsValues []*models.Game) error {
 	command := constants.CGCommands.ResponseServerGameList
-	return sendGameList(command, responseInfo, paramsValues)
+	return sendGameList(server, command, responseInfo, paramsValues)
 }
 
-func SendResponseServerReconnectBeforeGame(reponseInfo models.MessageInfo, game *models.Game) error {
+func SendResponseServerReconnectBeforeGame(server *models.Server, reponseInfo models.MessageInfo, game *models.Game) error {
 	command := constants.CGCommands.ResponseServerReconnectBeforeGame
 	messageDataplayersInGameList := game.GetPlayers()
 
-	params, err := prepareParamsForPlayerList(command, messageDataplayersInGameList)
+	params, err := prepareParamsForPlayerList(server, command, messageDataplayersInGameList)
 	if err != nil {
 		return fmt.Errorf("error creating params %w", err)
 	}
 
-	err = sendMessageWrapper(reponseInfo, command, params)
+	err = sendMessageWrapper(server, reponseInfo, command, params)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("error sending response %w", err)
 	}
 	return nil
 }
 
-func SendResponseServerReconnectRunningGame(responseInfo models.MessageInfo, paramsValues models.GameData) error {
+func SendResponseServerReconnectRunningGame(server *models.Server, responseInfo models.MessageInfo, paramsValues models.GameData) error {
 	command := constants.CGCommands.ResponseServerReconnectRunningGame
 	paramsValue := parser.ConvertListGameDataToNetworkString(paramsValues)
 	params, err := models.CreateParams(command.ParamsNames, []string{paramsValue})
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return err
 	}
 
-	err = sendMessageWrapper(responseInfo, command, params)
+	err = sendMessageWrapper(server, responseInfo, command, params)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("error sending response %w", err)
 	}
 
 	return nil
 }
 
-func SendResponseServerSelectCubes(values []int, responseInfo models.MessageInfo) error {
+func SendResponseServerSelectCubes(server *models.Server, values []int, responseInfo models.MessageInfo) error {
 	command := constants.CGCommands.ResponseServerSelectCubes
 
-	err := sendResponseServerSelectCubes(command, values, responseInfo)
+	err := sendResponseServerSelectCubes(server, command, values, responseInfo)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("error sending response %w", err)
 	}
 	return nil
 }
 
-func SendResponseServerEndTurn(player *models.Player) error {
+func SendResponseServerEndTurn(server *models.Server, player *models.Player) error {
 	command := constants.CGCommands.ResponseServerEndTurn
 
-	return sendResponseEmpty(player.GetNickname(), player.GetConnectionInfo().Connection, command.CommandID)
+	return sendResponseEmpty(server, player.GetNickname(), player.GetConnectionInfo().Connection, command.CommandID)
 }
 
-func SendResponseServerDiceSuccess(player *models.Player) error {
+func SendResponseServerDiceSuccess(server *models.Server, player *models.Player) error {
 	command := constants.CGCommands.ResponseServerDiceSuccess
 
 	responseInfo := models.MessageInfo{
@@ -565,16 +716,16 @@ func SendResponseServerDiceSuccess(player *models.Player) error {
 		PlayerNickname: player.GetNickname(),
 	}
 
-	err := sendMessageWrapper(responseInfo, command, constants.CGNetworkEmptyParams)
+	err := sendMessageWrapper(server, responseInfo, command, constants.CGNetworkEmptyParams)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("error sending response %w", err)
 	}
 
 	return nil
 }
 
-func SendResponseServerEndScore(player *models.Player) error {
+func SendResponseServerEndScore(server *models.Server, player *models.Player) error {
 	command := constants.CGCommands.ResponseServerEndScore
 
 	responseInfo := models.MessageInfo{
@@ -582,9 +733,9 @@ func SendResponseServerEndScore(player *models.Player) error {
 		PlayerNickname: player.GetNickname(),
 	}
 
-	err := sendMessageWrapper(responseInfo, command, constants.CGNetworkEmptyParams)
+	err := sendMessageWrapper(server, responseInfo, command, constants.CGNetworkEmptyParams)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("error sending response %w", err)
 	}
 
@@ -596,13 +747,13 @@ func SendResponseServerEndScore(player *models.Player) error {
 //region COMMUNICATION FUNCTIONS
 
 // region SEND FUNCTIONS
-func sendStandardUpdateToAllMessage(playerList []*models.Player, command constants.Command, params []constants.Params) error {
+func sendStandardUpdateToAllMessage(server *models.Server, playerList []*models.Player, command constants.Command, params []constants.Params) error {
 
 	for _, player := range playerList {
 		//null responseExpected
-		err := sendUpdateList(player, command, params)
+		err := sendUpdateList(server, player, command, params)
 		if err != nil {
-			errorHandeling.PrintError(err)
+			errorHandeling.PrintError(server.Log, err)
 			return fmt.Errorf("error sending update list %w", err)
 		}
 	}
@@ -612,107 +763,158 @@ func sendStandardUpdateToAllMessage(playerList []*models.Player, command constan
 // ProcessCommunicationServerUpdateGameList
 // args: playerList - list of players, paramsValues - list of games, player - player that sent the message
 
-func CommunicationServerUpdateGameList(playerList []*models.Player, paramsValues []*models.Game) error {
+func CommunicationServerUpdateGameList(server *models.Server, playerList []*models.Player, paramsValues []*models.Game) error {
 	command := constants.CGCommands.ServerUpdateGameList
 	paramsValue := parser.ConvertListGameListToNetworkString(paramsValues)
 	params, err := models.CreateParams(command.ParamsNames, []string{paramsValue})
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return err
 	}
 
-	err = sendStandardUpdateToAllMessage(playerList, command, params)
+	err = sendStandardUpdateToAllMessage(server, playerList, command, params)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("error sending update list %w", err)
 	}
 
 	return nil
 }
 
-func prepareParamsForPlayerList(command constants.Command, playersInGameList []*models.Player) ([]constants.Params, error) {
+func prepareParamsForPlayerList(server *models.Server, command constants.Command, playersInGameList []*models.Player) ([]constants.Params, error) {
 	paramsValue := parser.ConvertListPlayerListToNetworkString(playersInGameList)
 	params, err := models.CreateParams(command.ParamsNames, []string{paramsValue})
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return nil, err
 	}
 	return params, nil
 }
 
-func CommunicationServerUpdatePlayerList(sendPlayerList []*models.Player, playersInGameList []*models.Player) error {
+func CommunicationServerUpdatePlayerList(server *models.Server, sendPlayerList []*models.Player, playersInGameList []*models.Player) error {
 	command := constants.CGCommands.ServerUpdatePlayerList
 
-	params, err := prepareParamsForPlayerList(command, playersInGameList)
+	params, err := prepareParamsForPlayerList(server, command, playersInGameList)
 	if err != nil {
 		return fmt.Errorf("error creating params %w", err)
 	}
 
-	err = sendStandardUpdateToAllMessage(sendPlayerList, command, params)
+	err = sendStandardUpdateToAllMessage(server, sendPlayerList, command, params)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("error sending update list %w", err)
 	}
 
 	return nil
 }
 
-func CommunicationServerUpdateStartGame(playerList []*models.Player) error {
+func CommunicationServerUpdateStartGame(server *models.Server, playerList []*models.Player) error {
 	command := constants.CGCommands.ServerUpdateStartGame
 	params := constants.CGNetworkEmptyParams
 
-	err := sendStandardUpdateToAllMessage(playerList, command, params)
+	err := sendStandardUpdateToAllMessage(server, playerList, command, params)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("error sending update list %w", err)
 	}
 
 	return nil
 }
 
-func CommunicationServerUpdateNotEnoughPlayers(playerList []*models.Player) error {
+func CommunicationServerUpdateNotEnoughPlayers(server *models.Server, playerList []*models.Player) error {
 	command := constants.CGCommands.ServerUpdateNotEnoughPlayers
 	params := constants.CGNetworkEmptyParams
 
-	err := sendStandardUpdateToAllMessage(playerList, command, params)
+	err := sendStandardUpdateToAllMessage(server, playerList, command, params)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("error sending update list %w", err)
 	}
 
 	return nil
 }
 
-func CommunicationServerUpdateGameData(sendPlayerList []*models.Player, gameData models.GameData) error {
+// CommunicationServerShutdown tells players that the server is going down, clients do not ack it
+func CommunicationServerShutdown(server *models.Server, playerList []*models.Player) error {
+	command := constants.CGCommands.ServerShutdown
+	params := constants.CGNetworkEmptyParams
+
+	var lastErr error
+	for _, player := range playerList {
+		responseInfo := models.MessageInfo{
+			ConnectionInfo: player.GetConnectionInfo(),
+			PlayerNickname: player.GetNickname(),
+		}
+
+		// one unreachable player must not stop notifying the others
+		err := sendMessageWrapper(server, responseInfo, command, params)
+		if err != nil {
+			errorHandeling.PrintError(server.Log, err)
+			lastErr = fmt.Errorf("error sending shutdown %w", err)
+		}
+	}
+
+	return lastErr
+}
+
+// CommunicationServerAnnouncement sends admin message to players, clients do not ack it
+func CommunicationServerAnnouncement(server *models.Server, playerList []*models.Player, message string) error {
+	command := constants.CGCommands.ServerAnnouncement
+
+	params, err := models.CreateParams(command.ParamsNames, []string{message})
+	if err != nil {
+		errorHandeling.PrintError(server.Log, err)
+		return err
+	}
+
+	var lastErr error
+	for _, player := range playerList {
+		responseInfo := models.MessageInfo{
+			ConnectionInfo: player.GetConnectionInfo(),
+			PlayerNickname: player.GetNickname(),
+		}
+
+		// one unreachable player must not stop notifying the others
+		err := sendMessageWrapper(server, responseInfo, command, params)
+		if err != nil {
+			errorHandeling.PrintError(server.Log, err)
+			lastErr = fmt.Errorf("error sending announcement %w", err)
+		}
+	}
+
+	return lastErr
+}
+
+func CommunicationServerUpdateGameData(server *models.Server, sendPlayerList []*models.Player, gameData models.GameData) error {
 	command := constants.CGCommands.ServerUpdateGameData
 	paramsValue := parser.ConvertListGameDataToNetworkString(gameData)
 	params, err := models.CreateParams(command.ParamsNames, []string{paramsValue})
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return err
 	}
 
-	err = sendStandardUpdateToAllMessage(sendPlayerList, command, params)
+	err = sendStandardUpdateToAllMessage(server, sendPlayerList, command, params)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("error sending update list %w", err)
 	}
 
 	return nil
 }
 
-func CommunicationServerUpdateEndScore(playerList []*models.Player, playerName string) error {
+func CommunicationServerUpdateEndScore(server *models.Server, playerList []*models.Player, playerName string) error {
 	command := constants.CGCommands.ServerUpdateEndScore
 
 	params, err := models.CreateParams(command.ParamsNames, []string{playerName})
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return err
 	}
 
-	err = sendStandardUpdateToAllMessage(playerList, command, params)
+	err = sendStandardUpdateToAllMessage(server, playerList, command, params)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("error sending update list %w", err)
 	}
 
@@ -722,12 +924,12 @@ func CommunicationServerUpdateEndScore(playerList []*models.Player, playerName s
 //region SERVER -> SINGLE CLIENT
 
 // CommunicationServerUpdateGameData
-func CommunicationServerStartTurn(player *models.Player) error {
+func CommunicationServerStartTurn(server *models.Server, player *models.Player) error {
 	command := constants.CGCommands.ServerStartTurn
 
-	err := sendMessageWithSuccessResponse(player, command, constants.CGNetworkEmptyParams)
+	err := sendMessageWithSuccessResponse(server, player, command, constants.CGNetworkEmptyParams)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("error sending ping player %w", err)
 	}
 
@@ -735,12 +937,12 @@ func CommunicationServerStartTurn(player *models.Player) error {
 }
 
 // CommunicationServerPingPlayer
-func CommunicationServerPingPlayer(player *models.Player) error {
+func CommunicationServerPingPlayer(server *models.Server, player *models.Player) error {
 	command := constants.CGCommands.ServerPingPlayer
 
-	err := sendMessageWithSuccessResponse(player, command, constants.CGNetworkEmptyParams)
+	err := sendMessageWithSuccessResponse(server, player, command, constants.CGNetworkEmptyParams)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("error sending ping player %w", err)
 	}
 	return err
@@ -757,48 +959,48 @@ func CommunicationServerPingPlayer(player *models.Player) error {
 
 //endregion
 
-func SendGameUpdates(game *models.Game) error {
-	logger.Log.Debugf("SendGameUpdates: Sending Disconnect updates for game")
+func SendGameUpdates(server *models.Server, game *models.Game) error {
+	server.Log.Debugf("SendGameUpdates: Sending Disconnect updates for game")
 
 	//region Send updates to all players
-	err := ProcessCommunicationServerUpdatePlayerList(game)
+	err := ProcessCommunicationServerUpdatePlayerList(server, game)
 	if err != nil {
 		err = fmt.Errorf("Error sending update: %w", err)
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return err
 	}
 
-	err = ProcessCommunicationServerUpdateGameData(game)
+	err = ProcessCommunicationServerUpdateGameData(server, game)
 	if err != nil {
 		err = fmt.Errorf("Error sending update: %w", err)
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return err
 	}
 	//endregion
 	return nil
 }
 
-func SendAllUpdates(game *models.Game) error {
+func SendAllUpdates(server *models.Server, game *models.Game) error {
 	//region ServerUpdateGameList
-	err := ProcessCommunicationServerUpdateGameList()
+	err := ProcessCommunicationServerUpdateGameList(server)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("Error sending response: %w", err)
 	}
 	//endregion
 
-	err = SendGameUpdates(game)
+	err = SendGameUpdates(server, game)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("Error sending response: %w", err)
 	}
 
 	return nil
 }
 
-func ProcessCommunicationServerUpdateGameData(game *models.Game) error {
+func ProcessCommunicationServerUpdateGameData(server *models.Server, game *models.Game) error {
 
-	logger.Log.Infof("ProcessCommunicationServerUpdateGameData")
+	server.Log.Infof("ProcessCommunicationServerUpdateGameData")
 	if game.GetState() != models.Running {
 		return nil
 	}
@@ -812,13 +1014,13 @@ func ProcessCommunicationServerUpdateGameData(game *models.Game) error {
 	playerList := helpers.PlayerListGetActivePlayers(playersInGameList)
 
 	if len(playerList) == 0 {
-		logger.Log.Debugf("ProcessCommunicationServerUpdateGameData: No players in game")
+		server.Log.Debugf("ProcessCommunicationServerUpdateGameData: No players in game")
 		return nil
 	}
 
-	err = CommunicationServerUpdateGameData(playerList, gameData)
+	err = CommunicationServerUpdateGameData(server, playerList, gameData)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("Error sending response: %w", err)
 	}
 
@@ -836,26 +1038,26 @@ func getPlayerListInfo(game *models.Game) ([]*models.Player, []*models.Player) {
 	return sendPlayerList, messageDataplayersInGameList
 }
 
-func ProcessCommunicationServerUpdatePlayerList(game *models.Game) error {
-	logger.Log.Infof("ProcessCommunicationServerUpdatePlayerList")
+func ProcessCommunicationServerUpdatePlayerList(server *models.Server, game *models.Game) error {
+	server.Log.Infof("ProcessCommunicationServerUpdatePlayerList")
 	playerList, playersInGameList := getPlayerListInfo(game)
 	if playerList == nil {
-		logger.Log.Debugf("ProcessCommunicationServerUpdatePlayerList: No players in game")
+		server.Log.Debugf("ProcessCommunicationServerUpdatePlayerList: No players in game")
 		return nil
 	}
 
-	err := CommunicationServerUpdatePlayerList(playerList, playersInGameList)
+	err := CommunicationServerUpdatePlayerList(server, playerList, playersInGameList)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("Error sending response: %w", err)
 	}
 
 	return nil
 }
 
-func ProcessCommunicationServerUpdateGameList() error {
-	gameList := models.GetInstanceGameList().GetCreatedGameList()
-	playerList := models.GetInstancePlayerList().GetActivePlayersInState(state_machine.StateNameMap.StateLobby)
+func ProcessCommunicationServerUpdateGameList(server *models.Server) error {
+	gameList := server.GameList.GetCreatedGameList()
+	playerList := server.PlayerList.GetActivePlayersInState(state_machine.StateNameMap.StateLobby)
 
 	if len(playerList) == 0 {
 		return nil
@@ -863,9 +1065,9 @@ func ProcessCommunicationServerUpdateGameList() error {
 
 	//Send only games which are in state created
 
-	err := CommunicationServerUpdateGameList(playerList, gameList)
+	err := CommunicationServerUpdateGameList(server, playerList, gameList)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("Error sending response: %w", err)
 	}
 
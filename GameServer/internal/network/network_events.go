@@ -0,0 +1,85 @@
+package network
+
+import (
+	"fmt"
+	"gameserver/internal/models"
+	"gameserver/internal/utils/errorHandeling"
+)
+
+// SubscribeEvents sends the continuous updates (game list, player list, game data) caused by game events
+func SubscribeEvents(server *models.Server) {
+	models.Subscribe(server.Events, func(event models.PlayerJoined) error {
+		err := ProcessCommunicationServerUpdateGameList(server)
+		if err != nil {
+			errorHandeling.PrintError(server.Log, err)
+			return fmt.Errorf("error sending game list update: %w", err)
+		}
+
+		err = ProcessCommunicationServerUpdatePlayerList(server, event.Game)
+		if err != nil {
+			errorHandeling.PrintError(server.Log, err)
+			return fmt.Errorf("error sending player list update: %w", err)
+		}
+		return nil
+	})
+
+	models.Subscribe(server.Events, func(event models.GameStarted) error {
+		err := ProcessCommunicationServerUpdateGameList(server)
+		if err != nil {
+			errorHandeling.PrintError(server.Log, err)
+			return fmt.Errorf("error sending game list update: %w", err)
+		}
+
+		return sendGameDataUpdate(server, event.Game)
+	})
+
+	models.Subscribe(server.Events, func(event models.TurnStarted) error {
+		return sendGameDataUpdate(server, event.Game)
+	})
+
+	models.Subscribe(server.Events, func(event models.DiceRolled) error {
+		return sendGameDataUpdate(server, event.Game)
+	})
+
+	models.Subscribe(server.Events, func(event models.ScoreChanged) error {
+		return sendGameDataUpdate(server, event.Game)
+	})
+
+	models.Subscribe(server.Events, func(event models.GameEnded) error {
+		err := ProcessCommunicationServerUpdateGameList(server)
+		if err != nil {
+			errorHandeling.PrintError(server.Log, err)
+			return fmt.Errorf("error sending game list update: %w", err)
+		}
+		return nil
+	})
+
+	models.Subscribe(server.Events, func(event models.GameAborted) error {
+		err := ProcessCommunicationServerUpdateGameList(server)
+		if err != nil {
+			errorHandeling.PrintError(server.Log, err)
+			return fmt.Errorf("error sending game list update: %w", err)
+		}
+		return nil
+	})
+
+	models.Subscribe(server.Events, func(event models.PlayerDisconnected) error {
+		if event.Game == nil {
+			return nil
+		}
+
+		if event.IsTotal {
+			return SendAllUpdates(server, event.Game)
+		}
+		return SendGameUpdates(server, event.Game)
+	})
+}
+
+func sendGameDataUpdate(server *models.Server, game *models.Game) error {
+	err := ProcessCommunicationServerUpdateGameData(server, game)
+	if err != nil {
+		errorHandeling.PrintError(server.Log, err)
+		return fmt.Errorf("error sending game data update: %w", err)
+	}
+	return nil
+}
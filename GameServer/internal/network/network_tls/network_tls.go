@@ -0,0 +1,132 @@
+package network_tls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"gameserver/internal/config"
+	"math/big"
+	"net"
+	"os"
+	"time"
+)
+
+//region CONSTANTS
+
+const cSelfSignedValidity = 365 * 24 * time.Hour
+
+var minVersions = map[string]uint16{
+	"":    tls.VersionTLS12,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	"":        tls.NoClientCert,
+	"none":    tls.NoClientCert,
+	"request": tls.VerifyClientCertIfGiven,
+	"require": tls.RequireAndVerifyClientCert,
+}
+
+//endregion
+
+//region FUNCTIONS
+
+// CreateTLSConfig builds server tls.Config from the tls section of config file
+func CreateTLSConfig(config config.TLSConfig) (*tls.Config, error) {
+	minVersion, ok := minVersions[config.MinVersion]
+	if !ok {
+		return nil, fmt.Errorf("invalid tls min_version %q", config.MinVersion)
+	}
+
+	clientAuth, ok := clientAuthTypes[config.ClientAuth]
+	if !ok {
+		return nil, fmt.Errorf("invalid tls client_auth %q", config.ClientAuth)
+	}
+
+	var certificate tls.Certificate
+	var err error
+	if config.SelfSigned {
+		certificate, err = createSelfSignedCertificate()
+	} else {
+		certificate, err = tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not load tls certificate: %w", err)
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		MinVersion:   minVersion,
+		ClientAuth:   clientAuth,
+	}
+
+	if clientAuth != tls.NoClientCert {
+		if config.ClientCAFile == "" {
+			return nil, fmt.Errorf("tls client_ca_file is required for client_auth %q", config.ClientAuth)
+		}
+		pool, err := loadCertPool(config.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.ClientCAs = pool
+	}
+
+	return tlsConfig, nil
+}
+
+func loadCertPool(filePath string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("could not read client ca file: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in client ca file")
+	}
+
+	return pool, nil
+}
+
+// createSelfSignedCertificate generates in-memory certificate for localhost, meant only for local testing
+func createSelfSignedCertificate() (tls.Certificate, error) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("could not generate key: %w", err)
+	}
+
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("could not generate serial number: %w", err)
+	}
+
+	now := time.Now()
+	template := x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name{Organization: []string{"gameserver development"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(cSelfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+
+	derBytes, err := x509.CreateCertificate(rand.Reader, &template, &template, &privateKey.PublicKey, privateKey)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("could not create certificate: %w", err)
+	}
+
+	return tls.Certificate{
+		Certificate: [][]byte{derBytes},
+		PrivateKey:  privateKey,
+	}, nil
+}
+
+//endregion
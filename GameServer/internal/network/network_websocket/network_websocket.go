@@ -0,0 +1,414 @@
+package network_websocket
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"gameserver/internal/utils/constants"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+//region CONSTANTS
+
+const cWebSocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+const (
+	cOpContinuation byte = 0x0
+	cOpText         byte = 0x1
+	cOpBinary       byte = 0x2
+	cOpClose        byte = 0x8
+	cOpPing         byte = 0x9
+	cOpPong         byte = 0xA
+)
+
+const (
+	cCloseNormal uint16 = 1000
+	cCloseTooBig uint16 = 1009
+)
+
+// cCloseWriteTimeout limits write of the close frame, so close does not block on client which does not read
+const cCloseWriteTimeout = 1 * time.Second
+
+// cAnyOrigin in allowed origins accepts every origin
+const cAnyOrigin = "*"
+
+// errMessageTooLong is returned for frame or fragmented message longer than maxMessageSize
+var errMessageTooLong = errors.New("message too long")
+
+//endregion
+
+//region DATA STRUCTURES
+
+// Conn wraps hijacked HTTP connection and exposes WebSocket payloads as a plain
+// byte stream so the rest of the server can handle it like any other net.Conn.
+type Conn struct {
+	net.Conn
+	reader *bufio.Reader
+	// maxMessageSize limits frames and messages of the client
+	maxMessageSize int
+
+	frames  chan []byte
+	pending []byte
+	readErr error
+
+	deadline     time.Time
+	deadlineLock sync.Mutex
+
+	writeMutex sync.Mutex
+	closeOnce  sync.Once
+	closed     chan struct{}
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "websocket read timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+//endregion
+
+//region FUNCTIONS
+
+// Upgrade performs the WebSocket handshake and returns connection which carries KIVUPS messages in text frames,
+// messages longer than maxMessageSize close the connection. Browser requests are accepted only from the same host
+// or from allowedOrigins, see isAllowedOrigin.
+func Upgrade(w http.ResponseWriter, r *http.Request, maxMessageSize int, allowedOrigins []string) (*Conn, error) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return nil, fmt.Errorf("invalid method %s", r.Method)
+	}
+	if !isAllowedOrigin(r, allowedOrigins) {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return nil, fmt.Errorf("origin %q not allowed", r.Header.Get("Origin"))
+	}
+	if !headerContains(r.Header, "Connection", "upgrade") || !headerContains(r.Header, "Upgrade", "websocket") {
+		http.Error(w, "websocket upgrade expected", http.StatusBadRequest)
+		return nil, fmt.Errorf("missing upgrade headers")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		http.Error(w, "unsupported websocket version", http.StatusBadRequest)
+		return nil, fmt.Errorf("unsupported websocket version")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		http.Error(w, "missing websocket key", http.StatusBadRequest)
+		return nil, fmt.Errorf("missing websocket key")
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket not supported", http.StatusInternalServerError)
+		return nil, fmt.Errorf("response writer cannot be hijacked")
+	}
+	netConn, bufrw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, fmt.Errorf("error hijacking connection: %w", err)
+	}
+	// deadlines of the HTTP server would stay on the hijacked connection
+	_ = netConn.SetDeadline(time.Time{})
+
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n\r\n"
+	_, err = bufrw.WriteString(response)
+	if err == nil {
+		err = bufrw.Flush()
+	}
+	if err != nil {
+		_ = netConn.Close()
+		return nil, fmt.Errorf("error writing handshake: %w", err)
+	}
+
+	conn := &Conn{
+		Conn:           netConn,
+		reader:         bufrw.Reader,
+		maxMessageSize: maxMessageSize,
+		frames:         make(chan []byte, 16),
+		closed:         make(chan struct{}),
+	}
+	go conn.readLoop()
+
+	return conn, nil
+}
+
+func acceptKey(key string) string {
+	hash := sha1.Sum([]byte(key + cWebSocketGUID))
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// isAllowedOrigin accepts requests without Origin (clients other than browsers), origin with the host of the request
+// and origins listed in allowedOrigins, e.g. "https://example.com", "*" accepts every origin
+func isAllowedOrigin(r *http.Request, allowedOrigins []string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	for _, allowed := range allowedOrigins {
+		if allowed == cAnyOrigin || strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+
+	originURL, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(originURL.Host, r.Host)
+}
+
+func headerContains(header http.Header, name string, value string) bool {
+	for _, v := range header.Values(name) {
+		for _, part := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+//endregion
+
+//region READ
+
+// readLoop reads frames independently of the read deadline so a timeout never leaves a frame half consumed
+func (c *Conn) readLoop() {
+	defer close(c.frames)
+
+	var message []byte
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			c.readErr = err
+			if errors.Is(err, errMessageTooLong) {
+				_ = c.closeWithStatus(cCloseTooBig)
+			}
+			return
+		}
+
+		switch opcode {
+		case cOpPing:
+			err = c.writeFrame(cOpPong, payload)
+			if err != nil {
+				c.readErr = err
+				return
+			}
+			continue
+		case cOpPong:
+			continue
+		case cOpClose:
+			_ = c.writeFrame(cOpClose, payload)
+			c.readErr = io.EOF
+			return
+		case cOpText, cOpBinary:
+			message = payload
+		case cOpContinuation:
+			message = append(message, payload...)
+		default:
+			c.readErr = fmt.Errorf("unknown opcode %d", opcode)
+			return
+		}
+
+		// Checked on every fragment, otherwise peer could grow the message by continuation frames without end
+		if len(message) > c.maxMessageSize {
+			c.readErr = errMessageTooLong
+			_ = c.closeWithStatus(cCloseTooBig)
+			return
+		}
+		if !fin {
+			continue
+		}
+
+		// Browser clients usually send one message per frame without the delimiter
+		if !strings.HasSuffix(string(message), constants.CMessageEndDelimiter) {
+			message = append(message, constants.CMessageEndDelimiter...)
+		}
+
+		select {
+		case c.frames <- message:
+		case <-c.closed:
+			return
+		}
+		message = nil
+	}
+}
+
+func (c *Conn) readFrame() (bool, byte, []byte, error) {
+	header := make([]byte, 2)
+	_, err := io.ReadFull(c.reader, header)
+	if err != nil {
+		return false, 0, nil, err
+	}
+
+	fin := header[0]&0x80 != 0
+	opcode := header[0] & 0x0F
+	masked := header[1]&0x80 != 0
+	length := uint64(header[1] & 0x7F)
+
+	switch length {
+	case 126:
+		extended := make([]byte, 2)
+		_, err = io.ReadFull(c.reader, extended)
+		if err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(extended))
+	case 127:
+		extended := make([]byte, 8)
+		_, err = io.ReadFull(c.reader, extended)
+		if err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(extended)
+	}
+
+	if length > uint64(c.maxMessageSize) {
+		return false, 0, nil, errMessageTooLong
+	}
+
+	// Clients must mask every frame
+	if !masked {
+		return false, 0, nil, fmt.Errorf("unmasked client frame")
+	}
+	mask := make([]byte, 4)
+	_, err = io.ReadFull(c.reader, mask)
+	if err != nil {
+		return false, 0, nil, err
+	}
+
+	payload := make([]byte, length)
+	_, err = io.ReadFull(c.reader, payload)
+	if err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+
+	return fin, opcode, payload, nil
+}
+
+// Read returns payload of received text frames, honouring the read deadline
+func (c *Conn) Read(p []byte) (int, error) {
+	if len(c.pending) == 0 {
+		c.deadlineLock.Lock()
+		deadline := c.deadline
+		c.deadlineLock.Unlock()
+
+		var timeout <-chan time.Time
+		if !deadline.IsZero() {
+			timer := time.NewTimer(time.Until(deadline))
+			defer timer.Stop()
+			timeout = timer.C
+		}
+
+		select {
+		case frame, ok := <-c.frames:
+			if !ok {
+				if c.readErr == nil {
+					return 0, io.EOF
+				}
+				return 0, c.readErr
+			}
+			c.pending = frame
+		case <-timeout:
+			return 0, timeoutError{}
+		case <-c.closed:
+			return 0, net.ErrClosed
+		}
+	}
+
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+//endregion
+
+//region WRITE
+
+// Write sends p as single text frame
+func (c *Conn) Write(p []byte) (int, error) {
+	err := c.writeFrame(cOpText, p)
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (c *Conn) writeFrame(opcode byte, payload []byte) error {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
+	frame := []byte{0x80 | opcode}
+	length := len(payload)
+	switch {
+	case length < 126:
+		frame = append(frame, byte(length))
+	case length <= 0xFFFF:
+		frame = append(frame, 126, 0, 0)
+		binary.BigEndian.PutUint16(frame[2:], uint16(length))
+	default:
+		frame = append(frame, 127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(frame[2:], uint64(length))
+	}
+	frame = append(frame, payload...)
+
+	_, err := c.Conn.Write(frame)
+	return err
+}
+
+//endregion
+
+//region CONNECTION
+
+// SetDeadline sets read and write deadline
+func (c *Conn) SetDeadline(t time.Time) error {
+	err := c.SetReadDeadline(t)
+	if err != nil {
+		return err
+	}
+	return c.Conn.SetWriteDeadline(t)
+}
+
+// SetReadDeadline is handled by Conn itself, underlying connection is read by readLoop without deadline
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	c.deadlineLock.Lock()
+	defer c.deadlineLock.Unlock()
+
+	c.deadline = t
+	return nil
+}
+
+// Close sends close frame and closes underlying connection
+func (c *Conn) Close() error {
+	return c.closeWithStatus(cCloseNormal)
+}
+
+// closeWithStatus sends close frame with status code, only the first close is sent
+func (c *Conn) closeWithStatus(status uint16) error {
+	err := net.ErrClosed
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		// deadline also ends write in progress, so writeMutex is released
+		_ = c.Conn.SetWriteDeadline(time.Now().Add(cCloseWriteTimeout))
+		payload := make([]byte, 2)
+		binary.BigEndian.PutUint16(payload, status)
+		_ = c.writeFrame(cOpClose, payload)
+		err = c.Conn.Close()
+	})
+	return err
+}
+
+//endregion
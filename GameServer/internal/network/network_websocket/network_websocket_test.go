@@ -0,0 +1,238 @@
+package network_websocket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const cTestMaxMessageSize = 64
+
+const cTestMessage = "KIVUPS012024-12-31 15:30:00.000000{nickname}{}"
+
+//region HELPERS
+
+// startServer upgrades every request and passes server side of the connection to the returned channel
+func startServer(t *testing.T) (string, <-chan *Conn) {
+	t.Helper()
+
+	connections := make(chan *Conn, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r, cTestMaxMessageSize, nil)
+		if err != nil {
+			t.Errorf("upgrade failed: %v", err)
+			return
+		}
+		connections <- conn
+	}))
+	t.Cleanup(server.Close)
+
+	return server.Listener.Addr().String(), connections
+}
+
+// dial connects local client and performs the handshake
+func dial(t *testing.T, address string) (net.Conn, *bufio.Reader) {
+	t.Helper()
+
+	client, err := net.Dial("tcp", address)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	_ = client.SetDeadline(time.Now().Add(5 * time.Second))
+
+	key := "dGhlIHNhbXBsZSBub25jZQ=="
+	request := "GET / HTTP/1.1\r\n" +
+		"Host: " + address + "\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: " + key + "\r\n" +
+		"Sec-WebSocket-Version: 13\r\n\r\n"
+	_, err = client.Write([]byte(request))
+	if err != nil {
+		t.Fatalf("handshake write failed: %v", err)
+	}
+
+	reader := bufio.NewReader(client)
+	response, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("handshake read failed: %v", err)
+	}
+	if response.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("expected status 101, got %d", response.StatusCode)
+	}
+	if response.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		t.Fatalf("invalid accept key %q", response.Header.Get("Sec-WebSocket-Accept"))
+	}
+
+	return client, reader
+}
+
+// writeClientFrame writes masked frame as browser would, payload has to be shorter than 126 bytes
+func writeClientFrame(t *testing.T, client net.Conn, fin bool, opcode byte, payload []byte) {
+	t.Helper()
+
+	first := opcode
+	if fin {
+		first |= 0x80
+	}
+	mask := []byte{0x12, 0x34, 0x56, 0x78}
+	frame := []byte{first, 0x80 | byte(len(payload))}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+
+	_, err := client.Write(frame)
+	if err != nil {
+		t.Fatalf("frame write failed: %v", err)
+	}
+}
+
+// readServerFrame reads unmasked frame of the server, payload has to be shorter than 126 bytes
+func readServerFrame(t *testing.T, reader *bufio.Reader) (byte, []byte) {
+	t.Helper()
+
+	header := make([]byte, 2)
+	_, err := io.ReadFull(reader, header)
+	if err != nil {
+		t.Fatalf("frame read failed: %v", err)
+	}
+	payload := make([]byte, header[1]&0x7F)
+	_, err = io.ReadFull(reader, payload)
+	if err != nil {
+		t.Fatalf("frame read failed: %v", err)
+	}
+
+	return header[0] & 0x0F, payload
+}
+
+// readMessage reads one KIVUPS message from server side of the connection
+func readMessage(t *testing.T, conn *Conn) string {
+	t.Helper()
+
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	message, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		t.Fatalf("server read failed: %v", err)
+	}
+	return message
+}
+
+//endregion
+
+//region TESTS
+
+func TestMessageInOneFrame(t *testing.T) {
+	address, connections := startServer(t)
+	client, _ := dial(t, address)
+	writeClientFrame(t, client, true, cOpText, []byte(cTestMessage))
+
+	conn := <-connections
+	defer conn.Close()
+
+	message := readMessage(t, conn)
+	if message != cTestMessage+"\n" {
+		t.Fatalf("expected %q, got %q", cTestMessage+"\n", message)
+	}
+}
+
+func TestMessageInFragmentedFrames(t *testing.T) {
+	address, connections := startServer(t)
+	client, _ := dial(t, address)
+
+	half := len(cTestMessage) / 2
+	writeClientFrame(t, client, false, cOpText, []byte(cTestMessage[:half]))
+	// Control frames may come between fragments
+	writeClientFrame(t, client, true, cOpPing, nil)
+	writeClientFrame(t, client, true, cOpContinuation, []byte(cTestMessage[half:]+"\n"))
+
+	conn := <-connections
+	defer conn.Close()
+
+	message := readMessage(t, conn)
+	if message != cTestMessage+"\n" {
+		t.Fatalf("expected %q, got %q", cTestMessage+"\n", message)
+	}
+}
+
+func TestOversizedFragmentedMessageIsRejected(t *testing.T) {
+	address, connections := startServer(t)
+	client, reader := dial(t, address)
+
+	// Every fragment is within the limit, the whole message is not
+	fragment := []byte(strings.Repeat("x", cTestMaxMessageSize/2))
+	writeClientFrame(t, client, false, cOpText, fragment)
+	writeClientFrame(t, client, false, cOpContinuation, fragment)
+	writeClientFrame(t, client, false, cOpContinuation, fragment)
+
+	opcode, payload := readServerFrame(t, reader)
+	if opcode != cOpClose {
+		t.Fatalf("expected close frame, got opcode %d", opcode)
+	}
+	if len(payload) != 2 || binary.BigEndian.Uint16(payload) != cCloseTooBig {
+		t.Fatalf("expected close status %d, got %v", cCloseTooBig, payload)
+	}
+
+	conn := <-connections
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err := conn.Read(make([]byte, cTestMaxMessageSize))
+	if err == nil {
+		t.Fatalf("expected read error after oversized message")
+	}
+}
+
+func TestAllowedOrigins(t *testing.T) {
+	tests := []struct {
+		name           string
+		origin         string
+		allowedOrigins []string
+		expected       bool
+	}{
+		{"no origin", "", nil, true},
+		{"same host", "http://game.example.com", nil, true},
+		{"other host", "https://evil.example.com", nil, false},
+		{"listed origin", "https://web.example.com", []string{"https://web.example.com/"}, true},
+		{"other scheme of listed origin", "http://web.example.com", []string{"https://web.example.com"}, false},
+		{"any origin", "https://evil.example.com", []string{"*"}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "http://game.example.com/ws", nil)
+			if test.origin != "" {
+				request.Header.Set("Origin", test.origin)
+			}
+
+			if allowed := isAllowedOrigin(request, test.allowedOrigins); allowed != test.expected {
+				t.Fatalf("expected %v, got %v", test.expected, allowed)
+			}
+		})
+	}
+}
+
+func TestForbiddenOriginIsRejected(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "http://game.example.com/ws", nil)
+	request.Header.Set("Connection", "Upgrade")
+	request.Header.Set("Upgrade", "websocket")
+	request.Header.Set("Sec-WebSocket-Version", "13")
+	request.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	request.Header.Set("Origin", "https://evil.example.com")
+	recorder := httptest.NewRecorder()
+
+	_, err := Upgrade(recorder, request, cTestMaxMessageSize, nil)
+	if err == nil {
+		t.Fatalf("expected upgrade error")
+	}
+	if recorder.Code != http.StatusForbidden {
+		t.Fatalf("expected status %d, got %d", http.StatusForbidden, recorder.Code)
+	}
+}
+
+//endregion
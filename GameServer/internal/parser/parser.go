@@ -5,13 +5,18 @@ import (
 	"gameserver/internal/logger"
 	"gameserver/internal/models"
 	"gameserver/internal/utils/constants"
-	"gameserver/internal/utils/errorHandeling"
 	"reflect"
 	"strconv"
 	"strings"
 	"unsafe"
 )
 
+// optional parameter of every client command, repeated ID means retry of the same command
+const cParamClientMessageID = "msgID"
+
+// last parameter of every server message waiting for ResponseClientSuccess, client acks and deduplicates by it
+const cParamSequenceNumber = "seq"
+
 //region FUNCTIONS PARSE
 
 func ParseReceiveMessageStr(input string) ([]models.Message, error) {
@@ -24,7 +29,6 @@ func ParseReceiveMessageStr(input string) ([]models.Message, error) {
 	partsLen := len(parts)
 	if partsLen < 2 {
 		err := fmt.Errorf("invalid input format")
-		errorHandeling.PrintError(err)
 		return messageList, err
 	}
 	parts = parts[:partsLen-1]
@@ -32,7 +36,6 @@ func ParseReceiveMessageStr(input string) ([]models.Message, error) {
 	for _, part := range parts {
 		message, err := parseMessage(part)
 		if err != nil {
-			errorHandeling.PrintError(err)
 			return messageList, fmt.Errorf("error parsing message: %v", err)
 		}
 		messageList = append(messageList, message)
@@ -44,7 +47,6 @@ func ParseReceiveMessageStr(input string) ([]models.Message, error) {
 func parseMessage(input string) (models.Message, error) {
 	if len(input) == 0 {
 		err := fmt.Errorf("empty input")
-		errorHandeling.PrintError(err)
 		return models.Message{}, err
 	}
 
@@ -75,7 +77,6 @@ func parseMessage(input string) (models.Message, error) {
 	//Read nickname
 	playerNickname, playerNicknameSize, err := parseMessagePlayerID(input[start:])
 	if err != nil {
-		errorHandeling.PrintError(err)
 		return models.Message{}, fmt.Errorf("error parsing player ID: %v", err)
 	}
 	start += playerNicknameSize
@@ -85,14 +86,12 @@ func parseMessage(input string) (models.Message, error) {
 
 	params, err := parseParamsStr(parametersStr)
 	if err != nil {
-		errorHandeling.PrintError(err)
 		return models.Message{}, fmt.Errorf("error parsing params: %v", err)
 	}
 
 	//convert values
 	commandIDint, err := strconv.ParseUint(commandID, 10, 64)
 	if err != nil {
-		errorHandeling.PrintError(err)
 		return models.Message{}, fmt.Errorf("error parsing command ID: %v", err)
 	}
 
@@ -105,79 +104,94 @@ func parseMessage(input string) (models.Message, error) {
 	}, nil
 }
 
+// CommandArgs are converted values of command parameters, by parameter name
+type CommandArgs map[string]interface{}
+
+// String returns value of ParamString or ParamName parameter, "" if optional parameter is missing
+func (a CommandArgs) String(name string) string {
+	value, _ := a[name].(string)
+	return value
+}
+
+// Int returns value of ParamInt or ParamPositiveInt parameter, 0 if optional parameter is missing
+func (a CommandArgs) Int(name string) int {
+	value, _ := a[name].(int)
+	return value
+}
+
+// Ints returns value of ParamCubeValues parameter
+func (a CommandArgs) Ints(name string) []int {
+	value, _ := a[name].([]int)
+	return value
+}
+
 /*
-ConvertParamClientCreateGame
+ConvertParams
 
-	checks if the parameters are correct and converts them to the correct type
+	checks that params match the schema (names, order, types) and converts their values,
+	names are checked against nameLength of the server
 */
-func ConvertParamClientCreateGame(params []constants.Params, names []string) (string, int, error) {
-	gameName := ""
-	maxPlayers := -1
-	var err error
+func ConvertParams(params []constants.Params, schema []constants.ParamSchema, nameLength models.NameLength) (CommandArgs, error) {
+	args := make(CommandArgs)
 
-	if len(params) != len(names) {
-		return gameName, maxPlayers, fmt.Errorf("invalid number of arguments")
+	// empty brackets are parsed as one empty param
+	if len(params) == 1 && params[0].Name == "" && params[0].Value == "" {
+		params = nil
 	}
 
-	for i := 0; i < len(params); i++ {
-		current := params[i]
-		if current.Name != names[i] {
-			return gameName, maxPlayers, fmt.Errorf("invalid number of arguments")
+	paramIndex := 0
+	for _, paramSchema := range schema {
+		if paramIndex >= len(params) || params[paramIndex].Name != paramSchema.Name {
+			if paramSchema.Optional {
+				continue
+			}
+			return nil, fmt.Errorf("invalid number of arguments")
 		}
 
-		switch current.Name {
-		case "gameName":
-			gameName = current.Value
-		case "maxPlayers":
-			maxPlayers, err = strconv.Atoi(current.Value)
-			if err != nil {
-				errorHandeling.PrintError(err)
-				return gameName, maxPlayers, fmt.Errorf("invalid number of arguments")
-			}
-		default:
-			return gameName, maxPlayers, fmt.Errorf("invalid number of arguments")
+		value, err := convertParamValue(params[paramIndex].Value, paramSchema.Type, nameLength)
+		if err != nil {
+			return nil, fmt.Errorf("invalid parameter %s: %w", paramSchema.Name, err)
 		}
+		args[paramSchema.Name] = value
+		paramIndex++
 	}
 
-	if gameName == "" || maxPlayers == -1 {
-		err := fmt.Errorf("values havent been converted")
-		errorHandeling.PrintError(err)
-		return gameName, maxPlayers, err
+	if paramIndex != len(params) {
+		return nil, fmt.Errorf("invalid number of arguments")
 	}
 
-	return gameName, maxPlayers, nil
+	return args, nil
 }
 
-func ConvertParamClientJoinGame(params []constants.Params, names []string) (string, error) {
-	gameName := ""
-
-	if len(params) != len(names) {
-		return gameName, fmt.Errorf("invalid number of arguments")
-	}
-
-	for i := 0; i < len(params); i++ {
-		current := params[i]
-		if current.Name != names[i] {
-			return gameName, fmt.Errorf("invalid number of arguments")
+func convertParamValue(value string, paramType constants.ParamType, nameLength models.NameLength) (interface{}, error) {
+	switch paramType {
+	case constants.ParamString:
+		if value == "" {
+			return nil, fmt.Errorf("empty value")
 		}
-
-		switch current.Name {
-		case "gameName":
-			gameName = current.Value
-			if !models.IsValidName(gameName) {
-				err := fmt.Errorf("invalid game name")
-				errorHandeling.PrintError(err)
-				return gameName, err
-			}
-		default:
-			return gameName, fmt.Errorf("invalid number of arguments")
+		return value, nil
+	case constants.ParamName:
+		if !nameLength.IsValidName(value) {
+			return nil, fmt.Errorf("invalid name")
 		}
+		return value, nil
+	case constants.ParamInt, constants.ParamPositiveInt:
+		intValue, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, err
+		}
+		if paramType == constants.ParamPositiveInt && intValue <= 0 {
+			return nil, fmt.Errorf("value is not positive")
+		}
+		return intValue, nil
+	case constants.ParamCubeValues:
+		return convertCubeValues(value)
 	}
 
-	return gameName, nil
+	return nil, fmt.Errorf("unknown parameter type")
 }
 
-func ConvertParamClientSelectedCubes(params []constants.Params, names []string) ([]int, error) {
+func convertCubeValues(value string) ([]int, error) {
 	//nested function isValidCubeValueList
 	isValidCubeValueList := func(cubeValue int) bool {
 
@@ -192,46 +206,53 @@ func ConvertParamClientSelectedCubes(params []constants.Params, names []string)
 
 	var cubeValueList []int
 
-	if len(params) != len(names) {
+	array, err := parseParamValueArray(value)
+	if err != nil {
 		return cubeValueList, fmt.Errorf("invalid number of arguments")
 	}
-
-	for i := 0; i < len(params); i++ {
-		current := params[i]
-		if current.Name != names[i] {
+	for _, value := range array {
+		intValue, err := strconv.Atoi(value)
+		if err != nil {
 			return cubeValueList, fmt.Errorf("invalid number of arguments")
 		}
 
-		switch current.Name {
-		case "cubeValues":
-			array, err := parseParamValueArray(current.Value)
-			if err != nil {
-				errorHandeling.PrintError(err)
-				return cubeValueList, fmt.Errorf("invalid number of arguments")
-			}
-			for _, value := range array {
-				intValue, err := strconv.Atoi(value)
-				if err != nil {
-					errorHandeling.PrintError(err)
-					return cubeValueList, fmt.Errorf("invalid number of arguments")
-				}
-
-				if !isValidCubeValueList(intValue) {
-					err := fmt.Errorf("not valid cube values")
-					errorHandeling.PrintError(err)
-					return cubeValueList, err
-				}
-
-				cubeValueList = append(cubeValueList, intValue)
-			}
-		default:
-			return cubeValueList, fmt.Errorf("invalid number of arguments")
+		if !isValidCubeValueList(intValue) {
+			err := fmt.Errorf("not valid cube values")
+			return cubeValueList, err
 		}
+
+		cubeValueList = append(cubeValueList, intValue)
 	}
 
 	return cubeValueList, nil
 }
 
+// ConvertParamClientMessageID removes optional client message ID from params
+// Return: message ID ("" if not present), params without the ID
+func ConvertParamClientMessageID(params []constants.Params) (string, []constants.Params) {
+	messageID := ""
+	var paramsWithoutID []constants.Params
+
+	for _, param := range params {
+		if param.Name == cParamClientMessageID {
+			messageID = param.Value
+			continue
+		}
+		paramsWithoutID = append(paramsWithoutID, param)
+	}
+
+	if messageID == "" {
+		return "", params
+	}
+
+	// same as params parsed from empty brackets
+	if len(paramsWithoutID) == 0 {
+		paramsWithoutID = []constants.Params{{}}
+	}
+
+	return messageID, paramsWithoutID
+}
+
 func parseParamValueArray(value string) ([]string, error) {
 	elementName := "value"
 
@@ -256,17 +277,14 @@ func parseParamValueArray(value string) ([]string, error) {
 	for _, valueStr := range valuesStr {
 		paramsArray, err := parseParamsStr(valueStr)
 		if err != nil {
-			errorHandeling.PrintError(err)
 			return valueArray, fmt.Errorf("invalid valueArray format")
 		}
 		if len(paramsArray) != 1 {
 			err := fmt.Errorf("invalid valueArray format")
-			errorHandeling.PrintError(err)
 			return valueArray, err
 		}
 		if paramsArray[0].Name != elementName {
 			err := fmt.Errorf("invalid valueArray format")
-			errorHandeling.PrintError(err)
 			return valueArray, err
 		}
 
@@ -287,13 +305,11 @@ func parseParamsStr(paramsString string) ([]constants.Params, error) {
 
 	if paramsString[0] != constants.CParamsBrackets.Opening[0] {
 		err := fmt.Errorf("invalid paramArray format")
-		errorHandeling.PrintError(err)
 		return paramArray, err
 	}
 
 	if paramsString[len(paramsString)-1] != constants.CParamsBrackets.Closing[0] {
 		err := fmt.Errorf("invalid paramArray format")
-		errorHandeling.PrintError(err)
 		return paramArray, err
 	}
 
@@ -304,7 +320,6 @@ func parseParamsStr(paramsString string) ([]constants.Params, error) {
 	for _, paramStr := range paramsStr {
 		parameter, err := parseParam(paramStr)
 		if err != nil {
-			errorHandeling.PrintError(err)
 			return paramArray, fmt.Errorf("invalid paramArray format")
 		}
 		paramArray = append(paramArray, parameter)
@@ -357,7 +372,6 @@ func parseMessagePlayerID(input string) (string, int, error) {
 
 	playerNickname, err := extractSubstring(input, opening, closing)
 	if err != nil {
-		errorHandeling.PrintError(err)
 		return playerNickname, playerNicknameSize, fmt.Errorf("invalid player ID format")
 	}
 
@@ -467,9 +481,12 @@ func ConvertMessageToNetworkString(message models.Message) (string, error) {
 	networkString += convertPlayerNicknameToNetworkString(message.PlayerNickname)
 
 	//Parameters
-	paramsStr, err := convertParamsToNetworkString(message.Parameters)
+	params := message.Parameters
+	if message.SequenceNumber != 0 {
+		params = appendParamSequenceNumber(params, message.SequenceNumber)
+	}
+	paramsStr, err := convertParamsToNetworkString(params)
 	if err != nil {
-		errorHandeling.PrintError(err)
 		return networkString, err
 	}
 	networkString += paramsStr
@@ -480,6 +497,20 @@ func ConvertMessageToNetworkString(message models.Message) (string, error) {
 	return networkString, nil
 }
 
+// appendParamSequenceNumber returns copy of params with sequence number as the last param
+func appendParamSequenceNumber(params []constants.Params, sequenceNumber int) []constants.Params {
+	result := make([]constants.Params, 0, len(params)+1)
+	for _, param := range params {
+		// same as params parsed from empty brackets
+		if param.Name == "" && param.Value == "" {
+			continue
+		}
+		result = append(result, param)
+	}
+
+	return append(result, constants.Params{Name: cParamSequenceNumber, Value: strconv.Itoa(sequenceNumber)})
+}
+
 func convertListToNetworkString(array interface{}, extractFields func(interface{}) map[string]string, fieldOrder []string) string {
 	arrayValue := reflect.ValueOf(array)
 	if arrayValue.Kind() != reflect.Slice {
@@ -0,0 +1,178 @@
+package scheduler
+
+import (
+	"container/heap"
+	"context"
+	"gameserver/internal/utils/errorHandeling"
+	"github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+//region DATA STRUCTURES
+
+type timer struct {
+	key      string
+	at       time.Time
+	callback func()
+	index    int
+}
+
+// timerHeap orders timers by time they are due
+type timerHeap []*timer
+
+func (h timerHeap) Len() int           { return len(h) }
+func (h timerHeap) Less(i, j int) bool { return h[i].at.Before(h[j].at) }
+func (h timerHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *timerHeap) Push(x interface{}) {
+	t := x.(*timer)
+	t.index = len(*h)
+	*h = append(*h, t)
+}
+
+func (h *timerHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	t := old[n-1]
+	old[n-1] = nil
+	t.index = -1
+	*h = old[:n-1]
+	return t
+}
+
+// Scheduler runs all server timers (pings, ack timeouts, total disconnects, turn timers) from one goroutine.
+// Every timer has a key, scheduling the same key again replaces the previous timer.
+type Scheduler struct {
+	timers timerHeap
+	byKey  map[string]*timer
+	mutex  sync.Mutex
+	wakeup chan struct{}
+	// isDebug keeps panics of callbacks fatal
+	isDebug bool
+	// log is logger of the server, panics of callbacks are logged to it
+	log *logrus.Logger
+}
+
+//endregion
+
+//region FUNCTIONS
+
+// CreateScheduler creates scheduler and starts its goroutine, pending timers never fire after ctx is done,
+// with isDebug panic of a callback is not recovered, otherwise it is logged to log
+func CreateScheduler(ctx context.Context, isDebug bool, log *logrus.Logger) *Scheduler {
+	s := &Scheduler{
+		timers:  timerHeap{},
+		byKey:   make(map[string]*timer),
+		wakeup:  make(chan struct{}, 1),
+		isDebug: isDebug,
+		log:     log,
+	}
+	go s.run(ctx)
+	return s
+}
+
+// Schedule runs callback after delay, callback runs on its own goroutine
+func (s *Scheduler) Schedule(key string, delay time.Duration, callback func()) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	at := time.Now().Add(delay)
+
+	if t, ok := s.byKey[key]; ok {
+		t.at = at
+		t.callback = callback
+		heap.Fix(&s.timers, t.index)
+	} else {
+		t = &timer{key: key, at: at, callback: callback}
+		heap.Push(&s.timers, t)
+		s.byKey[key] = t
+	}
+
+	s.notify()
+}
+
+// Cancel removes timer with key, does nothing if there is none
+func (s *Scheduler) Cancel(key string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	t, ok := s.byKey[key]
+	if !ok {
+		return
+	}
+
+	heap.Remove(&s.timers, t.index)
+	delete(s.byKey, key)
+
+	s.notify()
+}
+
+// IsScheduled returns true if timer with key is waiting
+func (s *Scheduler) IsScheduled(key string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	_, ok := s.byKey[key]
+	return ok
+}
+
+func (s *Scheduler) notify() {
+	select {
+	case s.wakeup <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Scheduler) run(ctx context.Context) {
+	sleep := time.NewTimer(time.Hour)
+	for {
+		s.mutex.Lock()
+		var due []*timer
+		now := time.Now()
+		for len(s.timers) > 0 && !s.timers[0].at.After(now) {
+			t := heap.Pop(&s.timers).(*timer)
+			delete(s.byKey, t.key)
+			due = append(due, t)
+		}
+
+		wait := time.Hour
+		if len(s.timers) > 0 {
+			wait = s.timers[0].at.Sub(now)
+		}
+		s.mutex.Unlock()
+
+		for _, t := range due {
+			go s.runCallback(t.callback)
+		}
+
+		if !sleep.Stop() {
+			select {
+			case <-sleep.C:
+			default:
+			}
+		}
+		sleep.Reset(wait)
+
+		select {
+		case <-sleep.C:
+		case <-s.wakeup:
+		case <-ctx.Done():
+			sleep.Stop()
+			return
+		}
+	}
+}
+
+//endregion
+
+// runCallback runs callback of a timer, its panic is logged and does not stop the server
+func (s *Scheduler) runCallback(callback func()) {
+	defer errorHandeling.RecoverPanic(s.log, s.isDebug, nil)
+
+	callback()
+}
@@ -0,0 +1,147 @@
+package scheduler
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	"io"
+	"testing"
+	"time"
+)
+
+const cTestTimeout = 2 * time.Second
+
+//region HELPERS
+
+func createTestScheduler(t *testing.T) *Scheduler {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	return CreateScheduler(ctx, false, createTestLog())
+}
+
+// createTestLog returns logger which discards recovered panics of callbacks
+func createTestLog() *logrus.Logger {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	return log
+}
+
+// expectFired waits for value from fired, it fails when nothing comes in cTestTimeout
+func expectFired(t *testing.T, fired <-chan string) string {
+	t.Helper()
+
+	select {
+	case key := <-fired:
+		return key
+	case <-time.After(cTestTimeout):
+		t.Fatalf("timer did not fire")
+		return ""
+	}
+}
+
+// expectNotFired fails when anything comes from fired within wait
+func expectNotFired(t *testing.T, fired <-chan string, wait time.Duration) {
+	t.Helper()
+
+	select {
+	case key := <-fired:
+		t.Fatalf("unexpected timer %s fired", key)
+	case <-time.After(wait):
+	}
+}
+
+//endregion
+
+//region TESTS
+
+func TestTimersFireInOrderOfTheirTime(t *testing.T) {
+	s := createTestScheduler(t)
+	fired := make(chan string, 3)
+
+	s.Schedule("late", 60*time.Millisecond, func() { fired <- "late" })
+	s.Schedule("early", 10*time.Millisecond, func() { fired <- "early" })
+	s.Schedule("middle", 35*time.Millisecond, func() { fired <- "middle" })
+
+	for _, expected := range []string{"early", "middle", "late"} {
+		if key := expectFired(t, fired); key != expected {
+			t.Fatalf("expected %s, got %s", expected, key)
+		}
+	}
+	if s.IsScheduled("late") {
+		t.Fatalf("fired timer is still scheduled")
+	}
+}
+
+func TestCancelledTimerDoesNotFire(t *testing.T) {
+	s := createTestScheduler(t)
+	fired := make(chan string, 2)
+
+	s.Schedule("cancelled", 20*time.Millisecond, func() { fired <- "cancelled" })
+	s.Schedule("kept", 40*time.Millisecond, func() { fired <- "kept" })
+	s.Cancel("cancelled")
+	// cancelling unknown key does nothing
+	s.Cancel("unknown")
+
+	if s.IsScheduled("cancelled") {
+		t.Fatalf("cancelled timer is still scheduled")
+	}
+	if key := expectFired(t, fired); key != "kept" {
+		t.Fatalf("expected kept, got %s", key)
+	}
+	expectNotFired(t, fired, 50*time.Millisecond)
+}
+
+func TestRescheduleReplacesTimer(t *testing.T) {
+	s := createTestScheduler(t)
+	fired := make(chan string, 2)
+
+	s.Schedule("ping", 20*time.Millisecond, func() { fired <- "first" })
+	s.Schedule("ping", 80*time.Millisecond, func() { fired <- "second" })
+
+	// the first callback was replaced and its time moved
+	expectNotFired(t, fired, 50*time.Millisecond)
+	if key := expectFired(t, fired); key != "second" {
+		t.Fatalf("expected second, got %s", key)
+	}
+	expectNotFired(t, fired, 50*time.Millisecond)
+}
+
+func TestRescheduleToEarlierTime(t *testing.T) {
+	s := createTestScheduler(t)
+	fired := make(chan string, 1)
+
+	start := time.Now()
+	s.Schedule("turn", time.Hour, func() { fired <- "turn" })
+	s.Schedule("turn", 10*time.Millisecond, func() { fired <- "turn" })
+
+	expectFired(t, fired)
+	if elapsed := time.Since(start); elapsed > cTestTimeout {
+		t.Fatalf("timer fired after %v", elapsed)
+	}
+}
+
+func TestTimersDoNotFireAfterContextIsDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := CreateScheduler(ctx, false, createTestLog())
+	fired := make(chan string, 1)
+
+	s.Schedule("ping", 30*time.Millisecond, func() { fired <- "ping" })
+	cancel()
+
+	expectNotFired(t, fired, 80*time.Millisecond)
+}
+
+func TestPanicOfCallbackDoesNotStopScheduler(t *testing.T) {
+	s := createTestScheduler(t)
+	fired := make(chan string, 1)
+
+	s.Schedule("panic", 0, func() { panic("callback failed") })
+	s.Schedule("next", 20*time.Millisecond, func() { fired <- "next" })
+
+	if key := expectFired(t, fired); key != "next" {
+		t.Fatalf("expected next, got %s", key)
+	}
+}
+
+//endregion
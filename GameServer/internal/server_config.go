@@ -0,0 +1,139 @@
+package internal
+
+import (
+	"fmt"
+	"gameserver/internal/config"
+	"gameserver/internal/logger"
+	"gameserver/internal/models"
+	"gameserver/internal/network/network_tls"
+	"gameserver/internal/utils/errorHandeling"
+	"github.com/sirupsen/logrus"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ConfigLoader reads configuration the same way as on start, e.g. config file, environment and flags
+type ConfigLoader func() (config.Config, error)
+
+// CreateServerConfig converts configuration to configuration of one server instance
+func CreateServerConfig(serverConfig config.Config) (models.ServerConfig, error) {
+	result := models.ServerConfig{
+		IP:               serverConfig.Server.IP,
+		Port:             fmt.Sprintf("%d", serverConfig.Server.Port),
+		Settings:         createSettings(serverConfig),
+		SnapshotFilePath: serverConfig.Snapshot.File,
+	}
+
+	result.WebSocketEnabled = serverConfig.WebSocket.Enabled
+	result.WebSocketIP = serverConfig.WebSocket.IP
+	result.WebSocketPort = fmt.Sprintf("%d", serverConfig.WebSocket.Port)
+	result.WebSocketPath = serverConfig.WebSocket.Path
+	result.WebSocketAllowedOrigins = serverConfig.WebSocket.AllowedOrigins
+
+	if serverConfig.TLS.Enabled {
+		tlsConfig, err := network_tls.CreateTLSConfig(serverConfig.TLS)
+		if err != nil {
+			return models.ServerConfig{}, fmt.Errorf("error setting up tls: %w", err)
+		}
+		result.TLSConfig = tlsConfig
+	}
+
+	result.HTTPEnabled = serverConfig.HTTP.Enabled
+	result.HTTPIP = serverConfig.HTTP.IP
+	result.HTTPPort = fmt.Sprintf("%d", serverConfig.HTTP.Port)
+
+	result.MetricsEnabled = serverConfig.Metrics.Enabled
+	result.MetricsIP = serverConfig.Metrics.IP
+	result.MetricsPort = fmt.Sprintf("%d", serverConfig.Metrics.Port)
+
+	result.HealthEnabled = serverConfig.Health.Enabled
+	result.HealthIP = serverConfig.Health.IP
+	result.HealthPort = fmt.Sprintf("%d", serverConfig.Health.Port)
+
+	result.AdminEnabled = serverConfig.Admin.Enabled
+	result.AdminNetwork = serverConfig.Admin.Network
+	result.AdminAddress = serverConfig.Admin.Address
+	result.AdminToken = serverConfig.Admin.Token
+
+	journal := serverConfig.Journal
+	result.JournalMaxMessages = journal.MaxMessagesPerSession
+	result.JournalRetention = time.Duration(journal.RetentionMinutes) * time.Minute
+	result.JournalSpillEnabled = journal.SpillEnabled
+	result.JournalFolder = journal.Folder
+	result.JournalRotation = logger.RotationConfig{
+		MaxSize:  int64(journal.MaxSizeMB) * 1024 * 1024,
+		MaxAge:   time.Duration(journal.MaxAgeHours) * time.Hour,
+		MaxFiles: journal.MaxFiles,
+		Compress: journal.Compress,
+	}
+
+	return result, nil
+}
+
+func createSettings(serverConfig config.Config) models.Settings {
+	network := serverConfig.Network
+	messages := serverConfig.Messages
+
+	return models.Settings{
+		AckTimeout:             time.Duration(network.AckTimeoutSeconds) * time.Second,
+		PingInterval:           time.Duration(network.PingIntervalSeconds) * time.Second,
+		ReconnectWindow:        time.Duration(network.ReconnectWindowSeconds) * time.Second,
+		WriteTimeout:           time.Duration(network.WriteTimeoutSeconds) * time.Second,
+		ShutdownTimeout:        time.Duration(network.ShutdownTimeoutSeconds) * time.Second,
+		SnapshotInterval:       time.Duration(serverConfig.Snapshot.IntervalSeconds) * time.Second,
+		HandlerStuckTime:       time.Duration(network.HandlerStuckSeconds) * time.Second,
+		SendQueueSize:          network.SendQueueSize,
+		QueueFullPolicy:        network.QueueFullPolicy,
+		MaxRetransmits:         network.MaxRetransmits,
+		MaxMessageSize:         messages.MaxSize,
+		MessageBufferSize:      messages.BufferSize,
+		ClientMessageCacheSize: messages.ClientCacheSize,
+		RateLimitMessages:      network.RateLimitMessages,
+		RateLimitWindow:        time.Duration(network.RateLimitWindowSeconds) * time.Second,
+		MOTD:                   serverConfig.Server.MOTD,
+		MaxScore:               serverConfig.Game.MaxScore,
+		TurnTimeout:            time.Duration(serverConfig.Game.TurnTimeoutSeconds) * time.Second,
+		NameLength: models.NameLength{
+			MinChars: messages.NameMinChars,
+			MaxChars: messages.NameMaxChars,
+		},
+		Debug: serverConfig.Debug,
+	}
+}
+
+// EnableReload lets server.Reload read configuration by load and apply keys which can change while server runs,
+// running is configuration the server was started with
+func EnableReload(server *models.Server, running config.Config, load ConfigLoader) {
+	var mutex sync.Mutex
+
+	server.SetReloadHandler(func() (models.ReloadResult, error) {
+		// SIGHUP and admin command may come at once
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		loaded, err := load()
+		if err != nil {
+			errorHandeling.PrintError(server.Log, err)
+			server.Log.Errorf("RELOAD: Config is not valid, nothing is changed: %v", err)
+			return models.ReloadResult{}, fmt.Errorf("error reloading config: %w", err)
+		}
+
+		var result models.ReloadResult
+		previousLevel := running.Log.Level
+		running, result.Applied, result.RestartRequired = config.Reload(running, loaded)
+
+		if running.Log.Level != previousLevel {
+			level, err := logrus.ParseLevel(running.Log.Level)
+			if err != nil {
+				errorHandeling.PrintError(server.Log, err)
+				return models.ReloadResult{}, fmt.Errorf("error setting log level: %w", err)
+			}
+			server.Log.SetLevel(level)
+		}
+		server.SetSettings(createSettings(running))
+
+		server.Log.Infof("RELOAD: Applied [%s], restart required for [%s]", strings.Join(result.Applied, ", "), strings.Join(result.RestartRequired, ", "))
+		return result, nil
+	})
+}
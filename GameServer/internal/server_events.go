@@ -0,0 +1,54 @@
+package internal
+
+import (
+	"fmt"
+	"gameserver/internal/models"
+	"gameserver/internal/network"
+)
+
+// subscribeEvents connects network updates, logging and persistence to game events of the server
+func subscribeEvents(server *models.Server) {
+	network.SubscribeEvents(server)
+
+	server.Events.SubscribeAll(func(event models.Event) error {
+		server.Log.Infof("EVENT: %s %s", event.EventName(), describeEvent(event))
+		return nil
+	})
+
+	// finished, aborted and started games are saved right away, not only by the periodic snapshot.
+	// Save runs on its own goroutine because snapshot waits for the game goroutine, saves are serialized by the server.
+	models.Subscribe(server.Events, func(event models.GameStarted) error {
+		go saveSnapshot(server)
+		return nil
+	})
+	models.Subscribe(server.Events, func(event models.GameEnded) error {
+		go saveSnapshot(server)
+		return nil
+	})
+	models.Subscribe(server.Events, func(event models.GameAborted) error {
+		go saveSnapshot(server)
+		return nil
+	})
+}
+
+func describeEvent(event models.Event) string {
+	switch e := event.(type) {
+	case models.PlayerJoined:
+		return "player=" + e.Player.GetNickname() + " game=" + e.Game.GetName()
+	case models.GameStarted:
+		return "player=" + e.Player.GetNickname() + " game=" + e.Game.GetName()
+	case models.TurnStarted:
+		return "player=" + e.Player.GetNickname() + " game=" + e.Game.GetName()
+	case models.DiceRolled:
+		return fmt.Sprintf("player=%s game=%s cubes=%v can_be_played=%v", e.Player.GetNickname(), e.Game.GetName(), e.CubeValues, e.CanBePlayed)
+	case models.ScoreChanged:
+		return fmt.Sprintf("player=%s game=%s score=%d", e.Player.GetNickname(), e.Game.GetName(), e.Score)
+	case models.GameEnded:
+		return fmt.Sprintf("winner=%s game=%s score=%d", e.Winner.GetNickname(), e.Game.GetName(), e.Score)
+	case models.GameAborted:
+		return "game=" + e.Game.GetName()
+	case models.PlayerDisconnected:
+		return fmt.Sprintf("player=%s total=%v", e.Player.GetNickname(), e.IsTotal)
+	}
+	return ""
+}
@@ -1,180 +1,402 @@
 package internal
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"gameserver/internal/admin"
 	"gameserver/internal/command_processing"
+	"gameserver/internal/http_api"
 	"gameserver/internal/logger"
 	"gameserver/internal/models"
-	"gameserver/internal/models/state_machine"
 	"gameserver/internal/network"
+	"gameserver/internal/network/network_websocket"
 	"gameserver/internal/utils/constants"
 	"gameserver/internal/utils/errorHandeling"
 	"net"
-	"os"
+	"net/http"
 	"time"
 )
 
-func StartServer() {
-	RunServer()
+const cShutdownPollInterval = 100 * time.Millisecond
+
+// cReadHeaderTimeout limits reading of request headers on HTTP listeners, so idle clients cannot hold connections
+const cReadHeaderTimeout = 5 * time.Second
+
+// StartServer listens on configured addresses and serves connections until ctx is done, then shuts the server down
+func StartServer(ctx context.Context, server *models.Server) error {
+	err := restoreSnapshot(server)
+	if err != nil {
+		return err
+	}
+
+	err = Listen(server)
+	if err != nil {
+		return err
+	}
+
+	go Serve(server)
+	scheduleSnapshot(server)
+
+	<-ctx.Done()
+	return Shutdown(server, server.GetSettings().ShutdownTimeout)
 }
 
-// RunServer starts a TCP server that listens on the specified port.
-func RunServer() {
-	ln, err := net.Listen(constants.CConnType, constants.CConIPadress+":"+constants.CConnPort)
+// Shutdown stops accepting connections, sends ServerShutdown to all players and lets turns in progress finish.
+// Connections are closed when no turn is running or timeout elapses.
+func Shutdown(server *models.Server, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	server.Log.Info("SHUTDOWN: Stopping server")
+
+	server.SetShuttingDown()
+	err := server.Close()
 	if err != nil {
-		errorHandeling.PrintError(err)
-		fmt.Println("Error listening:", err)
-		os.Exit(1)
+		errorHandeling.PrintError(server.Log, err)
 	}
-	defer func() {
-		errorHandeling.PrintError(fmt.Errorf("Error closing listener:", ln.Close()))
-		err := ln.Close()
-		if err != nil {
-			errorHandeling.PrintError(err)
-			fmt.Println("Error closing listener:", err)
+
+	var players []*models.Player
+	for _, player := range server.PlayerList.GetValuesArray() {
+		if player.IsConnected() {
+			players = append(players, player)
 		}
-	}()
-	fmt.Println("Server is listening on " + constants.CConIPadress + ":" + constants.CConnPort)
+	}
+	err = network.CommunicationServerShutdown(server, players)
+	if err != nil {
+		errorHandeling.PrintError(server.Log, err)
+	}
 
-	for {
-		conn, err := ln.Accept()
+	var shutdownErr error
+	for isTurnInProgress(server) {
+		if time.Now().After(deadline) {
+			shutdownErr = fmt.Errorf("shutdown timeout elapsed with turns in progress")
+			break
+		}
+		time.Sleep(cShutdownPollInterval)
+	}
+
+	saveSnapshot(server)
+
+	// closes connections of all handlers and stops timers
+	server.Cancel()
+	for _, game := range server.GameList.GetValuesArray() {
+		game.Stop()
+	}
+
+	if !server.WaitConnections(time.Until(deadline)) && shutdownErr == nil {
+		shutdownErr = fmt.Errorf("shutdown timeout elapsed with connections open")
+	}
+	if !network.FlushConnections(server, deadline) && shutdownErr == nil {
+		shutdownErr = fmt.Errorf("shutdown timeout elapsed with messages not sent")
+	}
+
+	// readiness has been reported until the drain ended
+	err = server.CloseMetrics()
+	if err != nil {
+		errorHandeling.PrintError(server.Log, err)
+	}
+
+	err = server.Journal.CloseSpill()
+	if err != nil {
+		errorHandeling.PrintError(server.Log, err)
+	}
+
+	if shutdownErr != nil {
+		errorHandeling.PrintError(server.Log, shutdownErr)
+		return shutdownErr
+	}
+
+	server.Log.Info("SHUTDOWN: Server stopped")
+	return nil
+}
+
+// isTurnInProgress returns true if a connected player is in the middle of a turn
+func isTurnInProgress(server *models.Server) bool {
+	for _, game := range server.GameList.GetValuesArray() {
+		if game.GetState() != models.Running {
+			continue
+		}
+
+		player, err := game.GetTurnPlayer()
 		if err != nil {
-			errorHandeling.PrintError(err)
-			fmt.Println("Error accepting connection:", err)
 			continue
 		}
-		go handleConnection(conn)
+		if player.IsConnected() && player.IsInTurn() {
+			return true
+		}
 	}
+
+	return false
 }
 
+// Listen subscribes game events, registers metrics, opens listeners of the server (http api, metrics and admin too when enabled)
+// and journal file when spill is enabled, with port "0" the chosen port is available from server.GetAddress
+func Listen(server *models.Server) error {
+	subscribeEvents(server)
+	registerMetrics(server)
 
-func _tryStartTurn(conn net.Conn) error {
-	player := models.GetInstancePlayerList().GetPlayerByConnection(conn)
-	if player == nil {
-		return nil
+	config := server.Config
+
+	ln, err := listen(server, config.IP+":"+config.Port)
+	if err != nil {
+		errorHandeling.PrintError(server.Log, err)
+		return fmt.Errorf("Error listening: %w", err)
 	}
+	server.SetListener(ln)
 
-	game := models.GetInstanceGameList().GetPlayersGame(player)
-	if game == nil {
-		return nil
+	if config.WebSocketEnabled {
+		wsLn, err := listen(server, config.WebSocketIP+":"+config.WebSocketPort)
+		if err != nil {
+			errorHandeling.PrintError(server.Log, err)
+			_ = server.Close()
+			return fmt.Errorf("Error listening websocket: %w", err)
+		}
+		server.SetWebSocketListener(wsLn)
 	}
 
-	if !game.IsPlayerTurn(player) {
-		return nil
+	if config.HTTPEnabled {
+		httpLn, err := listen(server, config.HTTPIP+":"+config.HTTPPort)
+		if err != nil {
+			errorHandeling.PrintError(server.Log, err)
+			_ = server.Close()
+			return fmt.Errorf("Error listening http: %w", err)
+		}
+		server.SetHTTPListener(httpLn)
 	}
 
-	if player.IsInTurn() {
-		//already have been send serverstart
-		return nil
+	if config.MetricsEnabled {
+		metricsLn, err := net.Listen(constants.CConnType, config.MetricsIP+":"+config.MetricsPort)
+		if err != nil {
+			errorHandeling.PrintError(server.Log, err)
+			_ = server.Close()
+			return fmt.Errorf("Error listening metrics: %w", err)
+		}
+		server.SetMetricsListener(metricsLn)
 	}
 
-	//is player turn and havent been started yet
-	err := command_processing.ProcessPlayerTurn(game)
+	if config.HealthEnabled {
+		healthLn, err := net.Listen(constants.CConnType, config.HealthIP+":"+config.HealthPort)
+		if err != nil {
+			errorHandeling.PrintError(server.Log, err)
+			_ = server.Close()
+			_ = server.CloseMetrics()
+			return fmt.Errorf("Error listening health: %w", err)
+		}
+		server.SetHealthListener(healthLn)
+	}
+
+	err = admin.Listen(server)
 	if err != nil {
-		err = fmt.Errorf("Error processing start turn: %w", err)
-		errorHandeling.PrintError(err)
+		_ = server.Close()
+		_ = server.CloseMetrics()
 		return err
 	}
 
+	if config.JournalSpillEnabled {
+		err = server.Journal.OpenSpill(config.JournalFolder, config.JournalRotation)
+		if err != nil {
+			errorHandeling.PrintError(server.Log, err)
+			_ = server.Close()
+			_ = server.CloseMetrics()
+			return fmt.Errorf("Error opening journal: %w", err)
+		}
+	}
+
 	return nil
 }
 
-func handleConnection(conn net.Conn) {
-	logger.Log.Info("New connection from " + conn.RemoteAddr().String())
-	go continuousSendPing(conn)
+// Serve accepts connections on listeners opened by Listen, it returns after server.Close
+func Serve(server *models.Server) {
+	if wsAddress := server.GetWebSocketAddress(); wsAddress != nil {
+		go RunWebSocketServer(server)
+	}
+	go http_api.Serve(server)
+	go RunMetricsServer(server)
+	go RunHealthServer(server)
+	go admin.Serve(server)
+	RunServer(server)
+}
+
+// listen opens tcp listener on address, wrapped in TLS when it is configured
+func listen(server *models.Server, address string) (net.Listener, error) {
+	ln, err := net.Listen(constants.CConnType, address)
+	if err != nil {
+		return nil, err
+	}
+
+	if server.Config.TLSConfig != nil {
+		ln = tls.NewListener(ln, server.Config.TLSConfig)
+	}
+
+	return ln, nil
+}
+
+// RunServer accepts TCP connections on the server listener.
+func RunServer(server *models.Server) {
+	ln := server.GetListener()
+	fmt.Println("Server is listening on " + ln.Addr().String())
+
+	server.SetAccepting(true)
+	defer server.SetAccepting(false)
 
 	for {
-		// if connection is closed
-		if conn == nil {
-			return
+		conn, err := ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			errorHandeling.PrintError(server.Log, err)
+			fmt.Println("Error accepting connection:", err)
+			continue
 		}
-		player := models.GetInstancePlayerList().GetPlayerByConnection(conn)
+		go handleConnection(server, conn)
+	}
+}
 
-		//isConnected := checkTotalDisconnect(player)
-		//logger.Log.Debugf("TOTAL_DISCONNECT: Check Total disconect IsConnected: " + fmt.Sprint(isConnected))
-		//if !isConnected {
-		//	return
-		//}
+// RunWebSocketServer serves HTTP on the WebSocket listener, requests on the configured path are upgraded to WebSocket
+// and handled the same way as TCP connections, so web and desktop players share PlayerList and GameList.
+func RunWebSocketServer(server *models.Server) {
+	ln := server.GetWebSocketListener()
 
-		// client havent responded to some of the messages
-		isResponseTimeout, err := checkTimeoutResponseSuccess(player)
+	mux := http.NewServeMux()
+	mux.HandleFunc(server.Config.WebSocketPath, func(w http.ResponseWriter, r *http.Request) {
+		conn, err := network_websocket.Upgrade(w, r, server.GetSettings().MaxMessageSize, server.Config.WebSocketAllowedOrigins)
 		if err != nil {
-			err = fmt.Errorf("Error checking timeout: %w", err)
-			errorHandeling.PrintError(err)
+			errorHandeling.PrintError(server.Log, fmt.Errorf("Error upgrading websocket connection: %w", err))
 			return
 		}
-		if isResponseTimeout {
-			err := disconnectPlayer(player)
-			if err != nil {
-				err = fmt.Errorf("Error disconnecting player: %w", err)
-				errorHandeling.PrintError(err)
-				return
-			}
+		handleConnection(server, conn)
+	})
+
+	httpServer := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: cReadHeaderTimeout,
+	}
+
+	fmt.Println("WebSocket server is listening on " + ln.Addr().String() + server.Config.WebSocketPath)
+	err := httpServer.Serve(ln)
+	if err != nil && !errors.Is(err, net.ErrClosed) {
+		errorHandeling.PrintError(server.Log, err)
+		fmt.Println("Error serving websocket:", err)
+	}
+}
+
+func _tryStartTurn(server *models.Server, session *models.Session) error {
+	player := session.GetPlayer()
+	if player == nil {
+		return nil
+	}
+
+	game := session.GetGame()
+	if game == nil {
+		return nil
+	}
+
+	return game.Execute(func() error {
+		if !game.IsPlayerTurn(player) {
+			return nil
+		}
+
+		if player.IsInTurn() {
+			//already have been send serverstart
+			return nil
+		}
+
+		//is player turn and havent been started yet
+		err := command_processing.ProcessPlayerTurn(server, game)
+		if err != nil {
+			err = fmt.Errorf("Error processing start turn: %w", err)
+			errorHandeling.PrintError(server.Log, err)
+			return err
+		}
+
+		return nil
+	})
+}
+
+func handleConnection(server *models.Server, conn net.Conn) {
+	server.AddConnection()
+	defer server.DoneConnection()
+	// closed connection is remembered until its handler ends
+	defer network.ForgetConnection(server, conn)
+
+	// server stop closes the connection, which ends the blocking read below
+	stopClose := context.AfterFunc(server.Context(), func() {
+		err := network.CloseConnection(server, conn)
+		if err != nil {
+			errorHandeling.PrintError(server.Log, err)
+		}
+	})
+	defer stopClose()
+
+	session := models.CreateSession(server, conn)
+	defer session.EndSession()
+	session.Log().Info("New connection from " + conn.RemoteAddr().String())
+
+	// panic while handling one client drops only this connection
+	defer errorHandeling.RecoverPanic(server.Log, server.GetSettings().Debug, func(err error) {
+		disconnectSession(server, session)
+	})
+
+	for {
+		// if connection is closed
+		if conn == nil {
 			return
 		}
+		player := session.GetPlayer()
 
 		// StartTurn
-		err = _tryStartTurn(conn)
+		err := _tryStartTurn(server, session)
 		if err != nil {
-			errorHandeling.PrintError(err)
+			errorHandeling.LogError(session.Log(), err)
 			fmt.Println("Error starting turn:", err)
 			return
 		}
 
-		messageList, isTimeout, err := network.Read(conn)
+		messageList, isTimeout, err := network.Read(server, conn)
 		if err != nil {
+			// connection closed by server stop
+			if server.Context().Err() != nil {
+				return
+			}
+
 			err = fmt.Errorf("Error reading: %w", err)
-			errorHandeling.PrintError(err)
+			errorHandeling.LogError(session.Log(), err)
 			//if error when reading client login messsage
 			if player == nil {
-				err := network.CloseConnection(conn)
+				err := network.CloseConnection(server, conn)
 				if err != nil {
 					err = fmt.Errorf("Error closing: %w", err)
-					errorHandeling.PrintError(err)
-					return
+					errorHandeling.PrintError(server.Log, err)
 				}
+				return
+			}
+
+			// already disconnected by timeout
+			if !player.IsConnected() {
+				return
 			}
 
 			//if error when reading client message
-			err = disconnectPlayer(player)
+			err = disconnectPlayer(server, player)
 			if err != nil {
 				err = fmt.Errorf("Error disconnecting player: %w", err)
-				errorHandeling.PrintError(err)
+				errorHandeling.PrintError(server.Log, err)
 				return
 			}
-			//network.ImidiateDisconnectPlayer(player.GetNickname())
+			//network.ImidiateDisconnectPlayer(server, player.GetNickname())
 
 			return
 		}
+		// pings and ack timeouts are handled by scheduler
 		if isTimeout {
-			//if client havenot logeed yet - waiting for login
-			if player == nil {
-				continue
-			}
-
-			//if in reconnect mode
-			if !player.IsConnected() {
-				continue
-			}
-
-			if player.GetCurrentStateName() == state_machine.StateNameMap.StateReconnect {
-				continue
-			}
-
-			//if client have logged in - reading with ping
-			err := command_processing.ProcessSendPingPlayer(player)
-			if err != nil {
-				err = fmt.Errorf("Timeout for player: %w", err)
-				errorHandeling.PrintError(err)
-				return
-			}
 			continue
 		}
 
 		for _, message := range messageList {
-			err = command_processing.ProcessMessage(message, conn)
+			err = command_processing.ProcessMessage(message, session)
 			if err != nil {
-				errorHandeling.PrintError(err)
+				errorHandeling.LogError(session.Log().WithField(logger.FieldCorrelationID, message.CorrelationID), err)
 				fmt.Println("Error processing message:", err)
 				return
 			}
@@ -182,92 +404,37 @@ func handleConnection(conn net.Conn) {
 	}
 }
 
-func continuousSendPing(conn net.Conn) {
-	for {
-		if conn == nil {
-			return
-		}
-		player := models.GetInstancePlayerList().GetPlayerByConnection(conn)
-
-		if player == nil {
-			continue
-		}
-
-		can_fire, err := player.GetStateMachine().CanFire(constants.CGCommands.ServerPingPlayer.Trigger)
-		if err != nil {
-			err = fmt.Errorf("Error checking if can fire: %w", err)
-			errorHandeling.PrintError(err)
-			return
-		}
-		if !can_fire {
-			continue
-		}
-
-		err = command_processing.ProcessSendPingPlayer(player)
+// disconnectSession disconnects player of the session, or only closes the connection if nobody has logged in
+func disconnectSession(server *models.Server, session *models.Session) {
+	player := session.GetPlayer()
+	if player == nil || !player.IsConnected() {
+		err := network.CloseConnection(server, session.GetConnection())
 		if err != nil {
-			logger.Log.Info("Couldne sending ping: " + err.Error())
-
-			err = disconnectPlayer(player)
-			if err != nil {
-				err = fmt.Errorf("Error disconnecting player: %w", err)
-				errorHandeling.PrintError(err)
-				return
-			}
-			return
+			errorHandeling.PrintError(server.Log, err)
 		}
-
-		time.Sleep(constants.CPingTime)
+		return
 	}
-}
 
-func disconnectPlayer(player *models.Player) error {
-	if player == nil {
-		errorHandeling.AssertError(fmt.Errorf("Error disconnecting player: player is nil"))
-	}
-
-	err := network.DisconnectPlayerConnection(player)
+	err := disconnectPlayer(server, player)
 	if err != nil {
 		err = fmt.Errorf("Error disconnecting player: %w", err)
-		errorHandeling.PrintError(err)
-		return err
+		errorHandeling.PrintError(server.Log, err)
 	}
-
-	return nil
 }
 
-
-func checkTimeoutResponseSuccess(player *models.Player) (bool, error) {
+func disconnectPlayer(server *models.Server, player *models.Player) error {
 	if player == nil {
-		return false, nil
-	}
-
-	if !player.IsResponseSuccessExpected() {
-		return false, nil
+		errorHandeling.AssertError(server.Log, fmt.Errorf("Error disconnecting player: player is nil"))
 	}
 
-	isTimeout, err := player.IsResponseExpectedTimeout()
+	err := server.GameList.ExecuteInPlayersGame(player, func() error {
+		return network.DisconnectPlayerConnection(server, player)
+	})
 	if err != nil {
-		errorHandeling.PrintError(err)
-		return false, fmt.Errorf("error checking timeout %w", err)
-	}
-
-	return isTimeout, nil
-}
-
-func checkTotalDisconnect(player *models.Player) bool {
-	if player == nil {
-		return true
-	}
-
-	if player.IsConnected() {
-		return true
-	}
-
-	if !player.IsTotalDisconnectTimeout() {
-		return true
+		err = fmt.Errorf("Error disconnecting player: %w", err)
+		errorHandeling.PrintError(server.Log, err)
+		return err
 	}
 
-	network.ImidiateDisconnectPlayer(player.GetNickname())
-
-	return false
+	return nil
 }
@@ -0,0 +1,143 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"gameserver/internal/health"
+	"gameserver/internal/metrics"
+	"gameserver/internal/models"
+	"gameserver/internal/utils/errorHandeling"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// registerMetrics adds gauges computed from PlayerList and GameList and measures turns from game events
+func registerMetrics(server *models.Server) {
+	registry := server.Metrics.Registry
+
+	registry.NewGaugeFunc("gameserver_sessions", "Players by connection, disconnected players wait for reconnect.", "state", func() map[string]float64 {
+		values := map[string]float64{
+			models.ConnectionStates.Connected.String():    0,
+			models.ConnectionStates.Disconnected.String(): 0,
+		}
+		for _, player := range server.PlayerList.GetValuesArray() {
+			state := player.GetConnectionState()
+			if state == models.ConnectionStates.TotalDisconnect {
+				continue
+			}
+			values[state.String()]++
+		}
+		return values
+	})
+
+	registry.NewGaugeFunc("gameserver_games", "Games by state.", "state", func() map[string]float64 {
+		values := map[string]float64{
+			models.Created.String(): 0,
+			models.Running.String(): 0,
+			models.Ended.String():   0,
+		}
+		for _, game := range server.GameList.GetValuesArray() {
+			values[game.GetState().String()]++
+		}
+		return values
+	})
+
+	subscribeTurnDuration(server)
+}
+
+// subscribeTurnDuration observes a turn when the next one starts or the game is over
+func subscribeTurnDuration(server *models.Server) {
+	var mutex sync.Mutex
+	turnStarts := make(map[*models.Game]time.Time)
+
+	endTurn := func(game *models.Game, isGameOver bool) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		start, ok := turnStarts[game]
+		if ok {
+			metrics.ObserveSince(server.Metrics.TurnDuration, start)
+		}
+		if isGameOver {
+			delete(turnStarts, game)
+			return
+		}
+		turnStarts[game] = time.Now()
+
+		// games removed because of not enough players have no event of their end
+		for startedGame := range turnStarts {
+			if !server.GameList.HasValue(startedGame) {
+				delete(turnStarts, startedGame)
+			}
+		}
+	}
+
+	models.Subscribe(server.Events, func(event models.TurnStarted) error {
+		endTurn(event.Game, false)
+		return nil
+	})
+	models.Subscribe(server.Events, func(event models.GameEnded) error {
+		endTurn(event.Game, true)
+		return nil
+	})
+	models.Subscribe(server.Events, func(event models.GameAborted) error {
+		endTurn(event.Game, true)
+		return nil
+	})
+}
+
+// RunMetricsServer serves metrics on /metrics and checks for process supervisor on /healthz and /readyz
+// of the metrics listener, it returns after server.Close
+func RunMetricsServer(server *models.Server) {
+	ln := server.GetMetricsListener()
+	if ln == nil {
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", server.Metrics.Registry.Handler())
+	handleHealthChecks(mux, server)
+
+	httpServer := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: cReadHeaderTimeout,
+	}
+
+	fmt.Println("Metrics are listening on " + ln.Addr().String() + "/metrics")
+	err := httpServer.Serve(ln)
+	if err != nil && !errors.Is(err, net.ErrClosed) {
+		errorHandeling.PrintError(server.Log, err)
+		fmt.Println("Error serving metrics:", err)
+	}
+}
+
+// RunHealthServer serves only /healthz and /readyz on the health listener, so checks do not need metrics,
+// it returns after server.CloseMetrics
+func RunHealthServer(server *models.Server) {
+	ln := server.GetHealthListener()
+	if ln == nil {
+		return
+	}
+
+	mux := http.NewServeMux()
+	handleHealthChecks(mux, server)
+
+	httpServer := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: cReadHeaderTimeout,
+	}
+
+	fmt.Println("Health checks are listening on " + ln.Addr().String() + "/healthz")
+	err := httpServer.Serve(ln)
+	if err != nil && !errors.Is(err, net.ErrClosed) {
+		errorHandeling.PrintError(server.Log, err)
+		fmt.Println("Error serving health checks:", err)
+	}
+}
+
+func handleHealthChecks(mux *http.ServeMux, server *models.Server) {
+	mux.HandleFunc("GET /healthz", health.HandleHealth(server))
+	mux.HandleFunc("GET /readyz", health.HandleReady(server))
+}
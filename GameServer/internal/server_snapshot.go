@@ -0,0 +1,86 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"gameserver/internal/models"
+	"gameserver/internal/network"
+	"gameserver/internal/utils/errorHandeling"
+	"os"
+)
+
+const cSnapshotTimerKey = "snapshot"
+
+// cCorruptSnapshotSuffix is appended to snapshot file which cannot be decoded
+const cCorruptSnapshotSuffix = ".corrupt"
+
+// restoreSnapshot loads games saved by previous run, restored players get the usual reconnect window
+func restoreSnapshot(server *models.Server) error {
+	filePath := server.Config.SnapshotFilePath
+	if filePath == "" {
+		return nil
+	}
+
+	snapshot, exists, err := models.ReadSnapshotFile(filePath)
+	if errors.Is(err, models.ErrSnapshotCorrupt) {
+		// corrupt file is kept for inspection, the server starts empty
+		server.Log.Errorf("SNAPSHOT: %v, moved to %s and starting without snapshot", err, filePath+cCorruptSnapshotSuffix)
+		err = os.Rename(filePath, filePath+cCorruptSnapshotSuffix)
+		if err != nil {
+			errorHandeling.PrintError(server.Log, err)
+			return fmt.Errorf("error moving corrupt snapshot: %w", err)
+		}
+		return nil
+	}
+	if err != nil {
+		errorHandeling.PrintError(server.Log, err)
+		return fmt.Errorf("error reading snapshot: %w", err)
+	}
+	if !exists {
+		return nil
+	}
+
+	players, gameCount, err := server.RestoreSnapshot(snapshot)
+	if err != nil {
+		errorHandeling.PrintError(server.Log, err)
+		return fmt.Errorf("error restoring snapshot: %w", err)
+	}
+
+	for _, player := range players {
+		network.ScheduleTotalDisconnect(server, player)
+	}
+
+	server.Log.Infof("SNAPSHOT: Restored %d players and %d games from %s", len(players), gameCount, filePath)
+	return nil
+}
+
+// scheduleSnapshot saves snapshot every snapshot interval until the server stops
+func scheduleSnapshot(server *models.Server) {
+	if server.Config.SnapshotFilePath == "" {
+		return
+	}
+
+	server.Scheduler.Schedule(cSnapshotTimerKey, server.GetSettings().SnapshotInterval, func() {
+		if server.Context().Err() != nil || server.IsShuttingDown() {
+			return
+		}
+
+		saveSnapshot(server)
+		scheduleSnapshot(server)
+	})
+}
+
+func saveSnapshot(server *models.Server) {
+	filePath := server.Config.SnapshotFilePath
+	if filePath == "" {
+		return
+	}
+
+	err := server.SaveSnapshot(filePath)
+	if err != nil {
+		errorHandeling.PrintError(server.Log, err)
+		return
+	}
+
+	server.Log.Debugf("SNAPSHOT: Saved to %s", filePath)
+}
@@ -0,0 +1,31 @@
+package internal
+
+import (
+	"gameserver/internal/config"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCorruptSnapshotIsMovedAside(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "snapshot.json")
+	err := os.WriteFile(filePath, []byte(`{"players": [`), 0o644)
+	if err != nil {
+		t.Fatalf("cannot write snapshot: %v", err)
+	}
+
+	server := startTestServer(t, func(serverConfig *config.Config) {
+		serverConfig.Snapshot.File = filePath
+	})
+
+	corrupt, err := os.ReadFile(filePath + cCorruptSnapshotSuffix)
+	if err != nil {
+		t.Fatalf("corrupt snapshot was not moved aside: %v", err)
+	}
+	if string(corrupt) != `{"players": [` {
+		t.Fatalf("corrupt snapshot was changed: %q", corrupt)
+	}
+	if len(server.GameList.GetValuesArray()) != 0 || len(server.PlayerList.GetValuesArray()) != 0 {
+		t.Fatalf("server did not start empty")
+	}
+}
@@ -0,0 +1,201 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"gameserver/internal/config"
+	"gameserver/internal/models"
+	"gameserver/internal/network"
+	"gameserver/internal/utils/constants"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const cTestTimeout = 5 * time.Second
+
+//region HELPERS
+
+// startTestServer starts server on ephemeral port of localhost, configure may change the default configuration
+func startTestServer(t *testing.T, configure func(serverConfig *config.Config)) *models.Server {
+	t.Helper()
+
+	serverConfig := config.Default()
+	serverConfig.Server.IP = "127.0.0.1"
+	serverConfig.Server.Port = 0
+	serverConfig.Snapshot.File = ""
+	if configure != nil {
+		configure(&serverConfig)
+	}
+
+	instanceConfig, err := CreateServerConfig(serverConfig)
+	if err != nil {
+		t.Fatalf("cannot create server config: %v", err)
+	}
+	instanceConfig.Log = logrus.New()
+	instanceConfig.Log.SetOutput(io.Discard)
+
+	server := models.CreateServer(instanceConfig)
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- StartServer(ctx, server)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		select {
+		case <-stopped:
+		case <-time.After(cTestTimeout):
+			t.Errorf("server did not stop")
+		}
+	})
+
+	deadline := time.Now().Add(cTestTimeout)
+	for server.GetAddress() == nil {
+		select {
+		case err := <-stopped:
+			t.Fatalf("server stopped before listening: %v", err)
+		default:
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("server is not listening")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return server
+}
+
+type testClient struct {
+	conn     net.Conn
+	reader   *bufio.Reader
+	nickname string
+}
+
+func dialTestClient(t *testing.T, server *models.Server, nickname string) *testClient {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", server.GetAddress().String())
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	_ = conn.SetDeadline(time.Now().Add(cTestTimeout))
+
+	return &testClient{conn: conn, reader: bufio.NewReader(conn), nickname: nickname}
+}
+
+func (c *testClient) send(t *testing.T, commandID int, params string) {
+	t.Helper()
+
+	timeStamp := time.Now().Format("2006-01-02 15:04:05.000000")
+	message := fmt.Sprintf("%s%02d%s{%s}%s%s", constants.CMessageSignature, commandID, timeStamp, c.nickname, params, constants.CMessageEndDelimiter)
+	_, err := c.conn.Write([]byte(message))
+	if err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+}
+
+// read returns command id and the whole line of the next message
+func (c *testClient) read(t *testing.T) (int, string) {
+	t.Helper()
+
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	prefixLength := len(constants.CMessageSignature)
+	commandID, err := strconv.Atoi(line[prefixLength : prefixLength+2])
+	if err != nil {
+		t.Fatalf("invalid message %q", line)
+	}
+	return commandID, line
+}
+
+func (c *testClient) expect(t *testing.T, commandID int) string {
+	t.Helper()
+
+	receivedID, line := c.read(t)
+	if receivedID != commandID {
+		t.Fatalf("expected command %d, got %q", commandID, line)
+	}
+	return line
+}
+
+func (c *testClient) login(t *testing.T) {
+	t.Helper()
+
+	c.send(t, constants.CGCommands.ClientLogin.CommandID, "{}")
+	c.expect(t, constants.CGCommands.ResponseServerGameList.CommandID)
+}
+
+//endregion
+
+//region TESTS
+
+func TestServersOnEphemeralPortsAreIsolated(t *testing.T) {
+	first := startTestServer(t, nil)
+	second := startTestServer(t, nil)
+
+	if first.GetAddress().String() == second.GetAddress().String() {
+		t.Fatalf("servers listen on the same address %s", first.GetAddress())
+	}
+
+	// the same nickname is free on every server
+	dialTestClient(t, first, "alice").login(t)
+	dialTestClient(t, second, "alice").login(t)
+
+	for _, server := range []*models.Server{first, second} {
+		if !server.PlayerList.HasItemName("alice") || len(server.PlayerList.GetValuesArray()) != 1 {
+			t.Fatalf("expected only alice on server %s", server.GetAddress())
+		}
+	}
+}
+
+func TestFlushConnectionsWaitsOnlyForOwnConnections(t *testing.T) {
+	busy := startTestServer(t, nil)
+	idle := startTestServer(t, nil)
+
+	// the response started writer of the connection, it stays open while the client is connected
+	dialTestClient(t, busy, "alice").login(t)
+
+	if network.FlushConnections(busy, time.Now().Add(100*time.Millisecond)) {
+		t.Fatalf("expected open connection to keep flush of its server waiting")
+	}
+	if !network.FlushConnections(idle, time.Now().Add(100*time.Millisecond)) {
+		t.Fatalf("flush of server without connections waited for writers of another server")
+	}
+}
+
+func TestNameLengthIsSetPerServer(t *testing.T) {
+	short := startTestServer(t, func(serverConfig *config.Config) {
+		serverConfig.Messages.NameMaxChars = 5
+	})
+	long := startTestServer(t, nil)
+
+	tests := []struct {
+		server *models.Server
+		code   constants.ErrorCode
+	}{
+		{short, constants.ErrorCodeBadParams},
+		{long, constants.ErrorCodeGameNotFound},
+	}
+	for _, test := range tests {
+		client := dialTestClient(t, test.server, "alice")
+		client.login(t)
+		client.send(t, constants.CGCommands.ClientJoinGame.CommandID, `{"gameName":"gamename1"}`)
+
+		line := client.expect(t, constants.CGCommands.ResponseServerError.CommandID)
+		expected := fmt.Sprintf(`"code":"%d"`, test.code)
+		if !strings.Contains(line, expected) {
+			t.Fatalf("expected %s, got %q", expected, line)
+		}
+	}
+}
+
+//endregion
@@ -28,27 +28,48 @@ const (
 	CParamsKeyValueDelimiter    = ":"
 	CParamsWrapper              = "\""
 
+	// sizes, name lengths and cache size are defaults of messages section of config file
 	CMessageMaxSize      int = 1024
 	CMessageBufferSize   int = 1024
 	CMessageNameMinChars int = 3
 	CMessageNameMaxChars int = 20
 
 	CMessageTimeFormat string = "2006-01-02 15:04:05.000000"
+
+	CClientMessageCacheSize int = 32
+
+	// CAnnouncementMaxLength and CAnnouncementForbiddenChars limit announcements and message of the day,
+	// forbidden chars would break params of the network message
+	CAnnouncementMaxLength      int = 200
+	CAnnouncementForbiddenChars     = "\"{}[],:;"
 )
 
 //endregion
 
 // region Network Constants
-var (
-	CConIPadress string
-	CConnPort    string
-)
 
+// timeouts and limits are defaults of network section of config file, see config.Default
 const (
 	CConnType            = "tcp"
 	CTimeout             = 5 * time.Second
 	CPingTime            = 7 * time.Second
 	CTotalDisconnectTime = 60 * time.Second //todo change
+	CWriteTimeout        = 5 * time.Second
+	CSendQueueSize       = 64
+	CMaxRetransmits      = 2
+	CShutdownTimeout     = 30 * time.Second
+	CSnapshotInterval    = 30 * time.Second
+	// CGameHandlerStuckTime is how long a handler may run on the game goroutine before health check fails
+	CGameHandlerStuckTime = 10 * time.Second
+)
+
+// policies of full send queue, see network.queue_full_policy in config file
+const (
+	// CQueueFullDropUpdates drops continuous updates (replacing the queued one of the same command),
+	// critical messages still disconnect the client
+	CQueueFullDropUpdates = "drop_updates"
+	// CQueueFullDisconnect disconnects the client on any overflow
+	CQueueFullDisconnect = "disconnect"
 )
 
 //endregion
@@ -56,7 +77,9 @@ const (
 //region FilePaths
 
 const CLogsFolderPath string = "logs"
+const CJournalFolderPath string = "journal"
 const CConfigFilePath string = "config.json"
+const CSnapshotFilePath string = "snapshot.json"
 
 //endregion
 
@@ -88,6 +111,27 @@ type Command struct {
 	ParamsNames []string
 }
 
+// ParamType is type of command parameter value, values are validated and converted by it
+type ParamType int
+
+const (
+	// ParamString is any non empty value
+	ParamString ParamType = iota
+	// ParamName is game or player name, see CMessageNameMinChars
+	ParamName
+	ParamInt
+	// ParamPositiveInt is int greater than zero
+	ParamPositiveInt
+	// ParamCubeValues is array of scoring cube values
+	ParamCubeValues
+)
+
+type ParamSchema struct {
+	Name     string
+	Type     ParamType
+	Optional bool
+}
+
 type CommandType struct {
 	//CLIENT->SERVER
 	ClientCreateGame Command
@@ -126,6 +170,8 @@ type CommandType struct {
 	ServerUpdateEndScore         Command
 	ServerUpdateStartGame        Command
 	ServerUpdateNotEnoughPlayers Command
+	ServerShutdown               Command
+	ServerAnnouncement           Command
 
 	// SERVER->MULTIPLE CLIENTS - CONTINUOUSLY
 	ServerUpdateGameData   Command
@@ -152,7 +198,7 @@ var CGCommands = CommandType{
 
 	//RESPONSES SERVER->CLIENT
 	ResponseServerSuccess: Command{30, nil, []string{""}},
-	ResponseServerError:   Command{32, nil, []string{"message"}},
+	ResponseServerError:   Command{32, nil, []string{"code", "message"}},
 
 	ResponseServerGameList: Command{33, nil, []string{"gameList"}},
 
@@ -171,6 +217,8 @@ var CGCommands = CommandType{
 	ServerUpdateStartGame:        Command{41, stateless.Trigger("ServerUpdateStartGame"), []string{""}},
 	ServerUpdateEndScore:         Command{42, stateless.Trigger("ServerUpdateEndScore"), []string{"playerName"}},
 	ServerUpdateNotEnoughPlayers: Command{51, stateless.Trigger("ServerUpdateNotEnoughPlayers"), []string{""}},
+	ServerShutdown:               Command{52, nil, []string{""}},
+	ServerAnnouncement:           Command{53, nil, []string{"message"}},
 
 	////// CONTINUOUSLY
 	ServerUpdateGameData:   Command{43, stateless.Trigger("ServerUpdateGameData"), []string{"gameData"}},
@@ -185,16 +233,81 @@ var CGCommands = CommandType{
 	ResponseClientSuccess: Command{60, stateless.Trigger("ResponseClientSuccess"), []string{""}},
 }
 
-func GetCommandName(commandID int) string {
+//region ERROR CODES
+
+// ErrorCode is sent in ResponseServerError, so clients do not have to match the message text
+type ErrorCode int
+
+const (
+	ErrorCodeUnknown        ErrorCode = 0
+	ErrorCodeInvalidState   ErrorCode = 1
+	ErrorCodeNotYourTurn    ErrorCode = 2
+	ErrorCodeGameFull       ErrorCode = 3
+	ErrorCodeNicknameTaken  ErrorCode = 4
+	ErrorCodeGameNameTaken  ErrorCode = 5
+	ErrorCodeBadParams      ErrorCode = 6
+	ErrorCodeUnknownCommand ErrorCode = 7
+	ErrorCodeGameNotFound   ErrorCode = 8
+	ErrorCodeGameStarted    ErrorCode = 9
+	ErrorCodeAlreadyInGame  ErrorCode = 10
+	ErrorCodeNotInGame      ErrorCode = 11
+	ErrorCodeInvalidCubes   ErrorCode = 12
+	ErrorCodeRateLimited    ErrorCode = 13
+	ErrorCodeLogout         ErrorCode = 14
+	ErrorCodeMaintenance    ErrorCode = 15
+	ErrorCodeKicked         ErrorCode = 16
+	ErrorCodeTurnTimeout    ErrorCode = 17
+	ErrorCodeInternal       ErrorCode = 99
+)
+
+var errorMessages = map[ErrorCode]string{
+	ErrorCodeUnknown:        "you have been disconnected",
+	ErrorCodeInvalidState:   "command is not allowed in current state",
+	ErrorCodeNotYourTurn:    "it is not your turn",
+	ErrorCodeGameFull:       "game is full",
+	ErrorCodeNicknameTaken:  "error duplicate nickname",
+	ErrorCodeGameNameTaken:  "error duplicate game name",
+	ErrorCodeBadParams:      "invalid parameters",
+	ErrorCodeUnknownCommand: "unknown command",
+	ErrorCodeGameNotFound:   "game not found",
+	ErrorCodeGameStarted:    "game has already started",
+	ErrorCodeAlreadyInGame:  "player is already in a game",
+	ErrorCodeNotInGame:      "player is not in a game",
+	ErrorCodeInvalidCubes:   "invalid cube values",
+	ErrorCodeRateLimited:    "too many messages",
+	ErrorCodeLogout:         "you have been logged out",
+	ErrorCodeMaintenance:    "server is in maintenance",
+	ErrorCodeKicked:         "you have been kicked by admin",
+	ErrorCodeTurnTimeout:    "turn time has elapsed",
+	ErrorCodeInternal:       "internal server error",
+}
+
+// GetErrorMessage returns human readable message of the code
+func GetErrorMessage(code ErrorCode) string {
+	message, ok := errorMessages[code]
+	if !ok {
+		return errorMessages[ErrorCodeUnknown]
+	}
+	return message
+}
+
+//endregion
+
+// commandNames maps command ID to field name in CGCommands, it is built once as names are looked up on every log line
+var commandNames = createCommandNames()
+
+func createCommandNames() map[int]string {
+	names := make(map[int]string)
 	v := reflect.ValueOf(CGCommands)
 	for i := 0; i < v.NumField(); i++ {
 		command := v.Field(i).Interface().(Command)
-		if command.CommandID == commandID {
-			//return the command name
-			return v.Type().Field(i).Name
-		}
+		names[command.CommandID] = v.Type().Field(i).Name
 	}
-	return ""
+	return names
+}
+
+func GetCommandName(commandID int) string {
+	return commandNames[commandID]
 }
 
 func IsAlphaNumeric(name string) bool {
@@ -219,6 +332,9 @@ func IsAlphaNumeric(name string) bool {
 // region GLOBAL VARIABLES
 
 // region GLOBAL VARIABLES
+
+// CMaxScore and CTurnTimeout are defaults of game section in config file, turn timeout 0 is disabled
 const (
-	CMaxScore = 100
+	CMaxScore                  = 100
+	CTurnTimeout time.Duration = 0
 )
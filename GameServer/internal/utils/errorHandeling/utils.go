@@ -2,24 +2,52 @@ package errorHandeling
 
 import (
 	"fmt"
-	"gameserver/internal/logger"
+	"github.com/sirupsen/logrus"
+	"runtime/debug"
 )
 
-const CDebugMode = false
+// function for printing error messages to log of the server, nil error is not printed
+func PrintError(log *logrus.Logger, err error) {
+	LogError(logrus.NewEntry(log), err)
+}
 
-// function for printing error messages
-func PrintError(err error) {
-	logger.Log.Error(err)
-	if err != nil && CDebugMode == true {
-		panic(err)
+// LogError prints error with fields of the entry, e.g. session or player fields, nil error is not printed.
+// Logged errors never stop the server, not even in debug mode.
+func LogError(entry *logrus.Entry, err error) {
+	if err == nil {
+		return
 	}
+
+	entry.Error(err)
 }
 
-func AssertError(err error) {
+// AssertError logs error to log of the server and panics, nil error is ignored
+func AssertError(log *logrus.Logger, err error) {
+	if err == nil {
+		return
+	}
+
 	errNew := fmt.Errorf("AssertError: %w", err)
-	logger.Log.Error(errNew)
+	log.Error(errNew)
+	panic(errNew)
+}
+
+// RecoverPanic stops panic of the goroutine, logs it with stack trace to log of the server and calls onPanic (may be nil).
+// It has to be deferred directly, with isDebug (debug mode of the server) the panic is not stopped.
+func RecoverPanic(log *logrus.Logger, isDebug bool, onPanic func(err error)) {
+	if isDebug {
+		return
+	}
+
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+
+	err := fmt.Errorf("recovered panic: %v", recovered)
+	log.Errorf("%v\n%s", err, debug.Stack())
 
-	if err != nil {
-		panic(errNew)
+	if onPanic != nil {
+		onPanic(err)
 	}
 }
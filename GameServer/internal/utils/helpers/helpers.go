@@ -1,25 +1,21 @@
 package helpers
 
 import (
-	"encoding/json"
 	"fmt"
 	"gameserver/internal/models"
 	"gameserver/internal/utils/errorHandeling"
-	"io"
-	"net"
-	"os"
 )
 
-func RemovePlayerFromLists(player *models.Player) error {
+func RemovePlayerFromLists(server *models.Server, player *models.Player) error {
 	if player == nil {
 		err := fmt.Errorf("player is nil")
-		errorHandeling.PrintError(err)
-		panic(err)
+		errorHandeling.PrintError(server.Log, err)
+		return err
 	}
 
-	playerlist := models.GetInstancePlayerList()
+	playerlist := server.PlayerList
 
-	gamelist := models.GetInstanceGameList()
+	gamelist := server.GameList
 	//Remove player from playerlist
 	err := playerlist.RemoveItem(player)
 	if err != nil {
@@ -28,25 +24,19 @@ func RemovePlayerFromLists(player *models.Player) error {
 	}
 
 	//Remove player from playersGame
-
-	gameFromList := gamelist.GetPlayersGame(player)
-	if gameFromList == nil {
-		return nil
-	}
-
-	err = gameFromList.RemovePlayer(player)
+	err = gamelist.RemovePlayerFromGame(player)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("cannot create playersGame %w", err)
 	}
 
 	return nil
 }
 
-func RemovePlayerFromGame(player *models.Player) error {
-	err := RemovePlayerFromLists(player)
+func RemovePlayerFromGame(server *models.Server, player *models.Player) error {
+	err := RemovePlayerFromLists(server, player)
 	if err != nil {
-		errorHandeling.PrintError(err)
+		errorHandeling.PrintError(server.Log, err)
 		return fmt.Errorf("Error sending response: %w", err)
 	}
 
@@ -63,60 +53,6 @@ func RemovePlayerFromList(list []*models.Player, player *models.Player) []*model
 	return newList
 }
 
-type ServerConfig struct {
-	IP   string `json:"ip"`
-	Port int    `json:"port"`
-}
-
-// read config file in json format where is server - which has values ip_adress and port
-func ReadConfigFile(filePath string) (string, string, error) {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return "", "", fmt.Errorf("could not open config file: %w", err)
-	}
-
-	bytes, err := io.ReadAll(file)
-	if err != nil {
-		return "", "", fmt.Errorf("could not read config file: %w", err)
-	}
-
-	var config struct {
-		Server ServerConfig `json:"server"`
-	}
-
-	err = json.Unmarshal(bytes, &config)
-	if err != nil {
-		return "", "", fmt.Errorf("could not unmarshal config file: %w", err)
-	}
-
-	//close file
-	err = file.Close()
-	if err != nil {
-		return "", "", fmt.Errorf("could not close config file: %w", err)
-	}
-
-	//check if ip and port are valid
-	if !isValidIP(config.Server.IP) {
-		return "", "", fmt.Errorf("invalid ip address")
-	}
-
-	if !isValidPort(config.Server.Port) {
-		return "", "", fmt.Errorf("invalid port")
-	}
-
-	portStr := fmt.Sprintf("%d", config.Server.Port)
-
-	return config.Server.IP, portStr, nil
-}
-
-func isValidPort(port int) bool {
-	return port > 0 && port <= 65535
-}
-
-func isValidIP(ip string) bool {
-	return net.ParseIP(ip) != nil
-}
-
 func Contains(states []string, name string) bool {
 	for _, s := range states {
 		if s == name {
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"context"
 	"flag"
-	"gameserver/internal/utils/errorHandeling"
 	"os"
 	"reflect"
 	"runtime"
@@ -142,7 +141,6 @@ func TestStateMachine_ToGraph(t *testing.T) {
 				}
 			} else {
 				if err != nil {
-					errorHandeling.PrintError(err)
 					t.Fatal(err)
 				}
 			}
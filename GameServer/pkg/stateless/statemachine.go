@@ -3,7 +3,6 @@ package stateless
 import (
 	"context"
 	"fmt"
-	"gameserver/internal/utils/errorHandeling"
 	"reflect"
 	"sync"
 )
@@ -140,7 +139,6 @@ func (sm *StateMachine) State(ctx context.Context) (State, error) {
 func (sm *StateMachine) MustState() State {
 	st, err := sm.State(context.Background())
 	if err != nil {
-		errorHandeling.PrintError(err)
 		panic(err)
 	}
 	return st
@@ -155,7 +153,6 @@ func (sm *StateMachine) PermittedTriggers(args ...any) ([]Trigger, error) {
 func (sm *StateMachine) PermittedTriggersCtx(ctx context.Context, args ...any) ([]Trigger, error) {
 	sr, err := sm.currentState(ctx)
 	if err != nil {
-		errorHandeling.PrintError(err)
 		return nil, err
 	}
 	return sr.PermittedTriggers(ctx, args...), nil
@@ -172,7 +169,6 @@ func (sm *StateMachine) Activate() error {
 func (sm *StateMachine) ActivateCtx(ctx context.Context) error {
 	sr, err := sm.currentState(ctx)
 	if err != nil {
-		errorHandeling.PrintError(err)
 		return err
 	}
 	return sr.Activate(ctx)
@@ -189,7 +185,6 @@ func (sm *StateMachine) Deactivate() error {
 func (sm *StateMachine) DeactivateCtx(ctx context.Context) error {
 	sr, err := sm.currentState(ctx)
 	if err != nil {
-		errorHandeling.PrintError(err)
 		return err
 	}
 	return sr.Deactivate(ctx)
@@ -205,7 +200,6 @@ func (sm *StateMachine) IsInState(state State) (bool, error) {
 func (sm *StateMachine) IsInStateCtx(ctx context.Context, state State) (bool, error) {
 	sr, err := sm.currentState(ctx)
 	if err != nil {
-		errorHandeling.PrintError(err)
 		return false, err
 	}
 	return sr.IsIncludedInState(state), nil
@@ -220,7 +214,6 @@ func (sm *StateMachine) CanFire(trigger Trigger, args ...any) (bool, error) {
 func (sm *StateMachine) CanFireCtx(ctx context.Context, trigger Trigger, args ...any) (bool, error) {
 	sr, err := sm.currentState(ctx)
 	if err != nil {
-		errorHandeling.PrintError(err)
 		return false, err
 	}
 	return sr.CanHandle(ctx, trigger, args...), nil
@@ -290,7 +283,6 @@ func (sm *StateMachine) Firing() bool {
 func (sm *StateMachine) String() string {
 	state, err := sm.State(context.Background())
 	if err != nil {
-		errorHandeling.PrintError(err)
 		return ""
 	}
 
@@ -306,7 +298,6 @@ func (sm *StateMachine) setState(ctx context.Context, state State) error {
 func (sm *StateMachine) currentState(ctx context.Context) (*stateRepresentation, error) {
 	state, err := sm.State(ctx)
 	if err != nil {
-		errorHandeling.PrintError(err)
 		return nil, err
 	}
 	return sm.stateRepresentation(state), nil
@@ -342,7 +333,6 @@ func (sm *StateMachine) internalFireOne(ctx context.Context, trigger Trigger, ar
 	}
 	source, err := sm.State(ctx)
 	if err != nil {
-		errorHandeling.PrintError(err)
 		return err
 	}
 	representativeState := sm.stateRepresentation(source)
@@ -391,7 +381,6 @@ func (sm *StateMachine) handleReentryTrigger(ctx context.Context, sr *stateRepre
 	callEvents(sm.onTransitioningEvents, ctx, transition)
 	rep, err := sm.enterState(ctx, newSr, transition, args...)
 	if err != nil {
-		errorHandeling.PrintError(err)
 		return err
 	}
 	if err := sm.setState(ctx, rep.State); err != nil {
@@ -412,7 +401,6 @@ func (sm *StateMachine) handleTransitioningTrigger(ctx context.Context, sr *stat
 	newSr := sm.stateRepresentation(transition.Destination)
 	rep, err := sm.enterState(ctx, newSr, transition, args...)
 	if err != nil {
-		errorHandeling.PrintError(err)
 		return err
 	}
 	// Check if state has changed by entering new state (by firing triggers in OnEntry or such)
@@ -429,7 +417,6 @@ func (sm *StateMachine) enterState(ctx context.Context, sr *stateRepresentation,
 	// Enter the new state
 	err := sr.Enter(ctx, transition, args...)
 	if err != nil {
-		errorHandeling.PrintError(err)
 		return nil, err
 	}
 	// Recursively enter substates that have an initial transition